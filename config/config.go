@@ -0,0 +1,464 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	ModeDevelopment = "development"
+	ModeProduction  = "production"
+	ModeTest        = "test"
+)
+
+// DevJWTSecret is only accepted when running in development mode.
+const DevJWTSecret = "dev-secret-change-me"
+
+type Config struct {
+	// Mode is "development" (default), "production" or "test".
+	Mode      string
+	JWTSecret string
+	// LogLevel is the minimum level written by the JSON logger.
+	LogLevel slog.Level
+
+	DB        DBConfig
+	HTTP      HTTPConfig
+	Tracing   TracingConfig
+	RateLimit RateLimitConfig
+
+	// StorageType is "local" (default) or "s3".
+	StorageType string
+	// AppURL is used to build public URLs for local storage (e.g. http://localhost:8080)
+	AppURL string
+	// ReservedHandles are reserved on top of the built-in list.
+	ReservedHandles []string
+	// StripEmailPlusTags makes bob+news@example.com the same account as
+	// bob@example.com.
+	StripEmailPlusTags bool
+	// AdminEmails are the emails of the users who may see the moderation
+	// queue and act on reports.
+	AdminEmails []string
+
+	S3 S3Config
+}
+
+// DBConfig holds the database connection and pool settings.
+type DBConfig struct {
+	DSN             string
+	MaxOpenConns    int
+	MaxIdleConns    int
+	ConnMaxLifetime time.Duration
+	// BusyTimeout is how long SQLite waits on a locked database before failing.
+	BusyTimeout time.Duration
+}
+
+// HTTPConfig holds the HTTP server settings. TLS is enabled when both
+// TLSCertFile and TLSKeyFile are set.
+type HTTPConfig struct {
+	Addr              string
+	ReadTimeout       time.Duration
+	ReadHeaderTimeout time.Duration
+	WriteTimeout      time.Duration
+	IdleTimeout       time.Duration
+	// ShutdownTimeout bounds how long in-flight requests may drain on SIGTERM.
+	ShutdownTimeout time.Duration
+	TLSCertFile     string
+	TLSKeyFile      string
+	// TrustProxyHeaders makes X-Forwarded-For the source of client IPs. Only
+	// enable it behind a reverse proxy that sets the header.
+	TrustProxyHeaders bool
+}
+
+func (h HTTPConfig) TLSEnabled() bool {
+	return h.TLSCertFile != "" && h.TLSKeyFile != ""
+}
+
+// TracingConfig controls OpenTelemetry tracing.
+type TracingConfig struct {
+	// Exporter is "none" (default), "stdout" or "otlp".
+	Exporter string
+	// OTLPEndpoint is the OTLP/HTTP collector URL, e.g. http://localhost:4318.
+	OTLPEndpoint string
+	// SampleRatio is the fraction of new traces recorded, from 0 to 1.
+	SampleRatio float64
+}
+
+// RateLimitConfig selects where rate limit buckets are kept.
+type RateLimitConfig struct {
+	// Backend is "memory" (default, per replica), "database" (shared by
+	// every replica) or "none".
+	Backend string
+}
+
+// S3Config holds the Backblaze B2 / S3-compatible storage settings.
+type S3Config struct {
+	Endpoint       string
+	Bucket         string
+	KeyID          string
+	ApplicationKey string
+	Region         string
+	// BaseURL is the public base URL for uploaded files
+	BaseURL string
+}
+
+type field struct {
+	key string
+	// def applies in every mode; devDef only outside production.
+	def    string
+	devDef string
+	secret bool
+	get    func(c *Config) string
+	set    func(c *Config, v string) error
+}
+
+func stringField(key string, ptr func(c *Config) *string) field {
+	return field{
+		key: key,
+		get: func(c *Config) string { return *ptr(c) },
+		set: func(c *Config, v string) error { *ptr(c) = v; return nil },
+	}
+}
+
+func intField(key string, ptr func(c *Config) *int) field {
+	return field{
+		key: key,
+		get: func(c *Config) string { return strconv.Itoa(*ptr(c)) },
+		set: func(c *Config, v string) error {
+			n, err := strconv.Atoi(v)
+			if err != nil {
+				return fmt.Errorf("%s must be an integer, got %q", key, v)
+			}
+			*ptr(c) = n
+			return nil
+		},
+	}
+}
+
+func durationField(key string, ptr func(c *Config) *time.Duration) field {
+	return field{
+		key: key,
+		get: func(c *Config) string { return ptr(c).String() },
+		set: func(c *Config, v string) error {
+			d, err := time.ParseDuration(v)
+			if err != nil {
+				return fmt.Errorf("%s must be a duration like 5s, got %q", key, v)
+			}
+			*ptr(c) = d
+			return nil
+		},
+	}
+}
+
+func floatField(key string, ptr func(c *Config) *float64) field {
+	return field{
+		key: key,
+		get: func(c *Config) string { return strconv.FormatFloat(*ptr(c), 'g', -1, 64) },
+		set: func(c *Config, v string) error {
+			f, err := strconv.ParseFloat(v, 64)
+			if err != nil {
+				return fmt.Errorf("%s must be a number, got %q", key, v)
+			}
+			*ptr(c) = f
+			return nil
+		},
+	}
+}
+
+func boolField(key string, ptr func(c *Config) *bool) field {
+	return field{
+		key: key,
+		get: func(c *Config) string { return strconv.FormatBool(*ptr(c)) },
+		set: func(c *Config, v string) error {
+			b, err := strconv.ParseBool(v)
+			if err != nil {
+				return fmt.Errorf("%s must be true or false, got %q", key, v)
+			}
+			*ptr(c) = b
+			return nil
+		},
+	}
+}
+
+// listField parses a comma-separated list, dropping empty items.
+func listField(key string, ptr func(c *Config) *[]string) field {
+	return field{
+		key: key,
+		get: func(c *Config) string { return strings.Join(*ptr(c), ",") },
+		set: func(c *Config, v string) error {
+			var items []string
+			for _, item := range strings.Split(v, ",") {
+				if item = strings.TrimSpace(item); item != "" {
+					items = append(items, item)
+				}
+			}
+			*ptr(c) = items
+			return nil
+		},
+	}
+}
+
+func levelField(key string, ptr func(c *Config) *slog.Level) field {
+	return field{
+		key: key,
+		get: func(c *Config) string { return ptr(c).String() },
+		set: func(c *Config, v string) error {
+			if err := ptr(c).UnmarshalText([]byte(v)); err != nil {
+				return fmt.Errorf("%s must be debug, info, warn or error, got %q", key, v)
+			}
+			return nil
+		},
+	}
+}
+
+func (f field) withDefault(def string) field    { f.def = def; return f }
+func (f field) withDevDefault(def string) field { f.devDef = def; return f }
+func (f field) redacted() field                 { f.secret = true; return f }
+
+// fields lists every supported setting in report order. APP_ENV comes first
+// because the other defaults depend on the mode.
+var fields = []field{
+	stringField("APP_ENV", func(c *Config) *string { return &c.Mode }).withDefault(ModeDevelopment),
+	stringField("JWT_SECRET", func(c *Config) *string { return &c.JWTSecret }).withDevDefault(DevJWTSecret).redacted(),
+	levelField("LOG_LEVEL", func(c *Config) *slog.Level { return &c.LogLevel }).withDefault("info"),
+	stringField("DB_DSN", func(c *Config) *string { return &c.DB.DSN }).withDevDefault("file:dev.db"),
+	intField("DB_MAX_OPEN_CONNS", func(c *Config) *int { return &c.DB.MaxOpenConns }).withDefault("10"),
+	intField("DB_MAX_IDLE_CONNS", func(c *Config) *int { return &c.DB.MaxIdleConns }).withDefault("5"),
+	durationField("DB_CONN_MAX_LIFETIME", func(c *Config) *time.Duration { return &c.DB.ConnMaxLifetime }).withDefault("30m"),
+	durationField("DB_BUSY_TIMEOUT", func(c *Config) *time.Duration { return &c.DB.BusyTimeout }).withDefault("5s"),
+	stringField("HTTP_ADDR", func(c *Config) *string { return &c.HTTP.Addr }).withDefault(":8080"),
+	durationField("HTTP_READ_TIMEOUT", func(c *Config) *time.Duration { return &c.HTTP.ReadTimeout }).withDefault("15s"),
+	durationField("HTTP_READ_HEADER_TIMEOUT", func(c *Config) *time.Duration { return &c.HTTP.ReadHeaderTimeout }).withDefault("5s"),
+	durationField("HTTP_WRITE_TIMEOUT", func(c *Config) *time.Duration { return &c.HTTP.WriteTimeout }).withDefault("30s"),
+	durationField("HTTP_IDLE_TIMEOUT", func(c *Config) *time.Duration { return &c.HTTP.IdleTimeout }).withDefault("60s"),
+	durationField("HTTP_SHUTDOWN_TIMEOUT", func(c *Config) *time.Duration { return &c.HTTP.ShutdownTimeout }).withDefault("20s"),
+	stringField("TLS_CERT_FILE", func(c *Config) *string { return &c.HTTP.TLSCertFile }),
+	stringField("TLS_KEY_FILE", func(c *Config) *string { return &c.HTTP.TLSKeyFile }),
+	boolField("TRUST_PROXY_HEADERS", func(c *Config) *bool { return &c.HTTP.TrustProxyHeaders }).withDefault("false"),
+	stringField("RATE_LIMIT_BACKEND", func(c *Config) *string { return &c.RateLimit.Backend }).withDefault("memory"),
+	stringField("TRACING_EXPORTER", func(c *Config) *string { return &c.Tracing.Exporter }).withDefault("none"),
+	stringField("TRACING_OTLP_ENDPOINT", func(c *Config) *string { return &c.Tracing.OTLPEndpoint }),
+	floatField("TRACING_SAMPLE_RATIO", func(c *Config) *float64 { return &c.Tracing.SampleRatio }).withDefault("1"),
+	stringField("STORAGE_TYPE", func(c *Config) *string { return &c.StorageType }).withDefault("local"),
+	stringField("APP_URL", func(c *Config) *string { return &c.AppURL }).withDevDefault("http://localhost:8080"),
+	listField("RESERVED_HANDLES", func(c *Config) *[]string { return &c.ReservedHandles }),
+	boolField("EMAIL_STRIP_PLUS_TAGS", func(c *Config) *bool { return &c.StripEmailPlusTags }).withDefault("false"),
+	listField("ADMIN_EMAILS", func(c *Config) *[]string { return &c.AdminEmails }),
+	stringField("S3_ENDPOINT", func(c *Config) *string { return &c.S3.Endpoint }),
+	stringField("S3_BUCKET", func(c *Config) *string { return &c.S3.Bucket }),
+	stringField("S3_KEY_ID", func(c *Config) *string { return &c.S3.KeyID }),
+	stringField("S3_APPLICATION_KEY", func(c *Config) *string { return &c.S3.ApplicationKey }).redacted(),
+	stringField("S3_REGION", func(c *Config) *string { return &c.S3.Region }).withDefault("us-west-004"),
+	stringField("S3_BASE_URL", func(c *Config) *string { return &c.S3.BaseURL }),
+}
+
+// Load builds a Config from the given sources and validates it. For every
+// key the first source with a non-empty value wins.
+func Load(sources ...Source) (*Config, error) {
+	cfg := &Config{}
+	var errs []error
+	for _, f := range fields {
+		v := ""
+		for _, src := range sources {
+			if sv, ok := src(f.key); ok && sv != "" {
+				v = sv
+				break
+			}
+		}
+		if v == "" {
+			v = f.def
+		}
+		if v == "" && cfg.Mode != ModeProduction {
+			v = f.devDef
+		}
+		if v == "" {
+			continue
+		}
+		if err := f.set(cfg, v); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	if len(errs) > 0 {
+		return nil, fmt.Errorf("invalid configuration: %w", errors.Join(errs...))
+	}
+
+	if err := cfg.validate(); err != nil {
+		return nil, err
+	}
+	return cfg, nil
+}
+
+// LoadFromEnvironment loads configuration from, in order of precedence, the
+// process environment, .env.$ENV (ENV defaults to "local", matching the
+// Makefile), .env, and the JSON file named by CONFIG_FILE.
+func LoadFromEnvironment() (*Config, error) {
+	sources := []Source{os.LookupEnv}
+
+	envName := os.Getenv("ENV")
+	if envName == "" {
+		envName = "local"
+	}
+	for _, path := range []string{".env." + envName, ".env"} {
+		src, err := FromDotEnv(path)
+		if err != nil {
+			return nil, err
+		}
+		sources = append(sources, src)
+	}
+
+	if path := os.Getenv("CONFIG_FILE"); path != "" {
+		src, err := FromJSONFile(path)
+		if err != nil {
+			return nil, err
+		}
+		sources = append(sources, src)
+	}
+
+	return Load(sources...)
+}
+
+func (c *Config) IsDev() bool {
+	return c.Mode == ModeDevelopment
+}
+
+func (c *Config) validate() error {
+	var errs []error
+
+	switch c.Mode {
+	case ModeDevelopment, ModeTest, ModeProduction:
+	default:
+		errs = append(errs, fmt.Errorf("APP_ENV must be %q, %q or %q, got %q", ModeDevelopment, ModeProduction, ModeTest, c.Mode))
+	}
+
+	if c.JWTSecret == "" {
+		errs = append(errs, errors.New("JWT_SECRET is required"))
+	} else if c.JWTSecret == DevJWTSecret && !c.IsDev() {
+		errs = append(errs, fmt.Errorf("JWT_SECRET must not be the development default in %s mode", c.Mode))
+	}
+
+	if c.DB.DSN == "" {
+		errs = append(errs, errors.New("DB_DSN is required"))
+	}
+	if c.DB.MaxOpenConns < 1 {
+		errs = append(errs, fmt.Errorf("DB_MAX_OPEN_CONNS must be at least 1, got %d", c.DB.MaxOpenConns))
+	}
+	if c.DB.MaxIdleConns < 0 || c.DB.MaxIdleConns > c.DB.MaxOpenConns {
+		errs = append(errs, fmt.Errorf("DB_MAX_IDLE_CONNS must be between 0 and DB_MAX_OPEN_CONNS, got %d", c.DB.MaxIdleConns))
+	}
+
+	if c.HTTP.Addr == "" {
+		errs = append(errs, errors.New("HTTP_ADDR is required"))
+	}
+	if (c.HTTP.TLSCertFile == "") != (c.HTTP.TLSKeyFile == "") {
+		errs = append(errs, errors.New("TLS_CERT_FILE and TLS_KEY_FILE must be set together"))
+	}
+	for _, path := range []string{c.HTTP.TLSCertFile, c.HTTP.TLSKeyFile} {
+		if path == "" {
+			continue
+		}
+		if _, err := os.Stat(path); err != nil {
+			errs = append(errs, fmt.Errorf("TLS file %s: %w", path, err))
+		}
+	}
+
+	switch c.Tracing.Exporter {
+	case "none", "stdout":
+	case "otlp":
+		if c.Tracing.OTLPEndpoint == "" {
+			errs = append(errs, errors.New("TRACING_OTLP_ENDPOINT is required when TRACING_EXPORTER=otlp"))
+		}
+	default:
+		errs = append(errs, fmt.Errorf("TRACING_EXPORTER must be \"none\", \"stdout\" or \"otlp\", got %q", c.Tracing.Exporter))
+	}
+	if c.Tracing.SampleRatio < 0 || c.Tracing.SampleRatio > 1 {
+		errs = append(errs, fmt.Errorf("TRACING_SAMPLE_RATIO must be between 0 and 1, got %g", c.Tracing.SampleRatio))
+	}
+
+	switch c.RateLimit.Backend {
+	case "memory", "database", "none":
+	default:
+		errs = append(errs, fmt.Errorf("RATE_LIMIT_BACKEND must be \"memory\", \"database\" or \"none\", got %q", c.RateLimit.Backend))
+	}
+
+	if c.AppURL == "" {
+		errs = append(errs, errors.New("APP_URL is required"))
+	} else if u, err := url.Parse(c.AppURL); err != nil || u.Scheme == "" || u.Host == "" {
+		errs = append(errs, fmt.Errorf("APP_URL must be an absolute URL, got %q", c.AppURL))
+	}
+
+	switch c.StorageType {
+	case "local":
+	case "s3":
+		for _, f := range fields {
+			if strings.HasPrefix(f.key, "S3_") && f.get(c) == "" {
+				errs = append(errs, fmt.Errorf("%s is required when STORAGE_TYPE=s3", f.key))
+			}
+		}
+	default:
+		errs = append(errs, fmt.Errorf("STORAGE_TYPE must be \"local\" or \"s3\", got %q", c.StorageType))
+	}
+
+	if len(errs) > 0 {
+		return fmt.Errorf("invalid configuration: %w", errors.Join(errs...))
+	}
+	return nil
+}
+
+// Redacted returns the effective configuration keyed by variable name, with
+// secrets and DSN credentials redacted, for logging at startup.
+func (c *Config) Redacted() map[string]string {
+	out := make(map[string]string, len(fields))
+	for _, f := range fields {
+		out[f.key] = f.redact(f.get(c))
+	}
+	return out
+}
+
+// Report renders Redacted one KEY=value per line in declaration order.
+func (c *Config) Report() string {
+	var b strings.Builder
+	for _, f := range fields {
+		fmt.Fprintf(&b, "%s=%s\n", f.key, f.redact(f.get(c)))
+	}
+	return b.String()
+}
+
+func (f field) redact(v string) string {
+	switch {
+	case v == "":
+		return "(unset)"
+	case f.secret:
+		return "[redacted]"
+	case f.key == "DB_DSN":
+		return redactDSN(v)
+	}
+	return v
+}
+
+// redactDSN hides passwords and auth tokens, e.g. Turso's ?authToken=.
+func redactDSN(dsn string) string {
+	u, err := url.Parse(dsn)
+	if err != nil {
+		return "[redacted]"
+	}
+	if _, ok := u.User.Password(); ok {
+		u.User = url.UserPassword(u.User.Username(), "redacted")
+	}
+	if u.RawQuery == "" {
+		return u.String()
+	}
+	q := u.Query()
+	for key := range q {
+		lower := strings.ToLower(key)
+		if strings.Contains(lower, "token") || strings.Contains(lower, "password") {
+			q.Set(key, "redacted")
+		}
+	}
+	u.RawQuery = q.Encode()
+	return u.String()
+}
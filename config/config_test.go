@@ -0,0 +1,267 @@
+package config
+
+import (
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestLoad(t *testing.T) {
+	t.Run("development defaults", func(t *testing.T) {
+		cfg, err := Load()
+		if err != nil {
+			t.Fatalf("Load failed: %v", err)
+		}
+		if cfg.Mode != ModeDevelopment {
+			t.Errorf("got mode %q, want %q", cfg.Mode, ModeDevelopment)
+		}
+		if cfg.JWTSecret != DevJWTSecret {
+			t.Errorf("got secret %q, want dev default", cfg.JWTSecret)
+		}
+		if cfg.DB.DSN != "file:dev.db" {
+			t.Errorf("got DSN %q, want file:dev.db", cfg.DB.DSN)
+		}
+		if cfg.DB.BusyTimeout != 5*time.Second {
+			t.Errorf("got busy timeout %v, want 5s", cfg.DB.BusyTimeout)
+		}
+		if cfg.StorageType != "local" {
+			t.Errorf("got storage %q, want local", cfg.StorageType)
+		}
+	})
+
+	t.Run("first source wins", func(t *testing.T) {
+		cfg, err := Load(
+			FromMap(map[string]string{"APP_URL": "https://first.example.com", "JWT_SECRET": ""}),
+			FromMap(map[string]string{"APP_URL": "https://second.example.com", "JWT_SECRET": "from-second"}),
+		)
+		if err != nil {
+			t.Fatalf("Load failed: %v", err)
+		}
+		if cfg.AppURL != "https://first.example.com" {
+			t.Errorf("got APP_URL %q, want first source", cfg.AppURL)
+		}
+		if cfg.JWTSecret != "from-second" {
+			t.Errorf("got JWT_SECRET %q, want fallthrough on empty value", cfg.JWTSecret)
+		}
+	})
+
+	t.Run("lists are comma separated", func(t *testing.T) {
+		cfg, err := Load(FromMap(map[string]string{
+			"RESERVED_HANDLES": " billing, ,press,",
+			"ADMIN_EMAILS":     "mod@example.com,",
+		}))
+		if err != nil {
+			t.Fatalf("Load failed: %v", err)
+		}
+		if got := strings.Join(cfg.ReservedHandles, "|"); got != "billing|press" {
+			t.Errorf("got reserved handles %q, want billing|press", got)
+		}
+		if got := strings.Join(cfg.AdminEmails, "|"); got != "mod@example.com" {
+			t.Errorf("got admin emails %q, want mod@example.com", got)
+		}
+	})
+
+	t.Run("production refuses dev secret", func(t *testing.T) {
+		_, err := Load(FromMap(map[string]string{
+			"APP_ENV":    ModeProduction,
+			"JWT_SECRET": DevJWTSecret,
+			"DB_DSN":     "libsql://db.turso.io",
+			"APP_URL":    "https://myapp.com",
+		}))
+		if err == nil || !strings.Contains(err.Error(), "JWT_SECRET") {
+			t.Errorf("expected JWT_SECRET error, got %v", err)
+		}
+	})
+
+	t.Run("production requires explicit values", func(t *testing.T) {
+		_, err := Load(FromMap(map[string]string{"APP_ENV": ModeProduction}))
+		if err == nil {
+			t.Fatal("expected error, got nil")
+		}
+		for _, key := range []string{"JWT_SECRET", "DB_DSN", "APP_URL"} {
+			if !strings.Contains(err.Error(), key) {
+				t.Errorf("expected error to mention %s, got %v", key, err)
+			}
+		}
+	})
+
+	t.Run("s3 requires credentials", func(t *testing.T) {
+		_, err := Load(FromMap(map[string]string{"STORAGE_TYPE": "s3", "S3_BUCKET": "bucket"}))
+		if err == nil {
+			t.Fatal("expected error, got nil")
+		}
+		if !strings.Contains(err.Error(), "S3_ENDPOINT") || strings.Contains(err.Error(), "S3_BUCKET") {
+			t.Errorf("expected only missing S3 keys to be reported, got %v", err)
+		}
+	})
+
+	t.Run("invalid pool settings", func(t *testing.T) {
+		_, err := Load(FromMap(map[string]string{"DB_MAX_OPEN_CONNS": "many", "DB_BUSY_TIMEOUT": "5"}))
+		if err == nil {
+			t.Fatal("expected error, got nil")
+		}
+		for _, key := range []string{"DB_MAX_OPEN_CONNS", "DB_BUSY_TIMEOUT"} {
+			if !strings.Contains(err.Error(), key) {
+				t.Errorf("expected error to mention %s, got %v", key, err)
+			}
+		}
+	})
+
+	t.Run("log level", func(t *testing.T) {
+		cfg, err := Load(FromMap(map[string]string{"LOG_LEVEL": "debug"}))
+		if err != nil {
+			t.Fatalf("Load failed: %v", err)
+		}
+		if cfg.LogLevel != slog.LevelDebug {
+			t.Errorf("got level %v, want debug", cfg.LogLevel)
+		}
+		if _, err := Load(FromMap(map[string]string{"LOG_LEVEL": "loud"})); err == nil {
+			t.Error("expected error for unknown level, got nil")
+		}
+	})
+
+	t.Run("tls files must be paired", func(t *testing.T) {
+		_, err := Load(FromMap(map[string]string{"TLS_CERT_FILE": "cert.pem"}))
+		if err == nil || !strings.Contains(err.Error(), "TLS_KEY_FILE") {
+			t.Errorf("expected TLS pairing error, got %v", err)
+		}
+	})
+
+	t.Run("tls files must exist", func(t *testing.T) {
+		dir := t.TempDir()
+		cert := filepath.Join(dir, "cert.pem")
+		if err := os.WriteFile(cert, []byte("cert"), 0o600); err != nil {
+			t.Fatal(err)
+		}
+		_, err := Load(FromMap(map[string]string{"TLS_CERT_FILE": cert, "TLS_KEY_FILE": filepath.Join(dir, "missing.pem")}))
+		if err == nil || !strings.Contains(err.Error(), "missing.pem") {
+			t.Errorf("expected missing key file error, got %v", err)
+		}
+	})
+
+	t.Run("otlp tracing requires endpoint", func(t *testing.T) {
+		_, err := Load(FromMap(map[string]string{"TRACING_EXPORTER": "otlp"}))
+		if err == nil || !strings.Contains(err.Error(), "TRACING_OTLP_ENDPOINT") {
+			t.Errorf("expected endpoint error, got %v", err)
+		}
+	})
+
+	t.Run("sample ratio range", func(t *testing.T) {
+		if _, err := Load(FromMap(map[string]string{"TRACING_SAMPLE_RATIO": "1.5"})); err == nil {
+			t.Error("expected error, got nil")
+		}
+	})
+
+	t.Run("rate limit settings", func(t *testing.T) {
+		cfg, err := Load(FromMap(map[string]string{"RATE_LIMIT_BACKEND": "database", "TRUST_PROXY_HEADERS": "true"}))
+		if err != nil {
+			t.Fatalf("Load failed: %v", err)
+		}
+		if cfg.RateLimit.Backend != "database" || !cfg.HTTP.TrustProxyHeaders {
+			t.Errorf("got backend %q trust %v", cfg.RateLimit.Backend, cfg.HTTP.TrustProxyHeaders)
+		}
+		for key, value := range map[string]string{"RATE_LIMIT_BACKEND": "redis", "TRUST_PROXY_HEADERS": "maybe"} {
+			if _, err := Load(FromMap(map[string]string{key: value})); err == nil || !strings.Contains(err.Error(), key) {
+				t.Errorf("expected error to mention %s, got %v", key, err)
+			}
+		}
+	})
+
+	t.Run("unknown storage type", func(t *testing.T) {
+		if _, err := Load(FromMap(map[string]string{"STORAGE_TYPE": "ftp"})); err == nil {
+			t.Error("expected error, got nil")
+		}
+	})
+}
+
+func TestReport(t *testing.T) {
+	cfg, err := Load(FromMap(map[string]string{
+		"JWT_SECRET":         "super-secret",
+		"DB_DSN":             "libsql://db.turso.io?authToken=abc123",
+		"STORAGE_TYPE":       "s3",
+		"S3_ENDPOINT":        "https://s3.example.com",
+		"S3_BUCKET":          "bucket",
+		"S3_KEY_ID":          "key-id",
+		"S3_APPLICATION_KEY": "app-key",
+		"S3_BASE_URL":        "https://cdn.example.com",
+	}))
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+
+	report := cfg.Report()
+	for _, secret := range []string{"super-secret", "abc123", "app-key"} {
+		if strings.Contains(report, secret) {
+			t.Errorf("report leaks %q:\n%s", secret, report)
+		}
+	}
+	if !strings.Contains(report, "S3_BUCKET=bucket") {
+		t.Errorf("expected report to include S3_BUCKET, got:\n%s", report)
+	}
+	if got := cfg.Redacted()["JWT_SECRET"]; got != "[redacted]" {
+		t.Errorf("expected redacted JWT_SECRET, got %q", got)
+	}
+}
+
+func TestFromDotEnv(t *testing.T) {
+	t.Run("parses values", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), ".env")
+		content := "# comment\n\nexport APP_URL=https://myapp.com\nJWT_SECRET=\"quoted value\"\n"
+		if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+			t.Fatal(err)
+		}
+
+		src, err := FromDotEnv(path)
+		if err != nil {
+			t.Fatalf("FromDotEnv failed: %v", err)
+		}
+		if v, _ := src("APP_URL"); v != "https://myapp.com" {
+			t.Errorf("got APP_URL %q", v)
+		}
+		if v, _ := src("JWT_SECRET"); v != "quoted value" {
+			t.Errorf("got JWT_SECRET %q", v)
+		}
+	})
+
+	t.Run("missing file is empty", func(t *testing.T) {
+		src, err := FromDotEnv(filepath.Join(t.TempDir(), "missing"))
+		if err != nil {
+			t.Fatalf("FromDotEnv failed: %v", err)
+		}
+		if _, ok := src("APP_URL"); ok {
+			t.Error("expected no values from missing file")
+		}
+	})
+
+	t.Run("malformed line", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), ".env")
+		if err := os.WriteFile(path, []byte("NOT_A_PAIR\n"), 0o600); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := FromDotEnv(path); err == nil {
+			t.Error("expected error, got nil")
+		}
+	})
+}
+
+func TestFromJSONFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.json")
+	if err := os.WriteFile(path, []byte(`{"S3_REGION": "eu-central-003"}`), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	src, err := FromJSONFile(path)
+	if err != nil {
+		t.Fatalf("FromJSONFile failed: %v", err)
+	}
+	cfg, err := Load(src)
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	if cfg.S3.Region != "eu-central-003" {
+		t.Errorf("got region %q, want eu-central-003", cfg.S3.Region)
+	}
+}
@@ -0,0 +1,314 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"mime"
+	"net/http"
+	"strings"
+
+	"myapp/model"
+	"myapp/services"
+	"myapp/storage"
+)
+
+const (
+	maxJSONBody   = 1 << 20
+	maxAvatarBody = 10 << 20
+)
+
+// APIHandler serves the versioned JSON API under /api/v1. It shares the
+// services with the form handlers; only the transport differs.
+type APIHandler struct {
+	authSvc    *services.AuthService
+	userSvc    *services.UserService
+	privacySvc *services.PrivacyService
+	store      storage.Storage
+}
+
+func NewAPIHandler(authSvc *services.AuthService, userSvc *services.UserService, privacySvc *services.PrivacyService, store storage.Storage) *APIHandler {
+	return &APIHandler{authSvc: authSvc, userSvc: userSvc, privacySvc: privacySvc, store: store}
+}
+
+type apiSignupRequest struct {
+	Email    string `json:"email"`
+	Password string `json:"password"`
+	Handle   string `json:"handle"`
+}
+
+type apiLoginRequest struct {
+	Email    string `json:"email"`
+	Password string `json:"password"`
+}
+
+// apiProfileUpdate mirrors services.UpdateProfileInput with every field
+// optional; the avatar has its own endpoint.
+type apiProfileUpdate struct {
+	Handle      *string       `json:"handle"`
+	DisplayName *string       `json:"display_name"`
+	Bio         *string       `json:"bio"`
+	Pronouns    *string       `json:"pronouns"`
+	Location    *string       `json:"location"`
+	Country     *string       `json:"country"`
+	Links       *[]model.Link `json:"links"`
+}
+
+type apiToken struct {
+	Token string `json:"token"`
+}
+
+type apiProfile struct {
+	Handle      string       `json:"handle"`
+	DisplayName string       `json:"display_name"`
+	Bio         string       `json:"bio"`
+	Pronouns    string       `json:"pronouns"`
+	Location    string       `json:"location"`
+	Country     string       `json:"country"`
+	AvatarURL   string       `json:"avatar_url"`
+	Links       []model.Link `json:"links"`
+}
+
+// apiAccount is the signed-in user's own view, which adds private fields.
+type apiAccount struct {
+	ID    string `json:"id"`
+	Email string `json:"email"`
+	apiProfile
+}
+
+type apiErrorBody struct {
+	Error apiErrorDetail `json:"error"`
+}
+
+type apiErrorDetail struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+	// Fields holds a localized message per invalid field, for validation
+	// errors only.
+	Fields map[string]string `json:"fields,omitempty"`
+}
+
+func newAPIProfile(u *model.User) apiProfile {
+	return apiProfile{
+		Handle:      u.Name,
+		DisplayName: u.DisplayName,
+		Bio:         u.Bio,
+		Pronouns:    u.Pronouns,
+		Location:    u.Location,
+		Country:     u.Country,
+		AvatarURL:   u.AvatarURL,
+		Links:       links(u.Links),
+	}
+}
+
+// links returns a profile's links, never nil, so the API always sends a
+// list.
+func links(l []model.Link) []model.Link {
+	if l == nil {
+		return []model.Link{}
+	}
+	return l
+}
+
+func newAPIAccount(u *model.User) apiAccount {
+	return apiAccount{ID: u.ID.String(), Email: u.Email, apiProfile: newAPIProfile(u)}
+}
+
+// Signup creates an account and returns a bearer token.
+func (h *APIHandler) Signup(w http.ResponseWriter, r *http.Request) {
+	var body apiSignupRequest
+	if !decodeJSON(w, r, &body) {
+		return
+	}
+	email := strings.TrimSpace(body.Email)
+	handle := model.NormalizeHandle(body.Handle)
+	if err := validateSignup(email, body.Password, body.Password, handle); err != nil {
+		writeAPIError(w, r, err)
+		return
+	}
+
+	token, err := h.authSvc.Signup(r.Context(), email, body.Password, handle)
+	if err != nil {
+		writeAPIError(w, r, err)
+		return
+	}
+	writeJSON(w, http.StatusCreated, apiToken{Token: token})
+}
+
+// Login exchanges credentials for a bearer token.
+func (h *APIHandler) Login(w http.ResponseWriter, r *http.Request) {
+	var body apiLoginRequest
+	if !decodeJSON(w, r, &body) {
+		return
+	}
+	email := strings.TrimSpace(body.Email)
+	if email == "" || body.Password == "" {
+		writeAPIError(w, r, errEmailPasswordRequired)
+		return
+	}
+
+	token, err := h.authSvc.Login(r.Context(), email, body.Password)
+	if err != nil {
+		writeAPIError(w, r, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, apiToken{Token: token})
+}
+
+// Logout clears the session cookie for browser clients. Bearer tokens are
+// stateless, so API clients simply discard theirs.
+func (h *APIHandler) Logout(w http.ResponseWriter, r *http.Request) {
+	clearSessionCookie(w)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// Me returns the signed-in user's account.
+func (h *APIHandler) Me(w http.ResponseWriter, r *http.Request) {
+	user := h.requireScope(w, r, services.ScopeProfileRead)
+	if user == nil {
+		return
+	}
+	writeJSON(w, http.StatusOK, newAPIAccount(user))
+}
+
+// UpdateMe applies a partial profile update; omitted fields are unchanged.
+func (h *APIHandler) UpdateMe(w http.ResponseWriter, r *http.Request) {
+	user := h.requireScope(w, r, services.ScopeProfileWrite)
+	if user == nil {
+		return
+	}
+	var body apiProfileUpdate
+	if !decodeJSON(w, r, &body) {
+		return
+	}
+
+	input := services.UpdateProfileInput{
+		Handle:      user.Name,
+		DisplayName: user.DisplayName,
+		Bio:         user.Bio,
+		Pronouns:    user.Pronouns,
+		Location:    user.Location,
+		Country:     user.Country,
+		Links:       user.Links,
+	}
+	setIfPresent(&input.Handle, body.Handle)
+	setIfPresent(&input.DisplayName, body.DisplayName)
+	setIfPresent(&input.Bio, body.Bio)
+	setIfPresent(&input.Pronouns, body.Pronouns)
+	setIfPresent(&input.Location, body.Location)
+	setIfPresent(&input.Country, body.Country)
+	if body.Links != nil {
+		input.Links = *body.Links
+	}
+
+	if err := h.userSvc.UpdateProfile(r.Context(), user.ID.String(), input); err != nil {
+		writeAPIError(w, r, err)
+		return
+	}
+	h.writeAccount(w, r, user.ID.String())
+}
+
+// UploadAvatar stores the request body as the user's avatar. The body is the
+// raw image and Content-Type must be a supported image type.
+func (h *APIHandler) UploadAvatar(w http.ResponseWriter, r *http.Request) {
+	user := h.requireScope(w, r, services.ScopeProfileWrite)
+	if user == nil {
+		return
+	}
+	contentType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if imageExt(contentType) == "" {
+		writeAPIError(w, r, errUnsupportedImage)
+		return
+	}
+
+	data, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxAvatarBody))
+	if err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			writeAPIError(w, r, errFileTooLarge.Wrap(err))
+			return
+		}
+		writeAPIError(w, r, errInvalidRequest.Wrap(err))
+		return
+	}
+
+	avatarURL, err := uploadAvatar(r.Context(), h.store, user.ID.String(), bytes.NewReader(data), int64(len(data)), contentType)
+	if err != nil {
+		writeAPIError(w, r, err)
+		return
+	}
+	if err := h.userSvc.SetAvatar(r.Context(), user.ID.String(), avatarURL); err != nil {
+		writeAPIError(w, r, err)
+		return
+	}
+	h.writeAccount(w, r, user.ID.String())
+}
+
+// Profile returns the public profile for the handle in the path.
+func (h *APIHandler) Profile(w http.ResponseWriter, r *http.Request) {
+	profile, err := LookupProfile(r, h.userSvc, "/api/v1/users/")
+	var moved *Redirect
+	if errors.As(err, &moved) {
+		http.Redirect(w, r, moved.URL, moved.Status)
+		return
+	}
+	if err != nil {
+		writeAPIError(w, r, err)
+		return
+	}
+
+	// Callers that could read the profile as themselves see what they
+	// would see signed in; everyone else gets the public view.
+	var viewer *model.User
+	if id := h.authSvc.Authenticate(r); id != nil && id.Can(services.ScopeProfileRead) {
+		viewer = id.User
+	}
+	view, err := h.privacySvc.View(r.Context(), viewer, profile)
+	if err != nil {
+		writeAPIError(w, r, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, newAPIProfile(view))
+}
+
+func (h *APIHandler) writeAccount(w http.ResponseWriter, r *http.Request, userID string) {
+	user, err := h.userSvc.GetByID(r.Context(), userID)
+	if err != nil {
+		writeAPIError(w, r, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, newAPIAccount(user))
+}
+
+// requireScope authenticates the caller, accepting sessions and personal
+// access tokens, and checks that it holds scope. It writes a 401 or 403 and
+// returns nil otherwise.
+func (h *APIHandler) requireScope(w http.ResponseWriter, r *http.Request, scope string) *model.User {
+	id := h.authSvc.Authenticate(r)
+	if id == nil {
+		w.Header().Set("WWW-Authenticate", "Bearer")
+		writeAPIError(w, r, errUnauthorized)
+		return nil
+	}
+	if !id.Can(scope) {
+		w.Header().Set("WWW-Authenticate", `Bearer error="insufficient_scope", scope="`+scope+`"`)
+		writeAPIError(w, r, errInsufficientScope)
+		return nil
+	}
+	return id.User
+}
+
+func setIfPresent(dst, src *string) {
+	if src != nil {
+		*dst = strings.TrimSpace(*src)
+	}
+}
+
+func decodeJSON(w http.ResponseWriter, r *http.Request, dst any) bool {
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxJSONBody)).Decode(dst); err != nil {
+		writeAPIError(w, r, errInvalidRequest.Wrap(err))
+		return false
+	}
+	return true
+}
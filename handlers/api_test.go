@@ -0,0 +1,380 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"myapp/i18n"
+	"myapp/model"
+	"myapp/services"
+	"myapp/storage"
+	"myapp/testutil"
+)
+
+func newTestAPIHandler(t *testing.T) (*APIHandler, *services.AuthService) {
+	t.Helper()
+	if err := i18n.Load(); err != nil {
+		t.Fatalf("failed to load translations: %v", err)
+	}
+	db := testutil.NewTestDB(t, &model.User{}, &model.HandleHistory{}, &model.Follow{}, &model.Block{})
+	repo := model.NewUserRepository(db)
+	authSvc := services.NewAuthService(repo, "test-secret", nil, services.EmailPolicy{}, nil, nil)
+	store, err := storage.NewLocalStorage(t.TempDir(), "https://cdn.example.com")
+	if err != nil {
+		t.Fatalf("NewLocalStorage failed: %v", err)
+	}
+	privacySvc := services.NewPrivacyService(repo, services.NewFollowService(model.NewFollowRepository(db), model.NewBlockRepository(db), repo))
+	return NewAPIHandler(authSvc, services.NewUserService(repo, nil), privacySvc, store), authSvc
+}
+
+// callAPI sends body (JSON-encoded unless it is already []byte) and decodes
+// the JSON response into a map.
+func callAPI(t *testing.T, handler http.HandlerFunc, method, target, token string, body any) (int, map[string]any) {
+	t.Helper()
+	var reader *bytes.Reader
+	switch b := body.(type) {
+	case nil:
+		reader = bytes.NewReader(nil)
+	case []byte:
+		reader = bytes.NewReader(b)
+	default:
+		data, err := json.Marshal(b)
+		if err != nil {
+			t.Fatal(err)
+		}
+		reader = bytes.NewReader(data)
+	}
+	req := httptest.NewRequest(method, target, reader)
+	req.Header.Set("Content-Type", "application/json")
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	w := httptest.NewRecorder()
+	handler(w, req)
+
+	var out map[string]any
+	if w.Body.Len() > 0 {
+		if err := json.NewDecoder(w.Body).Decode(&out); err != nil {
+			t.Fatalf("invalid JSON response: %v", err)
+		}
+	}
+	return w.Code, out
+}
+
+func errorCode(body map[string]any) string {
+	e, _ := body["error"].(map[string]any)
+	code, _ := e["code"].(string)
+	return code
+}
+
+func TestAPISignup(t *testing.T) {
+	valid := map[string]string{"email": "user@example.com", "password": "password123", "handle": "testuser"}
+
+	t.Run("success returns token", func(t *testing.T) {
+		h, _ := newTestAPIHandler(t)
+		code, body := callAPI(t, h.Signup, http.MethodPost, "/api/v1/auth/signup", "", valid)
+		if code != http.StatusCreated {
+			t.Fatalf("expected 201, got %d: %v", code, body)
+		}
+		if token, _ := body["token"].(string); token == "" {
+			t.Error("expected token in response")
+		}
+	})
+
+	t.Run("validation error", func(t *testing.T) {
+		h, _ := newTestAPIHandler(t)
+		code, body := callAPI(t, h.Signup, http.MethodPost, "/api/v1/auth/signup", "",
+			map[string]string{"email": "user@example.com", "password": "short", "handle": "testuser"})
+		if code != http.StatusUnprocessableEntity || errorCode(body) != "passwordTooShort" {
+			t.Errorf("expected 422 passwordTooShort, got %d %v", code, body)
+		}
+		fields, _ := body["error"].(map[string]any)["fields"].(map[string]any)
+		if fields["password"] == nil {
+			t.Errorf("expected a password field error, got %v", body)
+		}
+	})
+
+	t.Run("duplicate email conflicts", func(t *testing.T) {
+		h, _ := newTestAPIHandler(t)
+		callAPI(t, h.Signup, http.MethodPost, "/api/v1/auth/signup", "", valid)
+		code, body := callAPI(t, h.Signup, http.MethodPost, "/api/v1/auth/signup", "",
+			map[string]string{"email": "user@example.com", "password": "password123", "handle": "otheruser"})
+		if code != http.StatusConflict || errorCode(body) != "emailTaken" {
+			t.Errorf("expected 409 emailTaken, got %d %v", code, body)
+		}
+	})
+
+	t.Run("malformed body", func(t *testing.T) {
+		h, _ := newTestAPIHandler(t)
+		code, body := callAPI(t, h.Signup, http.MethodPost, "/api/v1/auth/signup", "", []byte("{"))
+		if code != http.StatusBadRequest || errorCode(body) != "invalidRequest" {
+			t.Errorf("expected 400 invalidRequest, got %d %v", code, body)
+		}
+	})
+}
+
+func TestAPILogin(t *testing.T) {
+	h, authSvc := newTestAPIHandler(t)
+	_, _ = authSvc.Signup(context.Background(), "user@example.com", "password123", "testuser")
+
+	t.Run("wrong password", func(t *testing.T) {
+		code, body := callAPI(t, h.Login, http.MethodPost, "/api/v1/auth/login", "",
+			map[string]string{"email": "user@example.com", "password": "wrong-password"})
+		if code != http.StatusUnauthorized || errorCode(body) != "invalidCredentials" {
+			t.Errorf("expected 401 invalidCredentials, got %d %v", code, body)
+		}
+	})
+
+	t.Run("token authenticates requests", func(t *testing.T) {
+		code, body := callAPI(t, h.Login, http.MethodPost, "/api/v1/auth/login", "",
+			map[string]string{"email": "user@example.com", "password": "password123"})
+		if code != http.StatusOK {
+			t.Fatalf("expected 200, got %d %v", code, body)
+		}
+		token, _ := body["token"].(string)
+
+		code, me := callAPI(t, h.Me, http.MethodGet, "/api/v1/me", token, nil)
+		if code != http.StatusOK || me["email"] != "user@example.com" || me["handle"] != "testuser" {
+			t.Errorf("expected own account, got %d %v", code, me)
+		}
+	})
+}
+
+func TestAPIMe(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("unauthenticated", func(t *testing.T) {
+		h, _ := newTestAPIHandler(t)
+		code, body := callAPI(t, h.Me, http.MethodGet, "/api/v1/me", "", nil)
+		if code != http.StatusUnauthorized || errorCode(body) != "unauthorized" {
+			t.Errorf("expected 401 unauthorized, got %d %v", code, body)
+		}
+	})
+
+	t.Run("partial update keeps omitted fields", func(t *testing.T) {
+		h, authSvc := newTestAPIHandler(t)
+		token, _ := authSvc.Signup(ctx, "user@example.com", "password123", "testuser")
+		callAPI(t, h.UpdateMe, http.MethodPatch, "/api/v1/me", token, map[string]string{"bio": "First bio"})
+
+		code, body := callAPI(t, h.UpdateMe, http.MethodPatch, "/api/v1/me", token, map[string]string{"display_name": "Test User"})
+		if code != http.StatusOK {
+			t.Fatalf("expected 200, got %d %v", code, body)
+		}
+		if body["display_name"] != "Test User" || body["bio"] != "First bio" || body["handle"] != "testuser" {
+			t.Errorf("unexpected account after update: %v", body)
+		}
+	})
+
+	t.Run("links replace the whole list", func(t *testing.T) {
+		h, authSvc := newTestAPIHandler(t)
+		token, _ := authSvc.Signup(ctx, "user@example.com", "password123", "testuser")
+		code, body := callAPI(t, h.UpdateMe, http.MethodPatch, "/api/v1/me", token, map[string]any{
+			"pronouns": "she/her",
+			"links": []map[string]string{
+				{"type": "github", "url": "https://github.com/testuser"},
+				{"type": "website", "url": "https://example.com", "label": "Blog"},
+			},
+		})
+		if code != http.StatusOK {
+			t.Fatalf("expected 200, got %d %v", code, body)
+		}
+		links, _ := body["links"].([]any)
+		if body["pronouns"] != "she/her" || len(links) != 2 {
+			t.Fatalf("unexpected account after update: %v", body)
+		}
+
+		code, body = callAPI(t, h.UpdateMe, http.MethodPatch, "/api/v1/me", token, map[string]any{"links": []any{}})
+		if links, _ := body["links"].([]any); code != http.StatusOK || links == nil || len(links) != 0 {
+			t.Errorf("expected links to be cleared, got %d %v", code, body)
+		}
+
+		code, body = callAPI(t, h.UpdateMe, http.MethodPatch, "/api/v1/me", token, map[string]any{
+			"links": []map[string]string{{"type": "github", "url": "https://gitlab.com/testuser"}},
+		})
+		if code != http.StatusUnprocessableEntity || errorCode(body) != "githubInvalid" {
+			t.Errorf("expected 422 githubInvalid, got %d %v", code, body)
+		}
+	})
+
+	t.Run("handle taken", func(t *testing.T) {
+		h, authSvc := newTestAPIHandler(t)
+		_, _ = authSvc.Signup(ctx, "user1@example.com", "password123", "user1hnd")
+		token, _ := authSvc.Signup(ctx, "user2@example.com", "password123", "user2hnd")
+		code, body := callAPI(t, h.UpdateMe, http.MethodPatch, "/api/v1/me", token, map[string]string{"handle": "user1hnd"})
+		if code != http.StatusConflict || errorCode(body) != "handleTaken" {
+			t.Errorf("expected 409 handleTaken, got %d %v", code, body)
+		}
+	})
+}
+
+func TestAPIProfileBlocked(t *testing.T) {
+	ctx := context.Background()
+	if err := i18n.Load(); err != nil {
+		t.Fatalf("failed to load translations: %v", err)
+	}
+	db := testutil.NewTestDB(t, &model.User{}, &model.HandleHistory{}, &model.Follow{}, &model.Block{})
+	users := model.NewUserRepository(db)
+	authSvc := services.NewAuthService(users, "test-secret", nil, services.EmailPolicy{}, nil, nil)
+	followSvc := services.NewFollowService(model.NewFollowRepository(db), model.NewBlockRepository(db), users)
+	h := NewAPIHandler(authSvc, services.NewUserService(users, nil), services.NewPrivacyService(users, followSvc), storage.Noop())
+
+	_, _ = authSvc.Signup(ctx, "alice@example.com", "password123", "alice")
+	session, _ := authSvc.Signup(ctx, "bob@example.com", "password123", "bob")
+	alice, _ := users.GetByHandle(ctx, "alice")
+	if err := followSvc.Block(ctx, alice, "bob"); err != nil {
+		t.Fatalf("Block failed: %v", err)
+	}
+
+	get := func(token string) int {
+		req := httptest.NewRequest(http.MethodGet, "/api/v1/users/alice", nil)
+		req.SetPathValue("handle", "alice")
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		w := httptest.NewRecorder()
+		h.Profile(w, req)
+		return w.Code
+	}
+	if code := get(session); code != http.StatusNotFound {
+		t.Errorf("expected 404 for the blocked user, got %d", code)
+	}
+	if code := get(""); code != http.StatusOK {
+		t.Errorf("expected 200 for signed-out visitors, got %d", code)
+	}
+}
+
+func TestAPIUploadAvatar(t *testing.T) {
+	ctx := context.Background()
+	h, authSvc := newTestAPIHandler(t)
+	token, _ := authSvc.Signup(ctx, "user@example.com", "password123", "testuser")
+
+	upload := func(contentType string) (int, map[string]any) {
+		req := httptest.NewRequest(http.MethodPut, "/api/v1/me/avatar", strings.NewReader("fake image bytes"))
+		req.Header.Set("Content-Type", contentType)
+		req.Header.Set("Authorization", "Bearer "+token)
+		w := httptest.NewRecorder()
+		h.UploadAvatar(w, req)
+		var body map[string]any
+		_ = json.NewDecoder(w.Body).Decode(&body)
+		return w.Code, body
+	}
+
+	t.Run("unsupported type", func(t *testing.T) {
+		code, body := upload("text/plain")
+		if code != http.StatusUnsupportedMediaType || errorCode(body) != "unsupportedImage" {
+			t.Errorf("expected 415 unsupportedImage, got %d %v", code, body)
+		}
+	})
+
+	t.Run("stores avatar", func(t *testing.T) {
+		code, body := upload("image/png")
+		if code != http.StatusOK {
+			t.Fatalf("expected 200, got %d %v", code, body)
+		}
+		if url, _ := body["avatar_url"].(string); !strings.HasPrefix(url, "https://cdn.example.com/avatars/") {
+			t.Errorf("unexpected avatar_url %q", url)
+		}
+	})
+}
+
+func TestAPIProfile(t *testing.T) {
+	h, authSvc := newTestAPIHandler(t)
+	_, _ = authSvc.Signup(context.Background(), "user@example.com", "password123", "testuser")
+
+	get := func(handle string) (int, map[string]any) {
+		req := httptest.NewRequest(http.MethodGet, "/api/v1/users/"+handle, nil)
+		req.Header.Set("Accept-Language", "es")
+		req.SetPathValue("handle", handle)
+		w := httptest.NewRecorder()
+		h.Profile(w, req)
+		var body map[string]any
+		_ = json.NewDecoder(w.Body).Decode(&body)
+		return w.Code, body
+	}
+
+	t.Run("public fields only", func(t *testing.T) {
+		code, body := get("testuser")
+		if code != http.StatusOK || body["handle"] != "testuser" {
+			t.Fatalf("expected profile, got %d %v", code, body)
+		}
+		if _, ok := body["email"]; ok {
+			t.Error("public profile must not expose email")
+		}
+	})
+
+	t.Run("hidden fields are left out", func(t *testing.T) {
+		user, _ := h.userSvc.GetByHandle(context.Background(), "testuser")
+		if err := h.userSvc.UpdateProfile(context.Background(), user.ID.String(), services.UpdateProfileInput{
+			Handle: "testuser", Bio: "Secret", Location: "Lisbon",
+		}); err != nil {
+			t.Fatalf("UpdateProfile failed: %v", err)
+		}
+		if err := h.privacySvc.UpdatePrivacy(context.Background(), user.ID.String(), services.UpdatePrivacyInput{
+			Visibility: model.ProfileVisibility{model.FieldBio: model.VisibilityFollowers},
+		}); err != nil {
+			t.Fatalf("UpdatePrivacy failed: %v", err)
+		}
+		_, body := get("testuser")
+		if body["bio"] != "" || body["location"] != "Lisbon" {
+			t.Errorf("expected only the bio to be hidden, got %v", body)
+		}
+	})
+
+	t.Run("old handle redirects to the current one", func(t *testing.T) {
+		user, _ := h.userSvc.GetByHandle(context.Background(), "testuser")
+		if err := h.userSvc.UpdateProfile(context.Background(), user.ID.String(), services.UpdateProfileInput{Handle: "renamed"}); err != nil {
+			t.Fatalf("UpdateProfile failed: %v", err)
+		}
+		req := httptest.NewRequest(http.MethodGet, "/api/v1/users/testuser", nil)
+		req.SetPathValue("handle", "testuser")
+		w := httptest.NewRecorder()
+		h.Profile(w, req)
+		if w.Code != http.StatusMovedPermanently || w.Header().Get("Location") != "/api/v1/users/renamed" {
+			t.Errorf("expected 301 to /api/v1/users/renamed, got %d %q", w.Code, w.Header().Get("Location"))
+		}
+	})
+
+	t.Run("unknown handle has localized error", func(t *testing.T) {
+		code, body := get("nobody")
+		if code != http.StatusNotFound {
+			t.Fatalf("expected 404, got %d", code)
+		}
+		e, _ := body["error"].(map[string]any)
+		if e["code"] != "notFound" || e["message"] != i18n.T("es", "error.notFound") {
+			t.Errorf("unexpected error body %v", body)
+		}
+	})
+}
+
+func TestAPITokenScopes(t *testing.T) {
+	ctx := context.Background()
+	db := testutil.NewTestDB(t, &model.User{}, &model.HandleHistory{}, &model.PersonalAccessToken{}, &model.Follow{}, &model.Block{})
+	users := model.NewUserRepository(db)
+	tokenSvc := services.NewTokenService(model.NewTokenRepository(db), users)
+	authSvc := services.NewAuthService(users, "test-secret", nil, services.EmailPolicy{}, tokenSvc, nil)
+	privacySvc := services.NewPrivacyService(users, services.NewFollowService(model.NewFollowRepository(db), model.NewBlockRepository(db), users))
+	h := NewAPIHandler(authSvc, services.NewUserService(users, nil), privacySvc, storage.Noop())
+
+	session, _ := authSvc.Signup(ctx, "user@example.com", "password123", "testuser")
+	user := authSvc.GetUserFromRequest(sessionRequest(session))
+	readOnly, _, _ := tokenSvc.Create(ctx, user.ID.String(), "read", []string{services.ScopeProfileRead}, 0)
+
+	t.Run("read scope allows me", func(t *testing.T) {
+		code, body := callAPI(t, h.Me, http.MethodGet, "/api/v1/me", readOnly, nil)
+		if code != http.StatusOK || body["handle"] != "testuser" {
+			t.Errorf("expected own account, got %d %v", code, body)
+		}
+	})
+
+	t.Run("write without scope is forbidden", func(t *testing.T) {
+		code, body := callAPI(t, h.UpdateMe, http.MethodPatch, "/api/v1/me", readOnly, map[string]string{"bio": "nope"})
+		if code != http.StatusForbidden || errorCode(body) != "insufficientScope" {
+			t.Errorf("expected 403 insufficientScope, got %d %v", code, body)
+		}
+	})
+}
@@ -1,62 +1,39 @@
 package handlers
 
 import (
-	"errors"
 	"net/http"
-	"net/url"
 	"strings"
 
-	"myapp/i18n"
 	"myapp/model"
 	"myapp/services"
+	"myapp/util"
 )
 
 type AuthHandler struct {
-	svc *services.AuthService
+	svc   *services.AuthService
+	flash *Flasher
 }
 
-func NewAuthHandler(svc *services.AuthService) *AuthHandler {
-	return &AuthHandler{svc: svc}
+func NewAuthHandler(svc *services.AuthService, flash *Flasher) *AuthHandler {
+	return &AuthHandler{svc: svc, flash: flash}
 }
 
 func (h *AuthHandler) Signup() http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		locale := i18n.DetectLocale(r)
 		email := strings.TrimSpace(r.FormValue("email"))
 		password := r.FormValue("password")
 		confirmPassword := r.FormValue("confirm_password")
-		handle := strings.TrimSpace(r.FormValue("handle"))
+		handle := model.NormalizeHandle(r.FormValue("handle"))
+		values := map[string]string{"email": email, "handle": handle}
 
-		if email == "" || password == "" {
-			http.Redirect(w, r, "/signup?error="+url.QueryEscape(i18n.T(locale, "error.emailPasswordRequired")), http.StatusSeeOther)
-			return
-		}
-		if handle == "" {
-			http.Redirect(w, r, "/signup?error="+url.QueryEscape(i18n.T(locale, "error.handleRequired")), http.StatusSeeOther)
-			return
-		}
-		if !model.HandleRegex.MatchString(handle) {
-			http.Redirect(w, r, "/signup?error="+url.QueryEscape(i18n.T(locale, "error.handleInvalid")), http.StatusSeeOther)
-			return
-		}
-		if password != confirmPassword {
-			http.Redirect(w, r, "/signup?error="+url.QueryEscape(i18n.T(locale, "error.passwordsMismatch")), http.StatusSeeOther)
-			return
-		}
-		if len(password) < 8 {
-			http.Redirect(w, r, "/signup?error="+url.QueryEscape(i18n.T(locale, "error.passwordTooShort")), http.StatusSeeOther)
+		if err := validateSignup(email, password, confirmPassword, handle); err != nil {
+			h.flash.redirectWithError(w, r, "/signup", err, values)
 			return
 		}
 
 		token, err := h.svc.Signup(r.Context(), email, password, handle)
 		if err != nil {
-			errKey := "error.somethingWrong"
-			if errors.Is(err, services.ErrEmailTaken) {
-				errKey = "error.emailTaken"
-			} else if errors.Is(err, services.ErrHandleTaken) {
-				errKey = "error.handleTaken"
-			}
-			http.Redirect(w, r, "/signup?error="+url.QueryEscape(i18n.T(locale, errKey)), http.StatusSeeOther)
+			h.flash.redirectWithError(w, r, "/signup", err, values)
 			return
 		}
 
@@ -65,20 +42,43 @@ func (h *AuthHandler) Signup() http.HandlerFunc {
 	}
 }
 
+var errEmailPasswordRequired = util.Validation("error.emailPasswordRequired", map[string]string{
+	"email":    "error.emailPasswordRequired",
+	"password": "error.emailPasswordRequired",
+})
+
+// validateSignup checks the signup fields shared by the form and JSON
+// endpoints and returns a validation error for the first problem, or nil.
+func validateSignup(email, password, confirmPassword, handle string) error {
+	switch {
+	case email == "" || password == "":
+		return errEmailPasswordRequired
+	case handle == "":
+		return util.Invalid("handle", "error.handleRequired")
+	case !model.HandleRegex.MatchString(handle):
+		return services.ErrHandleInvalid
+	case password != confirmPassword:
+		return util.Invalid("confirm_password", "error.passwordsMismatch")
+	case len(password) < 8:
+		return util.Invalid("password", "error.passwordTooShort")
+	}
+	return nil
+}
+
 func (h *AuthHandler) Login() http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		locale := i18n.DetectLocale(r)
 		email := strings.TrimSpace(r.FormValue("email"))
 		password := r.FormValue("password")
+		values := map[string]string{"email": email}
 
 		if email == "" || password == "" {
-			http.Redirect(w, r, "/login?error="+url.QueryEscape(i18n.T(locale, "error.emailPasswordRequired")), http.StatusSeeOther)
+			h.flash.redirectWithError(w, r, "/login", errEmailPasswordRequired, values)
 			return
 		}
 
 		token, err := h.svc.Login(r.Context(), email, password)
 		if err != nil {
-			http.Redirect(w, r, "/login?error="+url.QueryEscape(i18n.T(locale, "error.invalidCredentials")), http.StatusSeeOther)
+			h.flash.redirectWithError(w, r, "/login", err, values)
 			return
 		}
 
@@ -14,8 +14,8 @@ import (
 
 func newTestHandler(t *testing.T) *AuthHandler {
 	t.Helper()
-	repo := model.NewUserRepository(testutil.NewTestDB(t, &model.User{}))
-	return NewAuthHandler(services.NewAuthService(repo))
+	repo := model.NewUserRepository(testutil.NewTestDB(t, &model.User{}, &model.HandleHistory{}))
+	return NewAuthHandler(services.NewAuthService(repo, "test-secret", nil, services.EmailPolicy{}, nil, nil), testFlasher)
 }
 
 func postForm(handler http.HandlerFunc, target string, values url.Values) *httptest.ResponseRecorder {
@@ -40,35 +40,30 @@ func TestHandlerSignup(t *testing.T) {
 		w := postForm(newTestHandler(t).Signup(), "/api/signup", url.Values{
 			"email": {""}, "password": {""}, "confirm_password": {""}, "handle": {""},
 		})
-		if w.Code != http.StatusSeeOther {
-			t.Errorf("expected %d, got %d", http.StatusSeeOther, w.Code)
-		}
-		if loc := w.Header().Get("Location"); !strings.HasPrefix(loc, "/signup?error=") {
-			t.Errorf("expected /signup?error=..., got %s", loc)
-		}
+		expectFlash(t, w, "/signup")
 	})
 
 	t.Run("missing handle redirects to signup with error", func(t *testing.T) {
 		w := postForm(newTestHandler(t).Signup(), "/api/signup", url.Values{
 			"email": {"user@example.com"}, "password": {"password123"}, "confirm_password": {"password123"}, "handle": {""},
 		})
-		if w.Code != http.StatusSeeOther {
-			t.Errorf("expected %d, got %d", http.StatusSeeOther, w.Code)
-		}
-		if loc := w.Header().Get("Location"); !strings.HasPrefix(loc, "/signup?error=") {
-			t.Errorf("expected /signup?error=..., got %s", loc)
-		}
+		expectFlash(t, w, "/signup")
 	})
 
 	t.Run("invalid handle redirects to signup with error", func(t *testing.T) {
 		w := postForm(newTestHandler(t).Signup(), "/api/signup", url.Values{
 			"email": {"user@example.com"}, "password": {"password123"}, "confirm_password": {"password123"}, "handle": {"ab"},
 		})
-		if w.Code != http.StatusSeeOther {
-			t.Errorf("expected %d, got %d", http.StatusSeeOther, w.Code)
-		}
-		if loc := w.Header().Get("Location"); !strings.HasPrefix(loc, "/signup?error=") {
-			t.Errorf("expected /signup?error=..., got %s", loc)
+		expectFlash(t, w, "/signup")
+	})
+
+	t.Run("invalid email redirects to signup with error", func(t *testing.T) {
+		w := postForm(newTestHandler(t).Signup(), "/api/signup", url.Values{
+			"email": {"not-an-email"}, "password": {"password123"}, "confirm_password": {"password123"}, "handle": {"testuser"},
+		})
+		flash := expectFlash(t, w, "/signup")
+		if flash.Fields["email"] != "error.emailInvalid" {
+			t.Errorf("expected an email error, got %v", flash.Fields)
 		}
 	})
 
@@ -76,23 +71,31 @@ func TestHandlerSignup(t *testing.T) {
 		w := postForm(newTestHandler(t).Signup(), "/api/signup", url.Values{
 			"email": {"user@example.com"}, "password": {"password123"}, "confirm_password": {"different123"}, "handle": {"testuser"},
 		})
-		if w.Code != http.StatusSeeOther {
-			t.Errorf("expected %d, got %d", http.StatusSeeOther, w.Code)
-		}
-		if loc := w.Header().Get("Location"); !strings.HasPrefix(loc, "/signup?error=") {
-			t.Errorf("expected /signup?error=..., got %s", loc)
-		}
+		expectFlash(t, w, "/signup")
 	})
 
 	t.Run("password too short redirect to signup with error", func(t *testing.T) {
 		w := postForm(newTestHandler(t).Signup(), "/api/signup", url.Values{
 			"email": {"user@example.com"}, "password": {"short"}, "confirm_password": {"short"}, "handle": {"testuser"},
 		})
-		if w.Code != http.StatusSeeOther {
-			t.Errorf("expected %d, got %d", http.StatusSeeOther, w.Code)
+		expectFlash(t, w, "/signup")
+	})
+
+	t.Run("flash keeps what was typed except passwords", func(t *testing.T) {
+		w := postForm(newTestHandler(t).Signup(), "/api/signup", url.Values{
+			"email": {"user@example.com"}, "password": {"password123"}, "confirm_password": {"different123"}, "handle": {"testuser"},
+		})
+		flash := expectFlash(t, w, "/signup")
+		if flash.Values["email"] != "user@example.com" || flash.Values["handle"] != "testuser" {
+			t.Errorf("expected email and handle to be kept, got %v", flash.Values)
+		}
+		if flash.Fields["confirm_password"] != "error.passwordsMismatch" {
+			t.Errorf("expected the mismatch on confirm_password, got %v", flash.Fields)
 		}
-		if loc := w.Header().Get("Location"); !strings.HasPrefix(loc, "/signup?error=") {
-			t.Errorf("expected /signup?error=..., got %s", loc)
+		for field, v := range flash.Values {
+			if strings.Contains(v, "123") {
+				t.Errorf("password leaked into the flash as %s", field)
+			}
 		}
 	})
 
@@ -120,12 +123,7 @@ func TestHandlerSignup(t *testing.T) {
 		w := postForm(h.Signup(), "/api/signup", url.Values{
 			"email": {"user@example.com"}, "password": {"password123"}, "confirm_password": {"password123"}, "handle": {"user2hnd"},
 		})
-		if w.Code != http.StatusSeeOther {
-			t.Errorf("expected %d, got %d", http.StatusSeeOther, w.Code)
-		}
-		if loc := w.Header().Get("Location"); !strings.HasPrefix(loc, "/signup?error=") {
-			t.Errorf("expected /signup?error=..., got %s", loc)
-		}
+		expectFlash(t, w, "/signup")
 	})
 
 	t.Run("duplicate handle redirect to signup with error", func(t *testing.T) {
@@ -137,12 +135,7 @@ func TestHandlerSignup(t *testing.T) {
 		w := postForm(h.Signup(), "/api/signup", url.Values{
 			"email": {"user2@example.com"}, "password": {"password123"}, "confirm_password": {"password123"}, "handle": {"testuser"},
 		})
-		if w.Code != http.StatusSeeOther {
-			t.Errorf("expected %d, got %d", http.StatusSeeOther, w.Code)
-		}
-		if loc := w.Header().Get("Location"); !strings.HasPrefix(loc, "/signup?error=") {
-			t.Errorf("expected /signup?error=..., got %s", loc)
-		}
+		expectFlash(t, w, "/signup")
 	})
 }
 
@@ -151,24 +144,14 @@ func TestHandlerLogin(t *testing.T) {
 		w := postForm(newTestHandler(t).Login(), "/api/login", url.Values{
 			"email": {""}, "password": {""},
 		})
-		if w.Code != http.StatusSeeOther {
-			t.Errorf("expected %d, got %d", http.StatusSeeOther, w.Code)
-		}
-		if loc := w.Header().Get("Location"); !strings.HasPrefix(loc, "/login?error=") {
-			t.Errorf("expected /login?error=..., got %s", loc)
-		}
+		expectFlash(t, w, "/login")
 	})
 
 	t.Run("invalid credentials redirect to login with error", func(t *testing.T) {
 		w := postForm(newTestHandler(t).Login(), "/api/login", url.Values{
 			"email": {"nobody@example.com"}, "password": {"password123"},
 		})
-		if w.Code != http.StatusSeeOther {
-			t.Errorf("expected %d, got %d", http.StatusSeeOther, w.Code)
-		}
-		if loc := w.Header().Get("Location"); !strings.HasPrefix(loc, "/login?error=") {
-			t.Errorf("expected /login?error=..., got %s", loc)
-		}
+		expectFlash(t, w, "/login")
 	})
 
 	t.Run("success sets session cookie and redirects home", func(t *testing.T) {
@@ -0,0 +1,59 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+
+	"myapp/i18n"
+	"myapp/util"
+)
+
+var (
+	errRateLimited       = util.NewError(util.KindRateLimited, "error.rateLimited")
+	errInvalidRequest    = util.BadRequest("error.invalidRequest")
+	errUnauthorized      = util.Unauthorized("error.unauthorized")
+	errInsufficientScope = util.Forbidden("error.insufficientScope")
+	errFileTooLarge      = util.NewError(util.KindTooLarge, "error.fileTooLarge")
+	errUnsupportedImage  = util.NewError(util.KindUnsupported, "error.unsupportedImage")
+)
+
+// translateError resolves err to the AppError shown to the user. This is
+// the only place errors become statuses and messages, for HTML and JSON
+// alike. Anything that is not an AppError is treated as internal; internal
+// errors are logged and presented generically so causes never leak.
+func translateError(r *http.Request, err error) *util.AppError {
+	var appErr *util.AppError
+	if !errors.As(err, &appErr) {
+		appErr = util.Internal(err)
+	}
+	if appErr.Kind == util.KindInternal {
+		util.Logger(r.Context()).Error("request failed", "error", err)
+	}
+	return appErr
+}
+
+// localizeFields translates field errors given as i18n keys, or returns nil.
+func localizeFields(locale string, fields map[string]string) map[string]string {
+	if len(fields) == 0 {
+		return nil
+	}
+	localized := make(map[string]string, len(fields))
+	for field, key := range fields {
+		localized[field] = i18n.T(locale, key)
+	}
+	return localized
+}
+
+// writeAPIError writes {"error": {"code", "message", "fields"}}. The code is
+// the i18n key without its "error." prefix, so clients can branch on it, and
+// the messages are localized for the request.
+func writeAPIError(w http.ResponseWriter, r *http.Request, err error) {
+	appErr := translateError(r, err)
+	locale := i18n.DetectLocale(r)
+	writeJSON(w, appErr.Status(), apiErrorBody{Error: apiErrorDetail{
+		Code:    strings.TrimPrefix(appErr.Message, "error."),
+		Message: i18n.T(locale, appErr.Message),
+		Fields:  localizeFields(locale, appErr.Fields),
+	}})
+}
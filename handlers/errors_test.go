@@ -0,0 +1,81 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"myapp/i18n"
+	"myapp/model"
+	"myapp/util"
+)
+
+func TestTranslateError(t *testing.T) {
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+
+	tests := []struct {
+		name    string
+		err     error
+		status  int
+		message string
+	}{
+		{"app error", model.ErrNotFound, http.StatusNotFound, "error.notFound"},
+		{"wrapped app error", fmt.Errorf("load profile: %w", model.ErrNotFound), http.StatusNotFound, "error.notFound"},
+		{"unknown error", errors.New("disk on fire"), http.StatusInternalServerError, "error.somethingWrong"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			appErr := translateError(req, tt.err)
+			if appErr.Status() != tt.status || appErr.Message != tt.message {
+				t.Errorf("got %d %q, want %d %q", appErr.Status(), appErr.Message, tt.status, tt.message)
+			}
+		})
+	}
+}
+
+func TestErrorResponses(t *testing.T) {
+	if err := i18n.Load(); err != nil {
+		t.Fatalf("failed to load translations: %v", err)
+	}
+	invalid := util.Invalid("handle", "error.handleInvalid")
+
+	t.Run("json includes localized fields", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.Header.Set("Accept-Language", "es")
+		w := httptest.NewRecorder()
+		writeAPIError(w, req, invalid)
+
+		var body apiErrorBody
+		_ = json.NewDecoder(w.Body).Decode(&body)
+		want := i18n.T("es", "error.handleInvalid")
+		if w.Code != http.StatusUnprocessableEntity || body.Error.Code != "handleInvalid" || body.Error.Fields["handle"] != want {
+			t.Errorf("unexpected response %d %+v", w.Code, body)
+		}
+	})
+
+	t.Run("html flashes the same message", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodPost, "/api/user/update", nil)
+		w := httptest.NewRecorder()
+		testFlasher.redirectWithError(w, req, "/user/someone/edit", invalid, nil)
+
+		flash := expectFlash(t, w, "/user/someone/edit")
+		if flash.Error != "error.handleInvalid" || flash.Fields["handle"] != "error.handleInvalid" {
+			t.Errorf("unexpected flash %+v", flash)
+		}
+	})
+
+	t.Run("internal errors stay generic", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		w := httptest.NewRecorder()
+		writeAPIError(w, req, errors.New("password for db is hunter2"))
+
+		var body apiErrorBody
+		_ = json.NewDecoder(w.Body).Decode(&body)
+		if w.Code != http.StatusInternalServerError || body.Error.Message != i18n.T("en", "error.somethingWrong") {
+			t.Errorf("unexpected response %d %+v", w.Code, body)
+		}
+	})
+}
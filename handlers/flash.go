@@ -0,0 +1,170 @@
+package handlers
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"myapp/i18n"
+)
+
+const (
+	flashCookie = "flash"
+	// maxFlashCookie keeps the cookie under the 4 KB browsers accept. Larger
+	// flashes drop the submitted values and keep only the errors.
+	maxFlashCookie = 3800
+)
+
+type flashKey struct{}
+
+// Flash carries the outcome of a form submission across its redirect: the
+// error, any per-field errors, and what the user typed so the form can be
+// filled in again. Messages are i18n keys, localized when the page renders,
+// so a flash can only ever show text the app itself defines.
+type Flash struct {
+	Error  string            `json:"e,omitempty"`
+	Fields map[string]string `json:"f,omitempty"`
+	Values map[string]string `json:"v,omitempty"`
+}
+
+// Flasher signs flashes into a short-lived cookie scoped to the page they
+// are for, and reveals them to that page's loader exactly once.
+type Flasher struct {
+	key []byte
+}
+
+// NewFlasher derives the signing key from secret, so the same application
+// secret can sign sessions and flashes without sharing a key.
+func NewFlasher(secret string) *Flasher {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte("flash"))
+	return &Flasher{key: mac.Sum(nil)}
+}
+
+// redirectWithError sends a form submission back to target, flashing err's
+// message and field errors together with values, the input to refill the
+// form with. Passwords must never be passed in values.
+func (f *Flasher) redirectWithError(w http.ResponseWriter, r *http.Request, target string, err error, values map[string]string) {
+	appErr := translateError(r, err)
+	f.set(w, r, target, &Flash{Error: appErr.Message, Fields: appErr.Fields, Values: values})
+	http.Redirect(w, r, target, http.StatusSeeOther)
+}
+
+// set stores flash in a cookie that is only sent to target's path.
+func (f *Flasher) set(w http.ResponseWriter, r *http.Request, target string, flash *Flash) {
+	value, err := f.encode(flash)
+	if err == nil && len(value) > maxFlashCookie {
+		trimmed := *flash
+		trimmed.Values = nil
+		value, err = f.encode(&trimmed)
+	}
+	if err != nil {
+		return
+	}
+	http.SetCookie(w, &http.Cookie{
+		Name:     flashCookie,
+		Value:    value,
+		Path:     flashPath(target),
+		MaxAge:   60,
+		HttpOnly: true,
+		Secure:   r.TLS != nil,
+		SameSite: http.SameSiteLaxMode,
+	})
+}
+
+// Reveal moves a valid flash from its cookie into the request context for
+// the page loader and expires the cookie, so the flash shows once. Cookies
+// that fail verification are discarded.
+func (f *Flasher) Reveal(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodGet {
+			if cookie, err := r.Cookie(flashCookie); err == nil {
+				http.SetCookie(w, &http.Cookie{
+					Name:   flashCookie,
+					Value:  "",
+					Path:   flashPath(r.URL.Path),
+					MaxAge: -1,
+				})
+				if flash := f.decode(cookie.Value); flash != nil {
+					r = r.WithContext(context.WithValue(r.Context(), flashKey{}, flash))
+				}
+			}
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// FlashFrom returns the flash revealed for this request, or nil.
+func FlashFrom(ctx context.Context) *Flash {
+	flash, _ := ctx.Value(flashKey{}).(*Flash)
+	return flash
+}
+
+// Props localizes the flash into page props: "error", plus "fieldErrors"
+// and "values" when present. A nil flash has no props.
+func (fl *Flash) Props(locale string) map[string]any {
+	if fl == nil {
+		return nil
+	}
+	props := map[string]any{}
+	if fl.Error != "" {
+		props["error"] = i18n.T(locale, fl.Error)
+	}
+	if fields := localizeFields(locale, fl.Fields); fields != nil {
+		props["fieldErrors"] = fields
+	}
+	if len(fl.Values) > 0 {
+		props["values"] = fl.Values
+	}
+	return props
+}
+
+func (f *Flasher) encode(flash *Flash) (string, error) {
+	payload, err := json.Marshal(flash)
+	if err != nil {
+		return "", err
+	}
+	enc := base64.RawURLEncoding
+	return enc.EncodeToString(payload) + "." + enc.EncodeToString(f.sign(payload)), nil
+}
+
+func (f *Flasher) decode(value string) *Flash {
+	enc := base64.RawURLEncoding
+	p, s, ok := strings.Cut(value, ".")
+	if !ok {
+		return nil
+	}
+	payload, err := enc.DecodeString(p)
+	if err != nil {
+		return nil
+	}
+	sig, err := enc.DecodeString(s)
+	if err != nil || !hmac.Equal(sig, f.sign(payload)) {
+		return nil
+	}
+	var flash Flash
+	if err := json.Unmarshal(payload, &flash); err != nil {
+		return nil
+	}
+	return &flash
+}
+
+func (f *Flasher) sign(payload []byte) []byte {
+	mac := hmac.New(sha256.New, f.key)
+	mac.Write(payload)
+	return mac.Sum(nil)
+}
+
+// flashPath is the cookie path for a redirect target: its path without the
+// query, so the flash reaches only the page it was meant for.
+func flashPath(target string) string {
+	path, _, _ := strings.Cut(target, "?")
+	if path == "" {
+		return "/"
+	}
+	return path
+}
@@ -0,0 +1,146 @@
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"myapp/i18n"
+	"myapp/metrics"
+	"myapp/util"
+)
+
+var testFlasher = NewFlasher("test-secret")
+
+// expectFlash asserts that w redirects to target with an error flashed for
+// it, and returns the flash.
+func expectFlash(t *testing.T, w *httptest.ResponseRecorder, target string) *Flash {
+	t.Helper()
+	if w.Code != http.StatusSeeOther {
+		t.Fatalf("expected %d, got %d", http.StatusSeeOther, w.Code)
+	}
+	if loc := w.Header().Get("Location"); loc != target {
+		t.Errorf("expected redirect to %s, got %s", target, loc)
+	}
+	for _, c := range w.Result().Cookies() {
+		if c.Name != flashCookie {
+			continue
+		}
+		if c.Path != flashPath(target) {
+			t.Errorf("expected flash cookie path %s, got %s", flashPath(target), c.Path)
+		}
+		flash := testFlasher.decode(c.Value)
+		if flash == nil || flash.Error == "" {
+			t.Fatalf("expected a signed flash with an error, got %+v", flash)
+		}
+		return flash
+	}
+	t.Fatal("expected a flash cookie")
+	return nil
+}
+
+func TestFlash(t *testing.T) {
+	if err := i18n.Load(); err != nil {
+		t.Fatalf("failed to load translations: %v", err)
+	}
+	invalid := util.Invalid("bio", "error.bioTooLong")
+
+	// reveal runs a GET for path carrying cookie through Reveal and returns
+	// the flash the loader would see.
+	reveal := func(f *Flasher, path string, cookie *http.Cookie) (*Flash, *httptest.ResponseRecorder) {
+		var seen *Flash
+		h := f.Reveal(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			seen = FlashFrom(r.Context())
+		}))
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		req.AddCookie(cookie)
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, req)
+		return seen, w
+	}
+
+	redirect := func(err error, values map[string]string) *http.Cookie {
+		w := httptest.NewRecorder()
+		testFlasher.redirectWithError(w, httptest.NewRequest(http.MethodPost, "/api/user/update", nil), "/user/someone/edit?success=1", err, values)
+		return w.Result().Cookies()[0]
+	}
+
+	t.Run("round trip to the loader", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		testFlasher.redirectWithError(w, httptest.NewRequest(http.MethodPost, "/api/user/update", nil), "/user/someone/edit", invalid, map[string]string{"bio": "typed"})
+		flash := expectFlash(t, w, "/user/someone/edit")
+		if strings.Contains(w.Header().Get("Location"), "error") {
+			t.Error("expected no error text in the URL")
+		}
+
+		cookie := w.Result().Cookies()[0]
+		seen, rw := reveal(testFlasher, "/user/someone/edit", cookie)
+		if seen == nil || seen.Error != flash.Error || seen.Values["bio"] != "typed" || seen.Fields["bio"] != "error.bioTooLong" {
+			t.Fatalf("expected the flash in the loader context, got %+v", seen)
+		}
+		cleared := rw.Result().Cookies()
+		if len(cleared) != 1 || cleared[0].MaxAge >= 0 || cleared[0].Path != "/user/someone/edit" {
+			t.Errorf("expected the cookie to be expired, got %+v", cleared)
+		}
+	})
+
+	t.Run("route label survives the reveal", func(t *testing.T) {
+		m := metrics.New()
+		mux := NewRouteMux()
+		mux.HandleFunc("GET /user/{handle}/edit", func(w http.ResponseWriter, r *http.Request) {})
+		h := RequestMetrics(m)(testFlasher.Reveal(mux))
+
+		req := httptest.NewRequest(http.MethodGet, "/user/someone/edit", nil)
+		req.AddCookie(redirect(invalid, nil))
+		h.ServeHTTP(httptest.NewRecorder(), req)
+
+		w := httptest.NewRecorder()
+		m.Handler().ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+		if want := `route="GET /user/{handle}/edit"`; !strings.Contains(w.Body.String(), want) {
+			t.Errorf("metrics output missing %q", want)
+		}
+	})
+
+	t.Run("props are localized", func(t *testing.T) {
+		flash := &Flash{Error: "error.bioTooLong", Fields: map[string]string{"bio": "error.bioTooLong"}, Values: map[string]string{"bio": "typed"}}
+		props := flash.Props("es")
+		want := i18n.T("es", "error.bioTooLong")
+		if props["error"] != want || props["fieldErrors"].(map[string]string)["bio"] != want || props["values"].(map[string]string)["bio"] != "typed" {
+			t.Errorf("unexpected props %v", props)
+		}
+		if (*Flash)(nil).Props("en") != nil {
+			t.Error("expected no props without a flash")
+		}
+	})
+
+	t.Run("cookie is scoped to the target path", func(t *testing.T) {
+		if c := redirect(invalid, nil); c.Path != "/user/someone/edit" || !c.HttpOnly {
+			t.Errorf("unexpected cookie %+v", c)
+		}
+	})
+
+	t.Run("tampered or foreign flashes are ignored", func(t *testing.T) {
+		c := redirect(invalid, nil)
+		payload, sig, _ := strings.Cut(c.Value, ".")
+		forged := *c
+		forged.Value = payload + "x." + sig
+		if seen, _ := reveal(testFlasher, "/user/someone/edit", &forged); seen != nil {
+			t.Errorf("expected a tampered flash to be dropped, got %+v", seen)
+		}
+		if seen, _ := reveal(NewFlasher("other-secret"), "/user/someone/edit", c); seen != nil {
+			t.Errorf("expected a flash signed with another secret to be dropped, got %+v", seen)
+		}
+	})
+
+	t.Run("oversized values are dropped, errors kept", func(t *testing.T) {
+		c := redirect(invalid, map[string]string{"bio": strings.Repeat("b", 2*maxFlashCookie)})
+		if len(c.Value) > maxFlashCookie {
+			t.Fatalf("cookie is %d bytes", len(c.Value))
+		}
+		flash := testFlasher.decode(c.Value)
+		if flash == nil || flash.Values != nil || flash.Fields["bio"] == "" {
+			t.Errorf("expected errors without values, got %+v", flash)
+		}
+	})
+}
@@ -0,0 +1,93 @@
+package handlers
+
+import (
+	"net/http"
+
+	"myapp/services"
+)
+
+type FollowHandler struct {
+	followSvc *services.FollowService
+	authSvc   *services.AuthService
+	flash     *Flasher
+}
+
+func NewFollowHandler(followSvc *services.FollowService, authSvc *services.AuthService, flash *Flasher) *FollowHandler {
+	return &FollowHandler{followSvc: followSvc, authSvc: authSvc, flash: flash}
+}
+
+// Follow makes the signed-in user follow the user in the path and returns
+// to their profile.
+func (h *FollowHandler) Follow() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		currentUser := h.authSvc.GetUserFromRequest(r)
+		if currentUser == nil {
+			http.Redirect(w, r, "/login", http.StatusSeeOther)
+			return
+		}
+
+		profile := "/user/" + r.PathValue("handle")
+		if err := h.followSvc.Follow(r.Context(), currentUser, r.PathValue("handle")); err != nil {
+			h.flash.redirectWithError(w, r, profile, err, nil)
+			return
+		}
+		http.Redirect(w, r, profile, http.StatusSeeOther)
+	}
+}
+
+// Unfollow makes the signed-in user stop following the user in the path and
+// returns to their profile.
+func (h *FollowHandler) Unfollow() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		currentUser := h.authSvc.GetUserFromRequest(r)
+		if currentUser == nil {
+			http.Redirect(w, r, "/login", http.StatusSeeOther)
+			return
+		}
+
+		profile := "/user/" + r.PathValue("handle")
+		if err := h.followSvc.Unfollow(r.Context(), currentUser, r.PathValue("handle")); err != nil {
+			h.flash.redirectWithError(w, r, profile, err, nil)
+			return
+		}
+		http.Redirect(w, r, profile, http.StatusSeeOther)
+	}
+}
+
+// Block makes the signed-in user block the user in the path and returns to
+// their profile.
+func (h *FollowHandler) Block() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		currentUser := h.authSvc.GetUserFromRequest(r)
+		if currentUser == nil {
+			http.Redirect(w, r, "/login", http.StatusSeeOther)
+			return
+		}
+
+		profile := "/user/" + r.PathValue("handle")
+		if err := h.followSvc.Block(r.Context(), currentUser, r.PathValue("handle")); err != nil {
+			h.flash.redirectWithError(w, r, profile, err, nil)
+			return
+		}
+		http.Redirect(w, r, profile, http.StatusSeeOther)
+	}
+}
+
+// Unblock lifts the signed-in user's block on the user in the path and
+// returns to their profile.
+func (h *FollowHandler) Unblock() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		currentUser := h.authSvc.GetUserFromRequest(r)
+		if currentUser == nil {
+			http.Redirect(w, r, "/login", http.StatusSeeOther)
+			return
+		}
+
+		profile := "/user/" + r.PathValue("handle")
+		if err := h.followSvc.Unblock(r.Context(), currentUser, r.PathValue("handle")); err != nil {
+			h.flash.redirectWithError(w, r, profile, err, nil)
+			return
+		}
+		http.Redirect(w, r, profile, http.StatusSeeOther)
+	}
+}
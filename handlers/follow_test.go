@@ -0,0 +1,106 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"myapp/i18n"
+	"myapp/model"
+	"myapp/services"
+	"myapp/testutil"
+)
+
+func TestHandlerFollow(t *testing.T) {
+	if err := i18n.Load(); err != nil {
+		t.Fatalf("failed to load translations: %v", err)
+	}
+	ctx := context.Background()
+	db := testutil.NewTestDB(t, &model.User{}, &model.HandleHistory{}, &model.Follow{}, &model.Block{})
+	users := model.NewUserRepository(db)
+	authSvc := services.NewAuthService(users, "test-secret", nil, services.EmailPolicy{}, nil, nil)
+	followSvc := services.NewFollowService(model.NewFollowRepository(db), model.NewBlockRepository(db), users)
+	h := NewFollowHandler(followSvc, authSvc, testFlasher)
+
+	session, _ := authSvc.Signup(ctx, "bob@example.com", "password123", "bob")
+	_, _ = authSvc.Signup(ctx, "alice@example.com", "password123", "alice")
+	alice, _ := users.GetByHandle(ctx, "alice")
+	bob, _ := users.GetByHandle(ctx, "bob")
+
+	post := func(handler http.HandlerFunc, handle, session string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/api/user/"+handle+"/follow", nil)
+		req.SetPathValue("handle", handle)
+		if session != "" {
+			req.AddCookie(&http.Cookie{Name: "session", Value: session})
+		}
+		w := httptest.NewRecorder()
+		handler(w, req)
+		return w
+	}
+
+	t.Run("redirect to login if not authenticated", func(t *testing.T) {
+		if loc := post(h.Follow(), "alice", "").Header().Get("Location"); loc != "/login" {
+			t.Errorf("expected redirect to /login, got %q", loc)
+		}
+	})
+
+	t.Run("follow returns to the profile", func(t *testing.T) {
+		w := post(h.Follow(), "alice", session)
+		if w.Code != http.StatusSeeOther || w.Header().Get("Location") != "/user/alice" {
+			t.Fatalf("expected redirect to /user/alice, got %d %q", w.Code, w.Header().Get("Location"))
+		}
+		if ok, _ := followSvc.IsFollowing(ctx, bob, alice); !ok {
+			t.Error("expected bob to follow alice")
+		}
+	})
+
+	t.Run("following yourself flashes an error", func(t *testing.T) {
+		flash := expectFlash(t, post(h.Follow(), "bob", session), "/user/bob")
+		if flash.Error != "error.followSelf" {
+			t.Errorf("expected error.followSelf, got %q", flash.Error)
+		}
+	})
+
+	t.Run("unknown users flash not found", func(t *testing.T) {
+		expectFlash(t, post(h.Follow(), "nobody", session), "/user/nobody")
+	})
+
+	t.Run("unfollow returns to the profile", func(t *testing.T) {
+		w := post(h.Unfollow(), "alice", session)
+		if w.Header().Get("Location") != "/user/alice" {
+			t.Fatalf("expected redirect to /user/alice, got %q", w.Header().Get("Location"))
+		}
+		if ok, _ := followSvc.IsFollowing(ctx, bob, alice); ok {
+			t.Error("expected bob to no longer follow alice")
+		}
+	})
+
+	t.Run("block ends the follow and returns to the profile", func(t *testing.T) {
+		_ = followSvc.Follow(ctx, bob, "alice")
+		w := post(h.Block(), "alice", session)
+		if w.Header().Get("Location") != "/user/alice" {
+			t.Fatalf("expected redirect to /user/alice, got %q", w.Header().Get("Location"))
+		}
+		if ok, _ := followSvc.HasBlocked(ctx, bob, alice); !ok {
+			t.Error("expected bob to block alice")
+		}
+		if ok, _ := followSvc.IsFollowing(ctx, bob, alice); ok {
+			t.Error("expected the follow to end")
+		}
+		flash := expectFlash(t, post(h.Follow(), "alice", session), "/user/alice")
+		if flash.Error != "error.blocked" {
+			t.Errorf("expected error.blocked, got %q", flash.Error)
+		}
+	})
+
+	t.Run("unblock returns to the profile", func(t *testing.T) {
+		w := post(h.Unblock(), "alice", session)
+		if w.Header().Get("Location") != "/user/alice" {
+			t.Fatalf("expected redirect to /user/alice, got %q", w.Header().Get("Location"))
+		}
+		if ok, _ := followSvc.HasBlocked(ctx, bob, alice); ok {
+			t.Error("expected the block to be lifted")
+		}
+	})
+}
@@ -0,0 +1,95 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"runtime/debug"
+	"time"
+
+	"myapp/i18n"
+	"myapp/storage"
+	"myapp/util"
+
+	"gorm.io/gorm"
+)
+
+const readinessCheckTimeout = 2 * time.Second
+
+type HealthHandler struct {
+	db    *gorm.DB
+	store storage.Storage
+}
+
+func NewHealthHandler(db *gorm.DB, store storage.Storage) *HealthHandler {
+	return &HealthHandler{db: db, store: store}
+}
+
+// Healthz reports that the process is alive. It never touches dependencies.
+func (h *HealthHandler) Healthz(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, map[string]string{"status": "ok"})
+}
+
+// Readyz reports whether the instance can serve traffic: the database
+// answers a ping, storage is reachable and translations are loaded. Each
+// check is "ok" or "fail"; the endpoint is public, so why a check failed
+// is only logged.
+func (h *HealthHandler) Readyz(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(r.Context(), readinessCheckTimeout)
+	defer cancel()
+
+	checks := map[string]string{}
+	ready := true
+	record := func(name string, err error) {
+		if err != nil {
+			util.Logger(ctx).Error("readiness check failed", "check", name, "error", err)
+			checks[name] = "fail"
+			ready = false
+			return
+		}
+		checks[name] = "ok"
+	}
+
+	record("database", util.PingDatabase(ctx, h.db))
+	record("storage", h.store.Ping(ctx))
+	if i18n.Loaded() {
+		record("i18n", nil)
+	} else {
+		record("i18n", errors.New("translations not loaded"))
+	}
+
+	status, code := "ok", http.StatusOK
+	if !ready {
+		status, code = "unavailable", http.StatusServiceUnavailable
+	}
+	writeJSON(w, code, map[string]any{"status": status, "checks": checks})
+}
+
+// Version reports the module version and VCS stamp embedded by the Go toolchain.
+func (h *HealthHandler) Version(w http.ResponseWriter, r *http.Request) {
+	info := map[string]string{}
+	if bi, ok := debug.ReadBuildInfo(); ok {
+		info["module"] = bi.Main.Path
+		info["version"] = bi.Main.Version
+		info["goVersion"] = bi.GoVersion
+		for _, s := range bi.Settings {
+			switch s.Key {
+			case "vcs.revision":
+				info["revision"] = s.Value
+			case "vcs.time":
+				info["buildTime"] = s.Value
+			case "vcs.modified":
+				info["modified"] = s.Value
+			}
+		}
+	}
+	writeJSON(w, http.StatusOK, info)
+}
+
+func writeJSON(w http.ResponseWriter, status int, body any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(body)
+}
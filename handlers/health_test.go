@@ -0,0 +1,78 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"myapp/i18n"
+	"myapp/storage"
+	"myapp/testutil"
+	"myapp/util"
+)
+
+func getJSON(t *testing.T, handler http.HandlerFunc, target string) (int, map[string]any) {
+	t.Helper()
+	w := httptest.NewRecorder()
+	handler(w, httptest.NewRequest(http.MethodGet, target, nil))
+	var body map[string]any
+	if err := json.NewDecoder(w.Body).Decode(&body); err != nil {
+		t.Fatalf("failed to decode body: %v", err)
+	}
+	return w.Code, body
+}
+
+func TestHandlerHealthz(t *testing.T) {
+	h := NewHealthHandler(testutil.NewTestDB(t), storage.Noop())
+	code, body := getJSON(t, h.Healthz, "/healthz")
+	if code != http.StatusOK {
+		t.Errorf("expected 200, got %d", code)
+	}
+	if body["status"] != "ok" {
+		t.Errorf("expected status ok, got %v", body["status"])
+	}
+}
+
+func TestHandlerReadyz(t *testing.T) {
+	if err := i18n.Load(); err != nil {
+		t.Fatalf("failed to load translations: %v", err)
+	}
+
+	t.Run("200 when dependencies are up", func(t *testing.T) {
+		h := NewHealthHandler(testutil.NewTestDB(t), storage.Noop())
+		code, body := getJSON(t, h.Readyz, "/readyz")
+		if code != http.StatusOK {
+			t.Errorf("expected 200, got %d: %v", code, body)
+		}
+	})
+
+	t.Run("503 when database is down", func(t *testing.T) {
+		db := testutil.NewTestDB(t)
+		_ = util.CloseDatabase(db)
+
+		h := NewHealthHandler(db, storage.Noop())
+		code, body := getJSON(t, h.Readyz, "/readyz")
+		if code != http.StatusServiceUnavailable {
+			t.Errorf("expected 503, got %d", code)
+		}
+		checks, _ := body["checks"].(map[string]any)
+		if checks["database"] != "fail" {
+			t.Errorf("expected database check to fail without details, got %v", checks["database"])
+		}
+		if checks["storage"] != "ok" {
+			t.Errorf("expected storage check to pass, got %v", checks["storage"])
+		}
+	})
+}
+
+func TestHandlerVersion(t *testing.T) {
+	h := NewHealthHandler(testutil.NewTestDB(t), storage.Noop())
+	code, body := getJSON(t, h.Version, "/version")
+	if code != http.StatusOK {
+		t.Errorf("expected 200, got %d", code)
+	}
+	if body["goVersion"] == nil || body["goVersion"] == "" {
+		t.Errorf("expected goVersion, got %v", body)
+	}
+}
@@ -0,0 +1,201 @@
+package handlers
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+	"strings"
+	"time"
+
+	"myapp/metrics"
+	"myapp/util"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const requestIDHeader = "X-Request-ID"
+
+// RequestLogger assigns every request an ID (reusing a well-formed incoming
+// X-Request-ID), echoes it in the response, stores a request-scoped logger in
+// the context and writes one access log line per request.
+func RequestLogger(logger *slog.Logger) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			start := time.Now()
+			r, route := withRoute(r)
+
+			requestID := r.Header.Get(requestIDHeader)
+			if !validRequestID(requestID) {
+				requestID = util.GenerateUuid()
+			}
+			w.Header().Set(requestIDHeader, requestID)
+
+			reqLogger := logger.With("request_id", requestID)
+			if sc := trace.SpanContextFromContext(r.Context()); sc.IsValid() {
+				reqLogger = reqLogger.With("trace_id", sc.TraceID().String())
+			}
+			ctx := util.WithRequestInfo(util.WithLogger(r.Context(), reqLogger))
+			r = r.WithContext(ctx)
+
+			rec := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+			next.ServeHTTP(rec, r)
+
+			attrs := []any{
+				"method", r.Method,
+				"route", route.label(),
+				"path", r.URL.Path,
+				"status", rec.status,
+				"bytes", rec.bytes,
+				"duration_ms", float64(time.Since(start).Microseconds()) / 1000,
+			}
+			if userID := util.RequestUserID(ctx); userID != "" {
+				attrs = append(attrs, "user_id", userID)
+			}
+
+			level := slog.LevelInfo
+			if rec.status >= 500 {
+				level = slog.LevelError
+			}
+			reqLogger.Log(ctx, level, "http request", attrs...)
+		})
+	}
+}
+
+// RequestMetrics records request count and latency labelled by the matched
+// route pattern, so path parameters do not explode label cardinality.
+func RequestMetrics(m *metrics.Metrics) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			start := time.Now()
+			r, route := withRoute(r)
+			rec := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+			next.ServeHTTP(rec, r)
+			m.ObserveHTTPRequest(r.Method, route.label(), rec.status, time.Since(start))
+		})
+	}
+}
+
+type routeKey struct{}
+
+// matchedRoute holds the pattern a RouteMux matched. The request middleware
+// put it in the context before serving and read it afterwards, so the label
+// survives middleware in between that replaces the request.
+type matchedRoute struct {
+	pattern string
+}
+
+// withRoute returns r with a route holder in its context, reusing the one an
+// outer middleware added.
+func withRoute(r *http.Request) (*http.Request, *matchedRoute) {
+	if route, ok := r.Context().Value(routeKey{}).(*matchedRoute); ok {
+		return r, route
+	}
+	route := &matchedRoute{}
+	return r.WithContext(context.WithValue(r.Context(), routeKey{}, route)), route
+}
+
+// label is the matched pattern, or "unmatched" if no route matched or the
+// request did not reach a RouteMux.
+func (m *matchedRoute) label() string {
+	if m.pattern == "" {
+		return "unmatched"
+	}
+	return m.pattern
+}
+
+// RouteMux is an http.ServeMux that records the pattern it matched for
+// RequestLogger, RequestMetrics and RequestTracing.
+type RouteMux struct {
+	*http.ServeMux
+}
+
+func NewRouteMux() *RouteMux {
+	return &RouteMux{ServeMux: http.NewServeMux()}
+}
+
+func (m *RouteMux) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	m.ServeMux.ServeHTTP(w, r)
+	if route, ok := r.Context().Value(routeKey{}).(*matchedRoute); ok {
+		route.pattern = r.Pattern
+	}
+}
+
+// RequestTracing starts a server span per request, continuing any incoming
+// W3C traceparent, and renames it after the matched route once served.
+func RequestTracing(tp trace.TracerProvider) func(http.Handler) http.Handler {
+	tracer := tp.Tracer("myapp/handlers")
+	propagator := propagation.TraceContext{}
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			r, matched := withRoute(r)
+			ctx := propagator.Extract(r.Context(), propagation.HeaderCarrier(r.Header))
+			ctx, span := tracer.Start(ctx, r.Method,
+				trace.WithSpanKind(trace.SpanKindServer),
+				trace.WithAttributes(
+					attribute.String("http.request.method", r.Method),
+					attribute.String("url.path", r.URL.Path),
+				),
+			)
+			defer span.End()
+
+			r = r.WithContext(ctx)
+			rec := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+			next.ServeHTTP(rec, r)
+
+			route := matched.label()
+			if _, path, ok := strings.Cut(route, " "); ok {
+				route = path
+			}
+			span.SetName(r.Method + " " + route)
+			span.SetAttributes(
+				attribute.String("http.route", route),
+				attribute.Int("http.response.status_code", rec.status),
+			)
+			if rec.status >= 500 {
+				span.SetStatus(codes.Error, http.StatusText(rec.status))
+			}
+		})
+	}
+}
+
+func validRequestID(id string) bool {
+	if id == "" || len(id) > 128 {
+		return false
+	}
+	for _, c := range id {
+		if c < '!' || c > '~' {
+			return false
+		}
+	}
+	return true
+}
+
+type statusRecorder struct {
+	http.ResponseWriter
+	status      int
+	bytes       int
+	wroteHeader bool
+}
+
+func (r *statusRecorder) WriteHeader(status int) {
+	if !r.wroteHeader {
+		r.status = status
+		r.wroteHeader = true
+	}
+	r.ResponseWriter.WriteHeader(status)
+}
+
+func (r *statusRecorder) Write(b []byte) (int, error) {
+	r.wroteHeader = true
+	n, err := r.ResponseWriter.Write(b)
+	r.bytes += n
+	return n, err
+}
+
+// Unwrap lets http.ResponseController reach the underlying writer.
+func (r *statusRecorder) Unwrap() http.ResponseWriter {
+	return r.ResponseWriter
+}
@@ -0,0 +1,206 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"myapp/metrics"
+	"myapp/util"
+
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func newLoggedMux(buf *bytes.Buffer) http.Handler {
+	mux := NewRouteMux()
+	mux.HandleFunc("GET /user/{handle}", func(w http.ResponseWriter, r *http.Request) {
+		util.SetRequestUserID(r.Context(), "user-123")
+		util.Logger(r.Context()).Info("inside handler")
+		w.WriteHeader(http.StatusTeapot)
+	})
+	return RequestLogger(util.NewLogger(buf, slog.LevelInfo))(mux)
+}
+
+func logLines(t *testing.T, buf *bytes.Buffer) []map[string]any {
+	t.Helper()
+	var lines []map[string]any
+	dec := json.NewDecoder(buf)
+	for dec.More() {
+		var line map[string]any
+		if err := dec.Decode(&line); err != nil {
+			t.Fatalf("invalid JSON log line: %v", err)
+		}
+		lines = append(lines, line)
+	}
+	return lines
+}
+
+func TestRequestLogger(t *testing.T) {
+	t.Run("generates request id and logs route", func(t *testing.T) {
+		var buf bytes.Buffer
+		w := httptest.NewRecorder()
+		newLoggedMux(&buf).ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/user/testuser", nil))
+
+		requestID := w.Header().Get("X-Request-ID")
+		if requestID == "" {
+			t.Fatal("expected X-Request-ID response header")
+		}
+
+		lines := logLines(t, &buf)
+		if len(lines) != 2 {
+			t.Fatalf("expected 2 log lines, got %d", len(lines))
+		}
+		if lines[0]["request_id"] != requestID {
+			t.Errorf("handler log missing request_id, got %v", lines[0])
+		}
+
+		access := lines[1]
+		want := map[string]any{
+			"msg":        "http request",
+			"method":     "GET",
+			"route":      "GET /user/{handle}",
+			"status":     float64(http.StatusTeapot),
+			"user_id":    "user-123",
+			"request_id": requestID,
+		}
+		for k, v := range want {
+			if access[k] != v {
+				t.Errorf("access log %s: got %v, want %v", k, access[k], v)
+			}
+		}
+		if _, ok := access["duration_ms"]; !ok {
+			t.Error("access log missing duration_ms")
+		}
+	})
+
+	t.Run("propagates incoming request id", func(t *testing.T) {
+		var buf bytes.Buffer
+		req := httptest.NewRequest(http.MethodGet, "/user/testuser", nil)
+		req.Header.Set("X-Request-ID", "abc-123")
+		w := httptest.NewRecorder()
+		newLoggedMux(&buf).ServeHTTP(w, req)
+
+		if got := w.Header().Get("X-Request-ID"); got != "abc-123" {
+			t.Errorf("got X-Request-ID %q, want abc-123", got)
+		}
+	})
+
+	t.Run("replaces malformed request id", func(t *testing.T) {
+		var buf bytes.Buffer
+		req := httptest.NewRequest(http.MethodGet, "/user/testuser", nil)
+		req.Header.Set("X-Request-ID", "bad id\twith spaces")
+		w := httptest.NewRecorder()
+		newLoggedMux(&buf).ServeHTTP(w, req)
+
+		if got := w.Header().Get("X-Request-ID"); got == "bad id\twith spaces" || got == "" {
+			t.Errorf("expected a generated request id, got %q", got)
+		}
+	})
+
+	t.Run("unmatched route", func(t *testing.T) {
+		var buf bytes.Buffer
+		newLoggedMux(&buf).ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/nope", nil))
+
+		lines := logLines(t, &buf)
+		if got := lines[len(lines)-1]["route"]; got != "unmatched" {
+			t.Errorf("got route %v, want unmatched", got)
+		}
+	})
+}
+
+func TestRequestMetrics(t *testing.T) {
+	m := metrics.New()
+	mux := NewRouteMux()
+	mux.HandleFunc("GET /user/{handle}", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+	})
+	handler := RequestMetrics(m)(mux)
+
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/user/alice", nil))
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/user/bob", nil))
+
+	w := httptest.NewRecorder()
+	m.Handler().ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	want := `myapp_http_requests_total{method="GET",route="GET /user/{handle}",status="404"} 2`
+	if !strings.Contains(w.Body.String(), want) {
+		t.Errorf("metrics output missing %q", want)
+	}
+}
+
+func TestRequestTracing(t *testing.T) {
+	rec := tracetest.NewSpanRecorder()
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(rec))
+	var buf bytes.Buffer
+	handler := RequestTracing(tp)(newLoggedMux(&buf))
+
+	t.Run("names span after route", func(t *testing.T) {
+		handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/user/testuser", nil))
+
+		spans := rec.Ended()
+		span := spans[len(spans)-1]
+		if span.Name() != "GET /user/{handle}" {
+			t.Errorf("got span name %q, want GET /user/{handle}", span.Name())
+		}
+		if span.SpanKind() != trace.SpanKindServer {
+			t.Errorf("got span kind %v, want server", span.SpanKind())
+		}
+
+		lines := logLines(t, &buf)
+		if got := lines[len(lines)-1]["trace_id"]; got != span.SpanContext().TraceID().String() {
+			t.Errorf("access log trace_id %v, want %s", got, span.SpanContext().TraceID())
+		}
+	})
+
+	t.Run("continues incoming traceparent", func(t *testing.T) {
+		const traceID = "4bf92f3577b34da6a3ce929d0e0e4736"
+		req := httptest.NewRequest(http.MethodGet, "/user/testuser", nil)
+		req.Header.Set("traceparent", "00-"+traceID+"-00f067aa0ba902b7-01")
+		handler.ServeHTTP(httptest.NewRecorder(), req)
+
+		spans := rec.Ended()
+		span := spans[len(spans)-1]
+		if got := span.SpanContext().TraceID().String(); got != traceID {
+			t.Errorf("got trace id %s, want %s", got, traceID)
+		}
+		if got := span.Parent().SpanID().String(); got != "00f067aa0ba902b7" {
+			t.Errorf("got parent span %s, want 00f067aa0ba902b7", got)
+		}
+	})
+}
+
+func TestRouteSurvivesContextMiddleware(t *testing.T) {
+	m := metrics.New()
+	rec := tracetest.NewSpanRecorder()
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(rec))
+	var buf bytes.Buffer
+
+	mux := NewRouteMux()
+	mux.HandleFunc("GET /user/{handle}", func(w http.ResponseWriter, r *http.Request) {})
+	type key struct{}
+	replacing := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mux.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), key{}, "value")))
+	})
+	handler := RequestTracing(tp)(RequestLogger(util.NewLogger(&buf, slog.LevelInfo))(RequestMetrics(m)(replacing)))
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/user/alice", nil))
+
+	spans := rec.Ended()
+	if got := spans[len(spans)-1].Name(); got != "GET /user/{handle}" {
+		t.Errorf("got span name %q, want GET /user/{handle}", got)
+	}
+	lines := logLines(t, &buf)
+	if got := lines[len(lines)-1]["route"]; got != "GET /user/{handle}" {
+		t.Errorf("got logged route %v, want GET /user/{handle}", got)
+	}
+	w := httptest.NewRecorder()
+	m.Handler().ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	if want := `route="GET /user/{handle}"`; !strings.Contains(w.Body.String(), want) {
+		t.Errorf("metrics output missing %q", want)
+	}
+}
@@ -0,0 +1,66 @@
+package handlers
+
+import (
+	"net/http"
+	"strings"
+
+	"myapp/services"
+)
+
+const reportsPage = "/admin/reports"
+
+type ModerationHandler struct {
+	moderationSvc *services.ModerationService
+	authSvc       *services.AuthService
+	flash         *Flasher
+}
+
+func NewModerationHandler(moderationSvc *services.ModerationService, authSvc *services.AuthService, flash *Flasher) *ModerationHandler {
+	return &ModerationHandler{moderationSvc: moderationSvc, authSvc: authSvc, flash: flash}
+}
+
+// Report files the report form on the user in the path and returns to their
+// profile.
+func (h *ModerationHandler) Report() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		currentUser := h.authSvc.GetUserFromRequest(r)
+		if currentUser == nil {
+			http.Redirect(w, r, "/login", http.StatusSeeOther)
+			return
+		}
+		_ = r.ParseForm()
+
+		profile := "/user/" + r.PathValue("handle")
+		input := services.ReportInput{
+			Reason:   r.FormValue("reason"),
+			Evidence: strings.TrimSpace(r.FormValue("evidence")),
+		}
+		if err := h.moderationSvc.Report(r.Context(), currentUser, r.PathValue("handle"), input); err != nil {
+			h.flash.redirectWithError(w, r, profile, err, map[string]string{"reason": input.Reason, "evidence": input.Evidence})
+			return
+		}
+		http.Redirect(w, r, profile+"?reported=1", http.StatusSeeOther)
+	}
+}
+
+// Resolve takes the action in the form on the report in the path and
+// returns to the moderation queue. Only admins get past the 404.
+func (h *ModerationHandler) Resolve() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		currentUser := h.authSvc.GetUserFromRequest(r)
+		if currentUser == nil {
+			http.Redirect(w, r, "/login", http.StatusSeeOther)
+			return
+		}
+		if !h.moderationSvc.IsAdmin(currentUser) {
+			http.NotFound(w, r)
+			return
+		}
+
+		if err := h.moderationSvc.Resolve(r.Context(), currentUser, r.PathValue("id"), r.FormValue("action")); err != nil {
+			h.flash.redirectWithError(w, r, reportsPage, err, nil)
+			return
+		}
+		http.Redirect(w, r, reportsPage+"?resolved=1", http.StatusSeeOther)
+	}
+}
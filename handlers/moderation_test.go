@@ -0,0 +1,113 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"myapp/i18n"
+	"myapp/model"
+	"myapp/services"
+	"myapp/testutil"
+)
+
+func TestHandlerModeration(t *testing.T) {
+	if err := i18n.Load(); err != nil {
+		t.Fatalf("failed to load translations: %v", err)
+	}
+	ctx := context.Background()
+	db := testutil.NewTestDB(t, &model.User{}, &model.HandleHistory{}, &model.Report{})
+	users := model.NewUserRepository(db)
+	authSvc := services.NewAuthService(users, "test-secret", nil, services.EmailPolicy{}, nil, nil)
+	moderationSvc := services.NewModerationService(model.NewReportRepository(db), users, services.EmailPolicy{}, "admin@example.com")
+	h := NewModerationHandler(moderationSvc, authSvc, testFlasher)
+
+	adminSession, _ := authSvc.Signup(ctx, "admin@example.com", "password123", "carol")
+	session, _ := authSvc.Signup(ctx, "bob@example.com", "password123", "bob")
+	_, _ = authSvc.Signup(ctx, "alice@example.com", "password123", "alice")
+	admin, _ := users.GetByHandle(ctx, "carol")
+
+	post := func(handler http.HandlerFunc, target, param, value, session string, values url.Values) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, target, strings.NewReader(values.Encode()))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		req.SetPathValue(param, value)
+		if session != "" {
+			req.AddCookie(&http.Cookie{Name: "session", Value: session})
+		}
+		w := httptest.NewRecorder()
+		handler(w, req)
+		return w
+	}
+	report := func(session string, values url.Values) *httptest.ResponseRecorder {
+		return post(h.Report(), "/api/user/alice/report", "handle", "alice", session, values)
+	}
+
+	t.Run("redirect to login if not authenticated", func(t *testing.T) {
+		w := report("", url.Values{"reason": {"spam"}})
+		if loc := w.Header().Get("Location"); loc != "/login" {
+			t.Errorf("expected redirect to /login, got %q", loc)
+		}
+	})
+
+	t.Run("invalid report flashes the form back", func(t *testing.T) {
+		w := report(session, url.Values{
+			"reason": {"boring"}, "evidence": {"  see her posts  "},
+		})
+		flash := expectFlash(t, w, "/user/alice")
+		if flash.Fields["reason"] == "" || flash.Values["reason"] != "boring" || flash.Values["evidence"] != "see her posts" {
+			t.Errorf("expected the submission and its error, got %+v", flash)
+		}
+	})
+
+	t.Run("report returns to the profile", func(t *testing.T) {
+		w := report(session, url.Values{
+			"reason": {"spam"}, "evidence": {"https://example.com/post/1"},
+		})
+		if loc := w.Header().Get("Location"); loc != "/user/alice?reported=1" {
+			t.Fatalf("expected redirect to /user/alice?reported=1, got %q", loc)
+		}
+	})
+
+	queue, err := moderationSvc.Queue(ctx, admin)
+	if err != nil || len(queue) != 1 {
+		t.Fatalf("expected one report in the queue, got %v, %v", queue, err)
+	}
+	id := queue[0].ID.String()
+	resolve := func(session, action string) *httptest.ResponseRecorder {
+		return post(h.Resolve(), "/api/admin/reports/"+id+"/resolve", "id", id, session, url.Values{"action": {action}})
+	}
+
+	t.Run("non-admins get not found", func(t *testing.T) {
+		w := resolve(session, "suspend")
+		if w.Code != http.StatusNotFound {
+			t.Errorf("expected 404, got %d", w.Code)
+		}
+	})
+
+	t.Run("invalid action flashes the queue", func(t *testing.T) {
+		flash := expectFlash(t, resolve(adminSession, "ban"), reportsPage)
+		if flash.Fields["action"] == "" {
+			t.Errorf("expected an action error, got %+v", flash)
+		}
+	})
+
+	t.Run("suspend returns to the queue", func(t *testing.T) {
+		w := resolve(adminSession, "suspend")
+		if loc := w.Header().Get("Location"); loc != reportsPage+"?resolved=1" {
+			t.Fatalf("expected redirect to the queue, got %q", loc)
+		}
+		if alice, _ := users.GetByHandle(ctx, "alice"); !alice.Suspended() {
+			t.Error("expected alice to be suspended")
+		}
+	})
+
+	t.Run("resolving twice flashes an error", func(t *testing.T) {
+		flash := expectFlash(t, resolve(adminSession, "dismiss"), reportsPage)
+		if flash.Error != "error.reportResolved" {
+			t.Errorf("expected error.reportResolved, got %q", flash.Error)
+		}
+	})
+}
@@ -0,0 +1,270 @@
+package handlers
+
+import (
+	"net/http"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+
+	"myapp/i18n"
+	"myapp/openapi"
+	"myapp/services"
+)
+
+// apiRoute documents one route registered on the server mux. Every route
+// must appear here; main's tests fail otherwise.
+type apiRoute struct {
+	pattern string // ServeMux pattern, e.g. "GET /api/v1/me"
+	id      string
+	summary string
+	tag     string
+	// scope is the token scope required, "session" for routes that only
+	// accept the session cookie, or "" for public routes.
+	scope       string
+	request     *openapi.MediaType
+	requestType string
+	status      int
+	response    *openapi.MediaType
+	// responseType defaults to application/json.
+	responseType string
+	errors       []int
+	// moved documents a 301 to the resource under its new name.
+	moved string
+}
+
+const (
+	tagAPI        = "api"
+	tagForms      = "forms"
+	tagOperations = "operations"
+	sessionOnly   = "session"
+)
+
+// apiVersion is the version of the documented API, bumped with the spec.
+const apiVersion = "1.0.0"
+
+var pathParam = regexp.MustCompile(`\{([a-zA-Z_]+)\}`)
+
+// OpenAPISpec describes every route the server registers. JSON schemas are
+// derived from the structs the API handlers encode, and error codes from
+// the translated error keys.
+func OpenAPISpec() *openapi.Document {
+	doc := openapi.New("MyApp API", apiVersion)
+	doc.Info.Description = "JSON API under /api/v1, plus the HTML form endpoints and operational routes served by the same mux."
+	doc.Components.SecuritySchemes["bearerAuth"] = openapi.SecurityScheme{
+		Type:        "http",
+		Scheme:      "bearer",
+		Description: "A session token from signup or login, or a personal access token (" + services.TokenPrefix + "...). Access tokens are limited to the scopes listed on each operation.",
+	}
+	doc.Components.SecuritySchemes["sessionCookie"] = openapi.SecurityScheme{
+		Type: "apiKey",
+		In:   "cookie",
+		Name: "session",
+	}
+
+	doc.Ref("ErrorDetail", apiErrorDetail{})
+	doc.Components.Schemas["ErrorDetail"].Properties["code"].Enum = errorCodes()
+	errorBody := &openapi.MediaType{Schema: doc.Ref("Error", apiErrorBody{})}
+
+	for _, rt := range apiRoutes(doc) {
+		method, path, _ := strings.Cut(rt.pattern, " ")
+		op := &openapi.Operation{
+			OperationID: rt.id,
+			Summary:     rt.summary,
+			Tags:        []string{rt.tag},
+			Responses:   map[string]*openapi.Response{},
+		}
+		for _, m := range pathParam.FindAllStringSubmatch(path, -1) {
+			op.Parameters = append(op.Parameters, openapi.Parameter{
+				Name: m[1], In: "path", Required: true, Schema: &openapi.Schema{Type: "string"},
+			})
+		}
+		switch rt.scope {
+		case "":
+		case sessionOnly:
+			op.Security = []map[string][]string{{"sessionCookie": {}}}
+		default:
+			op.Security = []map[string][]string{{"bearerAuth": {rt.scope}}, {"sessionCookie": {}}}
+		}
+		if rt.request != nil {
+			op.RequestBody = &openapi.RequestBody{
+				Required: true,
+				Content:  map[string]openapi.MediaType{rt.requestType: *rt.request},
+			}
+		}
+
+		resp := &openapi.Response{Description: http.StatusText(rt.status)}
+		if rt.response != nil {
+			contentType := rt.responseType
+			if contentType == "" {
+				contentType = "application/json"
+			}
+			resp.Content = map[string]openapi.MediaType{contentType: *rt.response}
+		}
+		if rt.status == http.StatusSeeOther {
+			resp.Description = "Redirects to the next page; failures redirect back to the form and flash the error and submitted values."
+			resp.Headers = map[string]openapi.Header{"Location": {Schema: &openapi.Schema{Type: "string"}}}
+		}
+		op.Responses[strconv.Itoa(rt.status)] = resp
+
+		if rt.moved != "" {
+			op.Responses[strconv.Itoa(http.StatusMovedPermanently)] = &openapi.Response{
+				Description: rt.moved,
+				Headers:     map[string]openapi.Header{"Location": {Schema: &openapi.Schema{Type: "string"}}},
+			}
+		}
+		for _, status := range rt.errors {
+			errResp := &openapi.Response{
+				Description: http.StatusText(status),
+				Content:     map[string]openapi.MediaType{"application/json": *errorBody},
+			}
+			if status == http.StatusTooManyRequests {
+				errResp.Headers = map[string]openapi.Header{
+					"Retry-After": {Description: "Seconds until the request may be retried.", Schema: &openapi.Schema{Type: "integer"}},
+				}
+			}
+			op.Responses[strconv.Itoa(status)] = errResp
+		}
+		doc.AddOperation(method, path, op)
+	}
+	return doc
+}
+
+func apiRoutes(doc *openapi.Document) []apiRoute {
+	jsonBody := func(name string, v any) *openapi.MediaType {
+		return &openapi.MediaType{Schema: doc.Ref(name, v)}
+	}
+	account := jsonBody("Account", apiAccount{})
+	token := jsonBody("Token", apiToken{})
+	object := &openapi.MediaType{Schema: &openapi.Schema{Type: "object"}}
+	profileForm := formBody("handle", "display_name", "bio", "pronouns", "location", "country", "avatar")
+	profileForm.Schema.Properties["avatar"] = &openapi.Schema{Type: "string", Format: "binary"}
+	for _, f := range []string{"link_type", "link_url", "link_label"} {
+		profileForm.Schema.Properties[f] = &openapi.Schema{Type: "array", Items: &openapi.Schema{Type: "string"}}
+	}
+	var visibilities []string
+	for _, v := range services.Visibilities {
+		visibilities = append(visibilities, string(v))
+	}
+	privacyForm := formBody("hide_from_search")
+	for _, field := range services.PrivateFields {
+		privacyForm.Schema.Properties["visibility."+field] = &openapi.Schema{Type: "string", Enum: visibilities}
+	}
+	reportForm := formBody("reason", "evidence")
+	reportForm.Schema.Properties["reason"].Enum = services.ReportReasons
+	resolveForm := formBody("action")
+	resolveForm.Schema.Properties["action"].Enum = services.ModerationActions
+
+	return []apiRoute{
+		{pattern: "POST /api/v1/auth/signup", id: "signup", summary: "Create an account", tag: tagAPI,
+			request: jsonBody("SignupRequest", apiSignupRequest{}), requestType: "application/json",
+			status: http.StatusCreated, response: token,
+			errors: []int{http.StatusBadRequest, http.StatusConflict, http.StatusUnprocessableEntity, http.StatusTooManyRequests}},
+		{pattern: "POST /api/v1/auth/login", id: "login", summary: "Exchange credentials for a session token", tag: tagAPI,
+			request: jsonBody("LoginRequest", apiLoginRequest{}), requestType: "application/json",
+			status: http.StatusOK, response: token,
+			errors: []int{http.StatusBadRequest, http.StatusUnauthorized, http.StatusForbidden, http.StatusUnprocessableEntity, http.StatusTooManyRequests}},
+		{pattern: "POST /api/v1/auth/logout", id: "logout", summary: "Clear the session cookie", tag: tagAPI,
+			status: http.StatusNoContent},
+		{pattern: "GET /api/v1/me", id: "getMe", summary: "Get your account", tag: tagAPI,
+			scope: services.ScopeProfileRead, status: http.StatusOK, response: account,
+			errors: []int{http.StatusUnauthorized, http.StatusForbidden}},
+		{pattern: "PATCH /api/v1/me", id: "updateMe", summary: "Update your profile; omitted fields are unchanged", tag: tagAPI,
+			scope:   services.ScopeProfileWrite,
+			request: jsonBody("ProfileUpdate", apiProfileUpdate{}), requestType: "application/json",
+			status: http.StatusOK, response: account,
+			errors: []int{http.StatusBadRequest, http.StatusUnauthorized, http.StatusForbidden, http.StatusConflict, http.StatusUnprocessableEntity, http.StatusTooManyRequests}},
+		{pattern: "PUT /api/v1/me/avatar", id: "uploadAvatar", summary: "Replace your avatar with the raw image in the body", tag: tagAPI,
+			scope:   services.ScopeProfileWrite,
+			request: &openapi.MediaType{Schema: &openapi.Schema{Type: "string", Format: "binary"}}, requestType: "image/*",
+			status: http.StatusOK, response: account,
+			errors: []int{http.StatusBadRequest, http.StatusUnauthorized, http.StatusForbidden, http.StatusRequestEntityTooLarge, http.StatusUnsupportedMediaType, http.StatusTooManyRequests}},
+		{pattern: "GET /api/v1/users/{handle}", id: "getProfile", summary: "Get a public profile", tag: tagAPI,
+			status: http.StatusOK, response: jsonBody("Profile", apiProfile{}),
+			errors: []int{http.StatusNotFound},
+			moved:  "The handle was changed; Location is the profile under its current handle."},
+		{pattern: "GET /api/openapi.json", id: "getOpenAPI", summary: "This document", tag: tagAPI,
+			status: http.StatusOK, response: object},
+
+		{pattern: "POST /api/signup", id: "formSignup", summary: "Sign up from the HTML form", tag: tagForms,
+			request: formBody("email", "password", "confirm_password", "handle"), requestType: "application/x-www-form-urlencoded",
+			status: http.StatusSeeOther},
+		{pattern: "POST /api/login", id: "formLogin", summary: "Log in from the HTML form", tag: tagForms,
+			request: formBody("email", "password"), requestType: "application/x-www-form-urlencoded",
+			status: http.StatusSeeOther},
+		{pattern: "POST /api/logout", id: "formLogout", summary: "Log out and return home", tag: tagForms,
+			status: http.StatusSeeOther},
+		{pattern: "POST /api/user/update", id: "formUpdateProfile", summary: "Save the profile edit form", tag: tagForms,
+			scope:   sessionOnly,
+			request: profileForm, requestType: "multipart/form-data",
+			status: http.StatusSeeOther},
+		{pattern: "POST /api/set-lang", id: "formSetLanguage", summary: "Set the lang cookie and return to the referring page", tag: tagForms,
+			request: formBody("lang"), requestType: "application/x-www-form-urlencoded",
+			status: http.StatusSeeOther},
+		{pattern: "POST /api/user/privacy", id: "formUpdatePrivacy", summary: "Save the privacy settings form", tag: tagForms,
+			scope:   sessionOnly,
+			request: privacyForm, requestType: "application/x-www-form-urlencoded",
+			status: http.StatusSeeOther},
+		{pattern: "POST /api/tokens", id: "formCreateToken", summary: "Create a personal access token", tag: tagForms,
+			scope:   sessionOnly,
+			request: formBody("name", "scope", "expires_in"), requestType: "application/x-www-form-urlencoded",
+			status: http.StatusSeeOther},
+		{pattern: "POST /api/tokens/{id}/revoke", id: "formRevokeToken", summary: "Revoke a personal access token", tag: tagForms,
+			scope: sessionOnly, status: http.StatusSeeOther},
+		{pattern: "POST /api/user/{handle}/follow", id: "formFollow", summary: "Follow a user and return to their profile", tag: tagForms,
+			scope: sessionOnly, status: http.StatusSeeOther},
+		{pattern: "POST /api/user/{handle}/unfollow", id: "formUnfollow", summary: "Unfollow a user and return to their profile", tag: tagForms,
+			scope: sessionOnly, status: http.StatusSeeOther},
+		{pattern: "POST /api/user/{handle}/block", id: "formBlock", summary: "Block a user and return to their profile", tag: tagForms,
+			scope: sessionOnly, status: http.StatusSeeOther},
+		{pattern: "POST /api/user/{handle}/unblock", id: "formUnblock", summary: "Unblock a user and return to their profile", tag: tagForms,
+			scope: sessionOnly, status: http.StatusSeeOther},
+		{pattern: "POST /api/user/{handle}/report", id: "formReport", summary: "Report a user to the admins and return to their profile", tag: tagForms,
+			scope:   sessionOnly,
+			request: reportForm, requestType: "application/x-www-form-urlencoded",
+			status: http.StatusSeeOther},
+		{pattern: "POST /api/admin/reports/{id}/resolve", id: "formResolveReport", summary: "Dismiss a report, or warn or suspend the reported user (admins only)", tag: tagForms,
+			scope:   sessionOnly,
+			request: resolveForm, requestType: "application/x-www-form-urlencoded",
+			status: http.StatusSeeOther, errors: []int{http.StatusNotFound}},
+
+		{pattern: "GET /healthz", id: "healthz", summary: "Liveness probe", tag: tagOperations,
+			status: http.StatusOK, response: object},
+		{pattern: "GET /readyz", id: "readyz", summary: "Readiness probe; 503 when a dependency is down", tag: tagOperations,
+			status: http.StatusOK, response: object},
+		{pattern: "GET /version", id: "version", summary: "Build information", tag: tagOperations,
+			status: http.StatusOK, response: object},
+		{pattern: "GET /metrics", id: "metrics", summary: "Prometheus metrics", tag: tagOperations,
+			status: http.StatusOK, response: &openapi.MediaType{Schema: &openapi.Schema{Type: "string"}}, responseType: "text/plain"},
+	}
+}
+
+// formBody describes a form whose fields are all strings. Required fields
+// are left to the handlers, which redirect with an error instead of failing.
+func formBody(fields ...string) *openapi.MediaType {
+	s := &openapi.Schema{Type: "object", Properties: map[string]*openapi.Schema{}}
+	for _, f := range fields {
+		s.Properties[f] = &openapi.Schema{Type: "string"}
+	}
+	return &openapi.MediaType{Schema: s}
+}
+
+// errorCodes lists the codes API errors can carry: the translated error
+// keys without their "error." prefix.
+func errorCodes() []string {
+	var codes []string
+	for key := range i18n.Translations("en") {
+		if code, ok := strings.CutPrefix(key, "error."); ok {
+			codes = append(codes, code)
+		}
+	}
+	slices.Sort(codes)
+	return codes
+}
+
+// OpenAPI serves doc, which must not be modified afterwards.
+func OpenAPI(doc *openapi.Document) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusOK, doc)
+	}
+}
@@ -0,0 +1,73 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"slices"
+	"strings"
+	"testing"
+
+	"myapp/i18n"
+	"myapp/services"
+)
+
+func TestOpenAPI(t *testing.T) {
+	if err := i18n.Load(); err != nil {
+		t.Fatalf("failed to load translations: %v", err)
+	}
+	w := httptest.NewRecorder()
+	OpenAPI(OpenAPISpec())(w, httptest.NewRequest(http.MethodGet, "/api/openapi.json", nil))
+	if w.Code != http.StatusOK || w.Header().Get("Content-Type") != "application/json" {
+		t.Fatalf("expected 200 JSON, got %d %q", w.Code, w.Header().Get("Content-Type"))
+	}
+	raw := w.Body.String()
+
+	var spec struct {
+		OpenAPI    string `json:"openapi"`
+		Components struct {
+			Schemas map[string]json.RawMessage `json:"schemas"`
+		} `json:"components"`
+	}
+	if err := json.Unmarshal([]byte(raw), &spec); err != nil {
+		t.Fatalf("invalid JSON: %v", err)
+	}
+	if spec.OpenAPI != "3.1.0" {
+		t.Errorf("expected OpenAPI 3.1.0, got %q", spec.OpenAPI)
+	}
+
+	t.Run("references resolve", func(t *testing.T) {
+		for _, part := range strings.Split(raw, `"$ref":"#/components/schemas/`)[1:] {
+			name, _, _ := strings.Cut(part, `"`)
+			if _, ok := spec.Components.Schemas[name]; !ok {
+				t.Errorf("dangling reference to %q", name)
+			}
+		}
+	})
+
+	t.Run("error codes come from translations", func(t *testing.T) {
+		codes := OpenAPISpec().Components.Schemas["ErrorDetail"].Properties["code"].Enum
+		for _, code := range []string{"insufficientScope", "handleTaken", "rateLimited"} {
+			if !slices.Contains(codes, code) {
+				t.Errorf("expected %q in error code enum %v", code, codes)
+			}
+		}
+	})
+}
+
+// The PATCH body must offer every profile field the service accepts, except
+// the avatar, which has its own endpoint.
+func TestProfileUpdateCoversService(t *testing.T) {
+	body := reflect.TypeOf(apiProfileUpdate{})
+	input := reflect.TypeOf(services.UpdateProfileInput{})
+	for i := range input.NumField() {
+		name := input.Field(i).Name
+		if name == "AvatarURL" {
+			continue
+		}
+		if _, ok := body.FieldByName(name); !ok {
+			t.Errorf("apiProfileUpdate has no field for UpdateProfileInput.%s", name)
+		}
+	}
+}
@@ -0,0 +1,122 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"html/template"
+	"net/http"
+
+	"myapp/i18n"
+	"myapp/tracing"
+	"myapp/util"
+)
+
+// Redirect is returned from a page loader to send the browser elsewhere
+// instead of rendering the page.
+type Redirect struct {
+	URL    string
+	Status int
+}
+
+func (r *Redirect) Error() string {
+	return "redirect to " + r.URL
+}
+
+func (r *Redirect) RedirectURL() string {
+	return r.URL
+}
+
+func (r *Redirect) RedirectStatusCode() int {
+	if r.Status == 0 {
+		return http.StatusSeeOther
+	}
+	return r.Status
+}
+
+type pageErrorKey struct{}
+
+// pageError carries the error a PageLoader failed with to PageErrors.
+type pageError struct {
+	err *util.AppError
+}
+
+// PageLoader makes the errors of load, redirects aside, go through
+// translateError like those of the form and JSON handlers, so PageErrors
+// can answer with their status and a localized message.
+func PageLoader(load tracing.PropsLoader) tracing.PropsLoader {
+	return func(r *http.Request) (map[string]any, error) {
+		props, err := load(r)
+		var redirect *Redirect
+		if err == nil || errors.As(err, &redirect) {
+			return props, err
+		}
+		appErr := translateError(r, err)
+		if holder, ok := r.Context().Value(pageErrorKey{}).(*pageError); ok {
+			holder.err = appErr
+		}
+		return nil, appErr
+	}
+}
+
+// PageErrors serves the error page for pages whose PageLoader failed. bifrost
+// answers every loader error with a 500 showing the error text, so once a
+// loader has failed, the response next writes is replaced.
+func PageErrors(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		holder := &pageError{}
+		r = r.WithContext(context.WithValue(r.Context(), pageErrorKey{}, holder))
+		next.ServeHTTP(&pageErrorWriter{ResponseWriter: w, r: r, holder: holder}, r)
+	})
+}
+
+// pageErrorWriter passes writes through until a loader fails, then writes
+// the error page in place of the first write and drops the rest.
+type pageErrorWriter struct {
+	http.ResponseWriter
+	r        *http.Request
+	holder   *pageError
+	replaced bool
+}
+
+func (w *pageErrorWriter) WriteHeader(status int) {
+	switch {
+	case w.replaced:
+	case w.holder.err != nil:
+		w.replaced = true
+		writeErrorPage(w.ResponseWriter, w.r, w.holder.err)
+	default:
+		w.ResponseWriter.WriteHeader(status)
+	}
+}
+
+func (w *pageErrorWriter) Write(b []byte) (int, error) {
+	if w.holder.err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		return len(b), nil
+	}
+	return w.ResponseWriter.Write(b)
+}
+
+// Unwrap lets http.ResponseController reach the underlying writer.
+func (w *pageErrorWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+var errorPage = template.Must(template.New("error").Parse(`<!doctype html>
+<html lang="{{.Locale}}">
+<head><meta charset="utf-8"><meta name="viewport" content="width=device-width, initial-scale=1"><title>{{.Message}} - MyApp</title></head>
+<body><main><h1>{{.Message}}</h1><p><a href="/">{{.Home}}</a></p></main></body>
+</html>
+`))
+
+func writeErrorPage(w http.ResponseWriter, r *http.Request, appErr *util.AppError) {
+	locale := i18n.DetectLocale(r)
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(appErr.Status())
+	_ = errorPage.Execute(w, map[string]string{
+		"Locale":  locale,
+		"Message": i18n.T(locale, appErr.Message),
+		"Home":    i18n.T(locale, "errorPage.home"),
+	})
+}
@@ -0,0 +1,95 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"myapp/i18n"
+	"myapp/model"
+	"myapp/tracing"
+)
+
+// bifrostPage serves a page the way bifrost does: it follows redirects,
+// answers any other loader error with a 500 showing the error text, and
+// otherwise renders the props.
+func bifrostPage(load tracing.PropsLoader) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		props, err := load(r)
+		var redirect *Redirect
+		if errors.As(err, &redirect) {
+			http.Redirect(w, r, redirect.RedirectURL(), redirect.RedirectStatusCode())
+			return
+		}
+		if err != nil {
+			w.Header().Set("Content-Type", "text/html; charset=utf-8")
+			w.WriteHeader(http.StatusInternalServerError)
+			_, _ = w.Write([]byte("<pre>" + err.Error() + "</pre>"))
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+		_, _ = w.Write([]byte(props["page"].(string)))
+	})
+}
+
+func TestPageErrors(t *testing.T) {
+	if err := i18n.Load(); err != nil {
+		t.Fatalf("failed to load translations: %v", err)
+	}
+	serve := func(loadErr error, locale string) *httptest.ResponseRecorder {
+		h := PageErrors(bifrostPage(PageLoader(func(*http.Request) (map[string]any, error) {
+			if loadErr != nil {
+				return nil, loadErr
+			}
+			return map[string]any{"page": "rendered"}, nil
+		})))
+		req := httptest.NewRequest(http.MethodGet, "/page", nil)
+		req.Header.Set("Accept-Language", locale)
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, req)
+		return w
+	}
+
+	cases := []struct {
+		name   string
+		err    error
+		locale string
+		status int
+		text   string
+	}{
+		{"not found", model.ErrNotFound, "en", http.StatusNotFound, i18n.T("en", "error.notFound")},
+		{"localized", model.ErrNotFound, "es", http.StatusNotFound, i18n.T("es", "error.notFound")},
+		{"bad request", model.ErrInvalidCursor, "en", http.StatusBadRequest, i18n.T("en", "error.invalidRequest")},
+		{"internal errors stay generic", errors.New("disk on fire"), "en", http.StatusInternalServerError, ""},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			w := serve(tc.err, tc.locale)
+			if w.Code != tc.status {
+				t.Errorf("expected %d, got %d", tc.status, w.Code)
+			}
+			body := w.Body.String()
+			if !strings.Contains(body, tc.text) {
+				t.Errorf("expected %q in the page, got %s", tc.text, body)
+			}
+			if strings.Contains(body, "error.") || strings.Contains(body, "disk on fire") || strings.Contains(body, "<pre>") {
+				t.Errorf("expected bifrost's error page to be replaced, got %s", body)
+			}
+		})
+	}
+
+	t.Run("successful pages pass through", func(t *testing.T) {
+		if w := serve(nil, "en"); w.Code != http.StatusOK || w.Body.String() != "rendered" {
+			t.Errorf("got %d %q", w.Code, w.Body.String())
+		}
+	})
+
+	t.Run("redirects pass through", func(t *testing.T) {
+		w := serve(&Redirect{URL: "/login"}, "en")
+		if w.Code != http.StatusSeeOther || w.Header().Get("Location") != "/login" {
+			t.Errorf("expected a redirect to /login, got %d %q", w.Code, w.Header().Get("Location"))
+		}
+	})
+}
@@ -0,0 +1,54 @@
+package handlers
+
+import (
+	"net/http"
+
+	"myapp/model"
+	"myapp/services"
+)
+
+const privacyPage = "/settings/privacy"
+
+type PrivacyHandler struct {
+	privacySvc *services.PrivacyService
+	authSvc    *services.AuthService
+	flash      *Flasher
+}
+
+func NewPrivacyHandler(privacySvc *services.PrivacyService, authSvc *services.AuthService, flash *Flasher) *PrivacyHandler {
+	return &PrivacyHandler{privacySvc: privacySvc, authSvc: authSvc, flash: flash}
+}
+
+// Update saves the privacy settings form. Each field's visibility comes from
+// a visibility.<field> select, and hide_from_search is a checkbox.
+func (h *PrivacyHandler) Update() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		currentUser := h.authSvc.GetUserFromRequest(r)
+		if currentUser == nil {
+			http.Redirect(w, r, "/login", http.StatusSeeOther)
+			return
+		}
+		_ = r.ParseForm()
+
+		input := services.UpdatePrivacyInput{
+			Visibility:     model.ProfileVisibility{},
+			HideFromSearch: r.FormValue("hide_from_search") != "",
+		}
+		values := map[string]string{}
+		for _, field := range services.PrivateFields {
+			if v := r.FormValue("visibility." + field); v != "" {
+				input.Visibility[field] = model.Visibility(v)
+				values["visibility."+field] = v
+			}
+		}
+		if input.HideFromSearch {
+			values["hide_from_search"] = "on"
+		}
+
+		if err := h.privacySvc.UpdatePrivacy(r.Context(), currentUser.ID.String(), input); err != nil {
+			h.flash.redirectWithError(w, r, privacyPage, err, values)
+			return
+		}
+		http.Redirect(w, r, privacyPage+"?success=1", http.StatusSeeOther)
+	}
+}
@@ -0,0 +1,69 @@
+package handlers
+
+import (
+	"context"
+	"net/url"
+	"testing"
+
+	"myapp/i18n"
+	"myapp/model"
+	"myapp/services"
+	"myapp/testutil"
+)
+
+func newTestPrivacyHandler(t *testing.T) (*PrivacyHandler, *services.AuthService) {
+	t.Helper()
+	if err := i18n.Load(); err != nil {
+		t.Fatalf("failed to load translations: %v", err)
+	}
+	db := testutil.NewTestDB(t, &model.User{}, &model.HandleHistory{}, &model.Follow{}, &model.Block{})
+	users := model.NewUserRepository(db)
+	authSvc := services.NewAuthService(users, "test-secret", nil, services.EmailPolicy{}, nil, nil)
+	privacySvc := services.NewPrivacyService(users, services.NewFollowService(model.NewFollowRepository(db), model.NewBlockRepository(db), users))
+	return NewPrivacyHandler(privacySvc, authSvc, testFlasher), authSvc
+}
+
+func TestHandlerUpdatePrivacy(t *testing.T) {
+	ctx := context.Background()
+	h, authSvc := newTestPrivacyHandler(t)
+	session, _ := authSvc.Signup(ctx, "user@example.com", "password123", "testuser")
+
+	t.Run("redirect to login if not authenticated", func(t *testing.T) {
+		w := postForm(h.Update(), "/api/user/privacy", url.Values{"visibility.email": {"public"}})
+		if loc := w.Header().Get("Location"); loc != "/login" {
+			t.Errorf("expected redirect to /login, got %q", loc)
+		}
+	})
+
+	t.Run("invalid visibility flashes the form back", func(t *testing.T) {
+		w := postAuthedForm(h.Update(), "/api/user/privacy", session, url.Values{
+			"visibility.email": {"everyone"}, "hide_from_search": {"on"},
+		})
+		flash := expectFlash(t, w, privacyPage)
+		if flash.Fields["visibility.email"] == "" || flash.Values["visibility.email"] != "everyone" || flash.Values["hide_from_search"] != "on" {
+			t.Errorf("expected the submission and its error, got %+v", flash)
+		}
+	})
+
+	t.Run("success saves the settings", func(t *testing.T) {
+		w := postAuthedForm(h.Update(), "/api/user/privacy", session, url.Values{
+			"visibility.email": {"public"}, "visibility.links": {"followers"}, "hide_from_search": {"on"},
+		})
+		if loc := w.Header().Get("Location"); loc != privacyPage+"?success=1" {
+			t.Fatalf("expected redirect to the privacy page, got %q", loc)
+		}
+		user := authSvc.GetUserFromRequest(sessionRequest(session))
+		if user.Visibility.Of(model.FieldEmail) != model.VisibilityPublic ||
+			user.Visibility.Of(model.FieldLinks) != model.VisibilityFollowers || !user.HideFromSearch {
+			t.Errorf("unexpected settings %v, hidden %v", user.Visibility, user.HideFromSearch)
+		}
+	})
+
+	t.Run("omitted fields return to their defaults", func(t *testing.T) {
+		postAuthedForm(h.Update(), "/api/user/privacy", session, url.Values{})
+		user := authSvc.GetUserFromRequest(sessionRequest(session))
+		if user.Visibility.Of(model.FieldEmail) != model.VisibilityPrivate || user.HideFromSearch {
+			t.Errorf("expected defaults, got %v, hidden %v", user.Visibility, user.HideFromSearch)
+		}
+	})
+}
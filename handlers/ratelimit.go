@@ -0,0 +1,181 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"math"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"myapp/ratelimit"
+	"myapp/services"
+	"myapp/util"
+)
+
+// RateLimitRule pairs a policy with the request attribute it counts against.
+// A rule whose Key returns "" does not apply to the request.
+type RateLimitRule struct {
+	Policy ratelimit.Policy
+	Key    func(r *http.Request) string
+}
+
+type RateLimiter struct {
+	store      ratelimit.Store
+	trustProxy bool
+	flash      *Flasher
+}
+
+// NewRateLimiter creates a limiter backed by store. With trustProxy set the
+// client IP is taken from the last X-Forwarded-For entry, which is the one
+// appended by the reverse proxy in front of the app. Rejected form posts are
+// reported through flash.
+func NewRateLimiter(store ratelimit.Store, trustProxy bool, flash *Flasher) *RateLimiter {
+	return &RateLimiter{store: store, trustProxy: trustProxy, flash: flash}
+}
+
+// PerIP counts requests per client IP.
+func (l *RateLimiter) PerIP(p ratelimit.Policy) RateLimitRule {
+	return RateLimitRule{Policy: p, Key: func(r *http.Request) string {
+		return ClientIP(r, l.trustProxy)
+	}}
+}
+
+// PerFormValue counts requests per value of a form field, e.g. the email a
+// login is attempted for. Values are trimmed and lowercased.
+func PerFormValue(p ratelimit.Policy, field string) RateLimitRule {
+	return RateLimitRule{Policy: p, Key: func(r *http.Request) string {
+		return strings.ToLower(strings.TrimSpace(r.FormValue(field)))
+	}}
+}
+
+// PerJSONField is PerFormValue for JSON endpoints. It buffers the body and
+// restores it so the handler can decode it again.
+func PerJSONField(p ratelimit.Policy, field string) RateLimitRule {
+	return RateLimitRule{Policy: p, Key: func(r *http.Request) string {
+		data, err := io.ReadAll(io.LimitReader(r.Body, maxJSONBody))
+		if err != nil {
+			return ""
+		}
+		r.Body = io.NopCloser(bytes.NewReader(data))
+		var fields map[string]any
+		if err := json.Unmarshal(data, &fields); err != nil {
+			return ""
+		}
+		value, _ := fields[field].(string)
+		return strings.ToLower(strings.TrimSpace(value))
+	}}
+}
+
+// PerEmail keys rule by its value as emails normalizes it, so spellings
+// of one address that log in to the same account share a bucket. Values
+// that are not valid emails are counted as rule keys them.
+func PerEmail(rule RateLimitRule, emails services.EmailPolicy) RateLimitRule {
+	key := rule.Key
+	rule.Key = func(r *http.Request) string {
+		value := key(r)
+		if normalized, err := emails.Normalize(value); err == nil {
+			return normalized
+		}
+		return value
+	}
+	return rule
+}
+
+// PerUser counts requests per signed-in user; anonymous requests are not
+// counted by this rule.
+func PerUser(p ratelimit.Policy, auth *services.AuthService) RateLimitRule {
+	return RateLimitRule{Policy: p, Key: func(r *http.Request) string {
+		if id := auth.Authenticate(r); id != nil {
+			return id.User.ID.String()
+		}
+		return ""
+	}}
+}
+
+// Limit takes a token from every applicable rule's bucket. Once any bucket is
+// empty the request is redirected back to the page it was posted from (or
+// fallback) with a flashed error and a Retry-After header. Store errors
+// are logged and the request is let through.
+func (l *RateLimiter) Limit(fallback string, rules ...RateLimitRule) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			retryAfter, limited := l.check(r, rules)
+			if !limited {
+				next.ServeHTTP(w, r)
+				return
+			}
+			w.Header().Set("Retry-After", strconv.Itoa(retryAfter))
+			l.flash.redirectWithError(w, r, refererPath(r, fallback), errRateLimited, nil)
+		})
+	}
+}
+
+// LimitAPI is Limit for JSON endpoints: it answers 429 with an error body
+// and a Retry-After header instead of redirecting.
+func (l *RateLimiter) LimitAPI(rules ...RateLimitRule) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			retryAfter, limited := l.check(r, rules)
+			if !limited {
+				next.ServeHTTP(w, r)
+				return
+			}
+			w.Header().Set("Retry-After", strconv.Itoa(retryAfter))
+			writeAPIError(w, r, errRateLimited)
+		})
+	}
+}
+
+// check takes a token from every applicable rule and, when one is empty,
+// reports how many seconds the client should wait.
+func (l *RateLimiter) check(r *http.Request, rules []RateLimitRule) (retryAfter int, limited bool) {
+	for _, rule := range rules {
+		key := rule.Key(r)
+		if key == "" {
+			continue
+		}
+		res, err := l.store.Allow(r.Context(), key, rule.Policy)
+		if err != nil {
+			util.Logger(r.Context()).Warn("rate limiter unavailable", "policy", rule.Policy.Name, "error", err)
+			continue
+		}
+		if res.Allowed {
+			continue
+		}
+		retryAfter = int(math.Ceil(res.RetryAfter.Seconds()))
+		util.Logger(r.Context()).Warn("rate limited", "policy", rule.Policy.Name, "retry_after_s", retryAfter)
+		return retryAfter, true
+	}
+	return 0, false
+}
+
+// ClientIP returns the client address of r without the port.
+func ClientIP(r *http.Request, trustProxy bool) string {
+	if trustProxy {
+		if fwd := r.Header.Get("X-Forwarded-For"); fwd != "" {
+			parts := strings.Split(fwd, ",")
+			if ip := strings.TrimSpace(parts[len(parts)-1]); ip != "" {
+				return ip
+			}
+		}
+	}
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+// refererPath returns the path of the Referer header, or fallback. Only the
+// path is kept so a forged Referer cannot turn this into an open redirect.
+func refererPath(r *http.Request, fallback string) string {
+	u, err := url.Parse(r.Referer())
+	if err != nil || !strings.HasPrefix(u.Path, "/") || strings.HasPrefix(u.Path, "//") {
+		return fallback
+	}
+	return u.Path
+}
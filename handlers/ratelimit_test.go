@@ -0,0 +1,142 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"myapp/i18n"
+	"myapp/ratelimit"
+	"myapp/services"
+)
+
+func TestRateLimiter(t *testing.T) {
+	if err := i18n.Load(); err != nil {
+		t.Fatalf("failed to load translations: %v", err)
+	}
+	policy := ratelimit.Policy{Name: "test", Limit: 2, Period: time.Minute}
+	ok := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	})
+
+	send := func(h http.Handler, remoteAddr string, mutate func(*http.Request)) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/api/login", strings.NewReader(url.Values{"email": {"User@Example.com"}}.Encode()))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		req.RemoteAddr = remoteAddr
+		if mutate != nil {
+			mutate(req)
+		}
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, req)
+		return w
+	}
+
+	t.Run("rejects with retry-after and flashed error", func(t *testing.T) {
+		l := NewRateLimiter(ratelimit.NewMemoryStore(), false, testFlasher)
+		h := l.Limit("/login", l.PerIP(policy))(ok)
+		for range policy.Limit {
+			if w := send(h, "1.2.3.4:5000", nil); w.Code != http.StatusNoContent {
+				t.Fatalf("expected request to pass, got %d", w.Code)
+			}
+		}
+
+		w := send(h, "1.2.3.4:5001", func(r *http.Request) { r.Header.Set("Accept-Language", "es") })
+		if flash := expectFlash(t, w, "/login"); flash.Error != "error.rateLimited" {
+			t.Errorf("got flash %+v", flash)
+		}
+		if got := w.Header().Get("Retry-After"); got != "30" {
+			t.Errorf("got Retry-After %q, want 30", got)
+		}
+
+		if w := send(h, "5.6.7.8:5000", nil); w.Code != http.StatusNoContent {
+			t.Errorf("expected other IP to pass, got %d", w.Code)
+		}
+	})
+
+	t.Run("per form value", func(t *testing.T) {
+		l := NewRateLimiter(ratelimit.NewMemoryStore(), false, testFlasher)
+		h := l.Limit("/login", PerFormValue(policy, "email"))(ok)
+		send(h, "1.2.3.4:5000", nil)
+		send(h, "5.6.7.8:5000", nil)
+		if w := send(h, "9.9.9.9:5000", nil); w.Code != http.StatusSeeOther {
+			t.Errorf("expected email bucket to be shared across IPs, got %d", w.Code)
+		}
+	})
+
+	t.Run("per email shares a bucket across spellings", func(t *testing.T) {
+		l := NewRateLimiter(ratelimit.NewMemoryStore(), false, testFlasher)
+		h := l.Limit("/login", PerEmail(PerFormValue(policy, "email"), services.EmailPolicy{StripPlusTags: true}))(ok)
+		post := func(email string) int {
+			req := httptest.NewRequest(http.MethodPost, "/api/login", strings.NewReader(url.Values{"email": {email}}.Encode()))
+			req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+			w := httptest.NewRecorder()
+			h.ServeHTTP(w, req)
+			return w.Code
+		}
+		post("alice+one@example.com")
+		post("Alice+two@Example.com")
+		if code := post("alice@example.com"); code != http.StatusSeeOther {
+			t.Errorf("expected plus-tag variants to share a bucket, got %d", code)
+		}
+		if code := post("not an email"); code != http.StatusNoContent {
+			t.Errorf("expected invalid emails to get their own bucket, got %d", code)
+		}
+	})
+
+	t.Run("per json field restores body", func(t *testing.T) {
+		l := NewRateLimiter(ratelimit.NewMemoryStore(), false, testFlasher)
+		var seen []string
+		h := l.LimitAPI(PerJSONField(policy, "email"))(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			var body struct{ Email string }
+			_ = json.NewDecoder(r.Body).Decode(&body)
+			seen = append(seen, body.Email)
+		}))
+		for range policy.Limit + 1 {
+			req := httptest.NewRequest(http.MethodPost, "/api/v1/auth/login", strings.NewReader(`{"email": "User@Example.com"}`))
+			w := httptest.NewRecorder()
+			h.ServeHTTP(w, req)
+			if len(seen) > policy.Limit {
+				t.Fatal("expected the bucket to be exhausted")
+			}
+			if w.Code == http.StatusTooManyRequests && w.Header().Get("Retry-After") == "" {
+				t.Error("expected Retry-After on 429")
+			}
+		}
+		if len(seen) != policy.Limit || seen[0] != "User@Example.com" {
+			t.Errorf("handler saw %v", seen)
+		}
+	})
+
+	t.Run("redirects back to referer path", func(t *testing.T) {
+		l := NewRateLimiter(ratelimit.NewMemoryStore(), false, testFlasher)
+		h := l.Limit("/", l.PerIP(ratelimit.Policy{Name: "one", Limit: 1, Period: time.Minute}))(ok)
+		send(h, "1.2.3.4:5000", nil)
+
+		cases := map[string]string{
+			"https://myapp.com/user/alice/edit?success=1": "/user/alice/edit",
+			"https://evil.example.com//evil.example.com":  "/",
+			"": "/",
+		}
+		for referer, want := range cases {
+			w := send(h, "1.2.3.4:5000", func(r *http.Request) { r.Header.Set("Referer", referer) })
+			expectFlash(t, w, want)
+		}
+	})
+}
+
+func TestClientIP(t *testing.T) {
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.RemoteAddr = "10.0.0.1:5000"
+	req.Header.Set("X-Forwarded-For", "6.6.6.6, 1.2.3.4")
+
+	if got := ClientIP(req, false); got != "10.0.0.1" {
+		t.Errorf("untrusted: got %q, want 10.0.0.1", got)
+	}
+	if got := ClientIP(req, true); got != "1.2.3.4" {
+		t.Errorf("trusted: got %q, want 1.2.3.4", got)
+	}
+}
@@ -0,0 +1,115 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"myapp/services"
+)
+
+const (
+	tokensPage = "/settings/tokens"
+	// newTokenCookie carries a freshly created token from the create
+	// redirect to the next page render, which is the only time it is shown.
+	newTokenCookie = "new_token"
+)
+
+type newTokenKey struct{}
+
+type TokenHandler struct {
+	tokenSvc *services.TokenService
+	authSvc  *services.AuthService
+	flash    *Flasher
+}
+
+func NewTokenHandler(tokenSvc *services.TokenService, authSvc *services.AuthService, flash *Flasher) *TokenHandler {
+	return &TokenHandler{tokenSvc: tokenSvc, authSvc: authSvc, flash: flash}
+}
+
+// Create issues a personal access token from the settings form. Scopes come
+// from the "scope" checkboxes and expires_in is a number of days, where
+// empty means the token never expires.
+func (h *TokenHandler) Create() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		currentUser := h.authSvc.GetUserFromRequest(r)
+		if currentUser == nil {
+			http.Redirect(w, r, "/login", http.StatusSeeOther)
+			return
+		}
+		_ = r.ParseForm()
+		values := map[string]string{"name": r.FormValue("name"), "expires_in": r.FormValue("expires_in")}
+
+		var ttl time.Duration
+		if v := strings.TrimSpace(r.FormValue("expires_in")); v != "" {
+			days, err := strconv.Atoi(v)
+			if err != nil || days <= 0 {
+				h.flash.redirectWithError(w, r, tokensPage, errInvalidRequest, values)
+				return
+			}
+			ttl = time.Duration(days) * 24 * time.Hour
+		}
+
+		plaintext, _, err := h.tokenSvc.Create(r.Context(), currentUser.ID.String(), r.FormValue("name"), r.Form["scope"], ttl)
+		if err != nil {
+			h.flash.redirectWithError(w, r, tokensPage, err, values)
+			return
+		}
+
+		http.SetCookie(w, &http.Cookie{
+			Name:     newTokenCookie,
+			Value:    plaintext,
+			Path:     tokensPage,
+			MaxAge:   300,
+			HttpOnly: true,
+			Secure:   r.TLS != nil,
+			SameSite: http.SameSiteStrictMode,
+		})
+		http.Redirect(w, r, tokensPage, http.StatusSeeOther)
+	}
+}
+
+// Revoke revokes the token in the path if it belongs to the signed-in user.
+func (h *TokenHandler) Revoke() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		currentUser := h.authSvc.GetUserFromRequest(r)
+		if currentUser == nil {
+			http.Redirect(w, r, "/login", http.StatusSeeOther)
+			return
+		}
+
+		if err := h.tokenSvc.Revoke(r.Context(), currentUser.ID.String(), r.PathValue("id")); err != nil {
+			h.flash.redirectWithError(w, r, tokensPage, err, nil)
+			return
+		}
+		http.Redirect(w, r, tokensPage+"?revoked=1", http.StatusSeeOther)
+	}
+}
+
+// RevealNewToken moves a token created by Create from its cookie into the
+// request context for the tokens page loader, and expires the cookie so the
+// token is shown exactly once.
+func RevealNewToken(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodGet && r.URL.Path == tokensPage {
+			if cookie, err := r.Cookie(newTokenCookie); err == nil && cookie.Value != "" {
+				http.SetCookie(w, &http.Cookie{
+					Name:   newTokenCookie,
+					Value:  "",
+					Path:   tokensPage,
+					MaxAge: -1,
+				})
+				r = r.WithContext(context.WithValue(r.Context(), newTokenKey{}, cookie.Value))
+			}
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// NewToken returns the token revealed by RevealNewToken, or "".
+func NewToken(ctx context.Context) string {
+	token, _ := ctx.Value(newTokenKey{}).(string)
+	return token
+}
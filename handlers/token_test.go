@@ -0,0 +1,165 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"myapp/i18n"
+	"myapp/metrics"
+	"myapp/model"
+	"myapp/services"
+	"myapp/testutil"
+)
+
+func newTestTokenHandler(t *testing.T) (*TokenHandler, *services.TokenService, *services.AuthService) {
+	t.Helper()
+	if err := i18n.Load(); err != nil {
+		t.Fatalf("failed to load translations: %v", err)
+	}
+	db := testutil.NewTestDB(t, &model.User{}, &model.HandleHistory{}, &model.PersonalAccessToken{})
+	users := model.NewUserRepository(db)
+	tokenSvc := services.NewTokenService(model.NewTokenRepository(db), users)
+	authSvc := services.NewAuthService(users, "test-secret", nil, services.EmailPolicy{}, tokenSvc, nil)
+	return NewTokenHandler(tokenSvc, authSvc, testFlasher), tokenSvc, authSvc
+}
+
+func postAuthedForm(handler http.HandlerFunc, target, session string, values url.Values) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodPost, target, strings.NewReader(values.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.AddCookie(&http.Cookie{Name: "session", Value: session})
+	w := httptest.NewRecorder()
+	handler(w, req)
+	return w
+}
+
+func TestHandlerCreateToken(t *testing.T) {
+	ctx := context.Background()
+	h, tokenSvc, authSvc := newTestTokenHandler(t)
+	session, _ := authSvc.Signup(ctx, "user@example.com", "password123", "testuser")
+
+	t.Run("redirect to login if not authenticated", func(t *testing.T) {
+		w := postForm(h.Create(), "/api/tokens", url.Values{"name": {"ci"}, "scope": {"profile:read"}})
+		if loc := w.Header().Get("Location"); loc != "/login" {
+			t.Errorf("expected redirect to /login, got %q", loc)
+		}
+	})
+
+	t.Run("invalid scope", func(t *testing.T) {
+		w := postAuthedForm(h.Create(), "/api/tokens", session, url.Values{"name": {"ci"}, "scope": {"admin"}})
+		expectFlash(t, w, tokensPage)
+	})
+
+	t.Run("success hands the token to the next page view", func(t *testing.T) {
+		w := postAuthedForm(h.Create(), "/api/tokens", session, url.Values{
+			"name": {"ci"}, "scope": {"profile:read", "profile:write"}, "expires_in": {"30"},
+		})
+		if loc := w.Header().Get("Location"); loc != "/settings/tokens" {
+			t.Fatalf("expected redirect to /settings/tokens, got %q", loc)
+		}
+		var cookie *http.Cookie
+		for _, c := range w.Result().Cookies() {
+			if c.Name == newTokenCookie {
+				cookie = c
+			}
+		}
+		if cookie == nil || !strings.HasPrefix(cookie.Value, services.TokenPrefix) || !cookie.HttpOnly {
+			t.Fatalf("expected HttpOnly new token cookie, got %v", cookie)
+		}
+
+		user := authSvc.GetUserFromRequest(sessionRequest(session))
+		tokens, _ := tokenSvc.List(ctx, user.ID.String())
+		if len(tokens) != 1 || tokens[0].Scopes != "profile:read profile:write" || tokens[0].ExpiresAt == nil {
+			t.Errorf("unexpected stored tokens %+v", tokens)
+		}
+	})
+}
+
+func TestHandlerRevokeToken(t *testing.T) {
+	ctx := context.Background()
+	h, tokenSvc, authSvc := newTestTokenHandler(t)
+	owner, _ := authSvc.Signup(ctx, "owner@example.com", "password123", "owner")
+	other, _ := authSvc.Signup(ctx, "other@example.com", "password123", "other")
+	user := authSvc.GetUserFromRequest(sessionRequest(owner))
+	plaintext, token, _ := tokenSvc.Create(ctx, user.ID.String(), "ci", []string{services.ScopeProfileRead}, 0)
+
+	revoke := func(session string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/api/tokens/"+token.ID.String()+"/revoke", nil)
+		req.SetPathValue("id", token.ID.String())
+		req.AddCookie(&http.Cookie{Name: "session", Value: session})
+		w := httptest.NewRecorder()
+		h.Revoke()(w, req)
+		return w
+	}
+
+	t.Run("other users cannot revoke", func(t *testing.T) {
+		w := revoke(other)
+		expectFlash(t, w, tokensPage)
+	})
+
+	t.Run("owner revokes", func(t *testing.T) {
+		w := revoke(owner)
+		if loc := w.Header().Get("Location"); loc != "/settings/tokens?revoked=1" {
+			t.Fatalf("expected revoked redirect, got %q", loc)
+		}
+		if _, _, err := tokenSvc.Authenticate(ctx, plaintext); err == nil {
+			t.Error("expected revoked token to stop authenticating")
+		}
+	})
+}
+
+func TestRevealNewToken(t *testing.T) {
+	var seen string
+	handler := RevealNewToken(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		seen = NewToken(r.Context())
+	}))
+
+	req := httptest.NewRequest(http.MethodGet, "/settings/tokens", nil)
+	req.AddCookie(&http.Cookie{Name: newTokenCookie, Value: "myapp_pat_secret"})
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, req)
+
+	if seen != "myapp_pat_secret" {
+		t.Errorf("expected token in context, got %q", seen)
+	}
+	cookies := w.Result().Cookies()
+	if len(cookies) != 1 || cookies[0].Name != newTokenCookie || cookies[0].MaxAge >= 0 {
+		t.Errorf("expected new token cookie to be cleared, got %v", cookies)
+	}
+
+	t.Run("route label survives the reveal", func(t *testing.T) {
+		m := metrics.New()
+		mux := NewRouteMux()
+		mux.HandleFunc("GET /settings/tokens", func(w http.ResponseWriter, r *http.Request) {})
+		h := RequestMetrics(m)(RevealNewToken(mux))
+
+		req := httptest.NewRequest(http.MethodGet, "/settings/tokens", nil)
+		req.AddCookie(&http.Cookie{Name: newTokenCookie, Value: "myapp_pat_secret"})
+		h.ServeHTTP(httptest.NewRecorder(), req)
+
+		w := httptest.NewRecorder()
+		m.Handler().ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+		if want := `route="GET /settings/tokens"`; !strings.Contains(w.Body.String(), want) {
+			t.Errorf("metrics output missing %q", want)
+		}
+	})
+
+	t.Run("other paths untouched", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.AddCookie(&http.Cookie{Name: newTokenCookie, Value: "myapp_pat_secret"})
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, req)
+		if seen != "" || len(w.Result().Cookies()) != 0 {
+			t.Errorf("expected no reveal outside the tokens page, got %q", seen)
+		}
+	})
+}
+
+func sessionRequest(session string) *http.Request {
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.AddCookie(&http.Cookie{Name: "session", Value: session})
+	return req
+}
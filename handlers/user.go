@@ -1,26 +1,61 @@
 package handlers
 
 import (
+	"context"
 	"errors"
-	"log"
+	"fmt"
+	"io"
 	"net/http"
-	"net/url"
 	"strings"
 
-	"myapp/i18n"
 	"myapp/model"
 	"myapp/services"
 	"myapp/storage"
+	"myapp/util"
 )
 
 type UserHandler struct {
 	userSvc *services.UserService
 	authSvc *services.AuthService
 	store   storage.Storage
+	flash   *Flasher
 }
 
-func NewUserHandler(userSvc *services.UserService, authSvc *services.AuthService, store storage.Storage) *UserHandler {
-	return &UserHandler{userSvc: userSvc, authSvc: authSvc, store: store}
+func NewUserHandler(userSvc *services.UserService, authSvc *services.AuthService, store storage.Storage, flash *Flasher) *UserHandler {
+	return &UserHandler{userSvc: userSvc, authSvc: authSvc, store: store, flash: flash}
+}
+
+// LookupProfile loads the user named by the {handle} path value of r, a
+// request for a path that starts with prefix followed by the handle. If the
+// handle is one its owner has since changed, or is not in canonical lower
+// case, the error is a permanent *Redirect to the same path under the
+// current handle, so old links follow the rename.
+func LookupProfile(r *http.Request, userSvc *services.UserService, prefix string) (*model.User, error) {
+	handle := r.PathValue("handle")
+	moved := func(current string) error {
+		target := prefix + current + strings.TrimPrefix(r.URL.Path, prefix+handle)
+		if r.URL.RawQuery != "" {
+			target += "?" + r.URL.RawQuery
+		}
+		return &Redirect{URL: target, Status: http.StatusMovedPermanently}
+	}
+
+	canonical := model.NormalizeHandle(handle)
+	profile, err := userSvc.GetByHandle(r.Context(), canonical)
+	if err == nil && canonical != handle {
+		return nil, moved(profile.Name)
+	}
+	if !errors.Is(err, model.ErrNotFound) {
+		return profile, err
+	}
+	current, pastErr := userSvc.GetByPastHandle(r.Context(), canonical)
+	if pastErr != nil {
+		if errors.Is(pastErr, model.ErrNotFound) {
+			return nil, err
+		}
+		return nil, pastErr
+	}
+	return nil, moved(current.Name)
 }
 
 func (h *UserHandler) ServeProfile(page http.Handler) http.HandlerFunc {
@@ -52,7 +87,6 @@ func (h *UserHandler) ServeEditProfile(page http.Handler) http.HandlerFunc {
 
 func (h *UserHandler) UpdateProfile() http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		locale := i18n.DetectLocale(r)
 		currentUser := h.authSvc.GetUserFromRequest(r)
 		if currentUser == nil {
 			http.Redirect(w, r, "/login", http.StatusSeeOther)
@@ -64,52 +98,95 @@ func (h *UserHandler) UpdateProfile() http.HandlerFunc {
 
 		oldHandle := currentUser.Name
 		input := services.UpdateProfileInput{
-			Handle:      strings.TrimSpace(r.FormValue("handle")),
+			Handle:      model.NormalizeHandle(r.FormValue("handle")),
 			DisplayName: strings.TrimSpace(r.FormValue("display_name")),
 			Bio:         strings.TrimSpace(r.FormValue("bio")),
+			Pronouns:    strings.TrimSpace(r.FormValue("pronouns")),
+			Location:    strings.TrimSpace(r.FormValue("location")),
 			Country:     strings.TrimSpace(r.FormValue("country")),
-			SocialLinks: model.SocialLinks{
-				Instagram: strings.TrimSpace(r.FormValue("instagram")),
-				Facebook:  strings.TrimSpace(r.FormValue("facebook")),
-				Linkedin:  strings.TrimSpace(r.FormValue("linkedin")),
-				X:         strings.TrimSpace(r.FormValue("x")),
-			},
+			Links:       formLinks(r),
 		}
 
-		log.Print("Uploading avatar picture")
 		if file, header, err := r.FormFile("avatar"); err == nil {
-			log.Print("Upload started")
 			defer file.Close()
 			contentType := header.Header.Get("Content-Type")
-			if ext := imageExt(contentType); ext != "" {
-				key := "avatars/" + currentUser.ID.String() + ext
-
-				avatarURL, err := h.store.Upload(r.Context(), key, file, header.Size, contentType)
-
+			if imageExt(contentType) != "" {
+				avatarURL, err := uploadAvatar(r.Context(), h.store, currentUser.ID.String(), file, header.Size, contentType)
 				if err != nil {
-					log.Printf("Error while uploading %v", err)
+					h.flash.redirectWithError(w, r, "/user/"+oldHandle+"/edit", err, profileFormValues(input))
 					return
 				}
-
-				log.Print("Upload finished")
 				input.AvatarURL = avatarURL
-
 			}
 		}
 
 		if err := h.userSvc.UpdateProfile(r.Context(), currentUser.ID.String(), input); err != nil {
-			errKey := "error.somethingWrong"
-			if errors.Is(err, services.ErrHandleTaken) {
-				errKey = "error.handleTaken"
-			} else if errors.Is(err, services.ErrHandleInvalid) {
-				errKey = "error.handleInvalid"
-			}
-			http.Redirect(w, r, "/user/"+oldHandle+"/edit?error="+url.QueryEscape(i18n.T(locale, errKey)), http.StatusSeeOther)
+			h.flash.redirectWithError(w, r, "/user/"+oldHandle+"/edit", err, profileFormValues(input))
 			return
 		}
 
-		http.Redirect(w, r, "/user/"+input.Handle, http.StatusSeeOther)
+		http.Redirect(w, r, "/user/"+input.Handle+"/edit?success=1", http.StatusSeeOther)
+	}
+}
+
+// formLinks reads the edit form's link rows, which repeat the link_type,
+// link_url and link_label fields in order. Rows left blank are skipped.
+func formLinks(r *http.Request) []model.Link {
+	types, urls, labels := r.Form["link_type"], r.Form["link_url"], r.Form["link_label"]
+	field := func(values []string, i int) string {
+		if i < len(values) {
+			return strings.TrimSpace(values[i])
+		}
+		return ""
+	}
+
+	var links []model.Link
+	for i := range max(len(types), len(urls), len(labels)) {
+		link := model.Link{Type: field(types, i), URL: field(urls, i), Label: field(labels, i)}
+		if link.URL == "" && link.Label == "" {
+			continue
+		}
+		links = append(links, link)
+	}
+	return links
+}
+
+// profileFormValues keys input by the field names UpdateProfile reports
+// errors under, so the edit page can match values to their errors.
+func profileFormValues(input services.UpdateProfileInput) map[string]string {
+	values := map[string]string{
+		"handle":       input.Handle,
+		"display_name": input.DisplayName,
+		"bio":          input.Bio,
+		"pronouns":     input.Pronouns,
+		"location":     input.Location,
+		"country":      input.Country,
+	}
+	for i, link := range input.Links {
+		prefix := fmt.Sprintf("links.%d.", i)
+		values[prefix+"type"] = link.Type
+		values[prefix+"url"] = link.URL
+		values[prefix+"label"] = link.Label
+	}
+	return values
+}
+
+// uploadAvatar stores an avatar for userID under a key derived from the image
+// type and returns its public URL.
+func uploadAvatar(ctx context.Context, store storage.Storage, userID string, body io.Reader, size int64, contentType string) (string, error) {
+	ext := imageExt(contentType)
+	if ext == "" {
+		return "", errUnsupportedImage
+	}
+	key := "avatars/" + userID + ext
+
+	avatarURL, err := store.Upload(ctx, key, body, size, contentType)
+	if err != nil {
+		util.Logger(ctx).Error("avatar upload failed", "key", key, "error", err)
+		return "", err
 	}
+	util.Logger(ctx).Info("avatar uploaded", "key", key, "bytes", size)
+	return avatarURL, nil
 }
 
 func imageExt(contentType string) string {
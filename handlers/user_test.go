@@ -2,6 +2,7 @@ package handlers
 
 import (
 	"context"
+	"errors"
 	"net/http"
 	"net/http/httptest"
 	"net/url"
@@ -16,10 +17,10 @@ import (
 
 func newTestUserHandler(t *testing.T) (*UserHandler, *services.AuthService) {
 	t.Helper()
-	repo := model.NewUserRepository(testutil.NewTestDB(t, &model.User{}))
-	authSvc := services.NewAuthService(repo)
-	userSvc := services.NewUserService(repo)
-	return NewUserHandler(userSvc, authSvc, storage.Noop()), authSvc
+	repo := model.NewUserRepository(testutil.NewTestDB(t, &model.User{}, &model.HandleHistory{}))
+	authSvc := services.NewAuthService(repo, "test-secret", nil, services.EmailPolicy{}, nil, nil)
+	userSvc := services.NewUserService(repo, nil)
+	return NewUserHandler(userSvc, authSvc, storage.Noop(), testFlasher), authSvc
 }
 
 func mockPage() http.Handler {
@@ -54,6 +55,57 @@ func TestHandlerProfile(t *testing.T) {
 	})
 }
 
+func TestLookupProfile(t *testing.T) {
+	ctx := context.Background()
+	h, authSvc := newTestUserHandler(t)
+	_, _ = authSvc.Signup(ctx, "test@example.com", "password123", "oldname")
+	user, _ := h.userSvc.GetByHandle(ctx, "oldname")
+	if err := h.userSvc.UpdateProfile(ctx, user.ID.String(), services.UpdateProfileInput{Handle: "newname"}); err != nil {
+		t.Fatalf("UpdateProfile failed: %v", err)
+	}
+
+	lookup := func(target, handle string) (*model.User, error) {
+		req := httptest.NewRequest(http.MethodGet, target, nil)
+		req.SetPathValue("handle", handle)
+		return LookupProfile(req, h.userSvc, "/user/")
+	}
+
+	t.Run("current handle", func(t *testing.T) {
+		if got, err := lookup("/user/newname", "newname"); err != nil || got.ID != user.ID {
+			t.Errorf("expected the user, got %v, %v", got, err)
+		}
+	})
+
+	t.Run("old handle redirects permanently, keeping the rest of the URL", func(t *testing.T) {
+		_, err := lookup("/user/oldname/followers?page=2", "oldname")
+		var moved *Redirect
+		if !errors.As(err, &moved) || moved.RedirectStatusCode() != http.StatusMovedPermanently {
+			t.Fatalf("expected a 301 redirect, got %v", err)
+		}
+		if moved.URL != "/user/newname/followers?page=2" {
+			t.Errorf("expected /user/newname/followers?page=2, got %s", moved.URL)
+		}
+	})
+
+	t.Run("mixed case redirects to the canonical handle", func(t *testing.T) {
+		_, err := lookup("/user/NewName/following", "NewName")
+		var moved *Redirect
+		if !errors.As(err, &moved) || moved.URL != "/user/newname/following" {
+			t.Errorf("expected a redirect to /user/newname/following, got %v", err)
+		}
+		_, err = lookup("/user/OldName", "OldName")
+		if !errors.As(err, &moved) || moved.URL != "/user/newname" {
+			t.Errorf("expected a redirect to /user/newname, got %v", err)
+		}
+	})
+
+	t.Run("unknown handle", func(t *testing.T) {
+		if _, err := lookup("/user/nobody", "nobody"); !errors.Is(err, model.ErrNotFound) {
+			t.Errorf("expected ErrNotFound, got %v", err)
+		}
+	})
+}
+
 func TestHandlerEditProfile(t *testing.T) {
 	ctx := context.Background()
 
@@ -128,12 +180,7 @@ func TestHandlerUpdateProfile(t *testing.T) {
 		w := httptest.NewRecorder()
 		h.UpdateProfile()(w, req)
 
-		if w.Code != http.StatusSeeOther {
-			t.Errorf("expected %d, got %d", http.StatusSeeOther, w.Code)
-		}
-		if loc := w.Header().Get("Location"); !strings.HasPrefix(loc, "/user/testuser/edit?error=") {
-			t.Errorf("expected /user/testuser/edit?error=..., got %s", loc)
-		}
+		expectFlash(t, w, "/user/testuser/edit")
 	})
 
 	t.Run("handle taken redirects with error", func(t *testing.T) {
@@ -147,11 +194,33 @@ func TestHandlerUpdateProfile(t *testing.T) {
 		w := httptest.NewRecorder()
 		h.UpdateProfile()(w, req)
 
-		if w.Code != http.StatusSeeOther {
-			t.Errorf("expected %d, got %d", http.StatusSeeOther, w.Code)
+		expectFlash(t, w, "/user/user2hnd/edit")
+	})
+
+	t.Run("invalid fields flash the submission back to the edit page", func(t *testing.T) {
+		h, authSvc := newTestUserHandler(t)
+		token, _ := authSvc.Signup(ctx, "user@example.com", "password123", "testuser")
+
+		values := url.Values{
+			"handle":       {"testuser"},
+			"display_name": {"Still Here"},
+			"country":      {"ZZ"},
+			"link_type":    {"x", "website"},
+			"link_url":     {"https://example.com/me", ""},
+			"link_label":   {"", ""},
+		}
+		req := httptest.NewRequest(http.MethodPost, "/api/user/update", strings.NewReader(values.Encode()))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		req.AddCookie(&http.Cookie{Name: "session", Value: token})
+		w := httptest.NewRecorder()
+		h.UpdateProfile()(w, req)
+
+		flash := expectFlash(t, w, "/user/testuser/edit")
+		if flash.Values["display_name"] != "Still Here" || flash.Values["links.0.url"] != "https://example.com/me" {
+			t.Errorf("expected submitted values, got %v", flash.Values)
 		}
-		if loc := w.Header().Get("Location"); !strings.HasPrefix(loc, "/user/user2hnd/edit?error=") {
-			t.Errorf("expected /user/user2hnd/edit?error=..., got %s", loc)
+		if flash.Fields["country"] == "" || flash.Fields["links.0.url"] == "" || len(flash.Fields) != 2 {
+			t.Errorf("expected country and x errors, got %v", flash.Fields)
 		}
 	})
 
@@ -30,6 +30,16 @@ func Load() error {
 	return nil
 }
 
+// Loaded reports whether Load has populated every supported locale.
+func Loaded() bool {
+	for _, locale := range supportedLocales {
+		if len(translations[locale]) == 0 {
+			return false
+		}
+	}
+	return true
+}
+
 func DetectLocale(r *http.Request) string {
 	if cookie, err := r.Cookie("lang"); err == nil {
 		for _, l := range supportedLocales {
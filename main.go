@@ -1,16 +1,29 @@
 package main
 
 import (
+	"context"
 	"embed"
-	"log"
+	"fmt"
+	"log/slog"
+	"maps"
 	"net/http"
+	"os"
+	"os/signal"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
 
 	"myapp/config"
 	"myapp/handlers"
 	"myapp/i18n"
+	"myapp/markdown"
+	"myapp/metrics"
 	"myapp/model"
+	"myapp/ratelimit"
 	"myapp/services"
 	"myapp/storage"
+	"myapp/tracing"
 	"myapp/util"
 
 	"github.com/3-lines-studio/bifrost"
@@ -19,47 +32,129 @@ import (
 //go:embed all:.bifrost
 var bifrostFS embed.FS
 
+// Rate limit policies for the form endpoints. Limit requests may burst, and
+// the allowance refills evenly over Period.
+var (
+	signupPerIP          = ratelimit.Policy{Name: "signup-ip", Limit: 5, Period: time.Hour}
+	loginPerIP           = ratelimit.Policy{Name: "login-ip", Limit: 20, Period: 10 * time.Minute}
+	loginPerEmail        = ratelimit.Policy{Name: "login-email", Limit: 5, Period: 15 * time.Minute}
+	profileUpdatePerUser = ratelimit.Policy{Name: "profile-update-user", Limit: 30, Period: 10 * time.Minute}
+	followPerUser        = ratelimit.Policy{Name: "follow-user", Limit: 60, Period: 10 * time.Minute}
+	reportPerUser        = ratelimit.Policy{Name: "report-user", Limit: 10, Period: time.Hour}
+)
+
 func main() {
-	database := util.Db
+	if err := run(); err != nil {
+		slog.Error("server stopped", "error", err)
+		os.Exit(1)
+	}
+}
+
+func run() error {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
 
-	sqlDB, err := database.DB()
+	cfg, err := config.LoadFromEnvironment()
 	if err != nil {
-		log.Fatalf("Failed to initialize database: %v", err)
+		return fmt.Errorf("failed to load configuration: %w", err)
+	}
+	logger := util.NewLogger(os.Stdout, cfg.LogLevel)
+	slog.SetDefault(logger)
+	logger.Info("effective configuration", "config", cfg.Redacted())
+
+	database, err := util.NewDatabase(ctx, cfg.DB)
+	if err != nil {
+		return fmt.Errorf("failed to initialize database: %w", err)
+	}
+	defer func() {
+		if err := util.CloseDatabase(database); err != nil {
+			logger.Error("failed to close database", "error", err)
+			return
+		}
+		logger.Info("database closed")
+	}()
+
+	tp, shutdownTracing, err := tracing.Setup(ctx, cfg.Tracing)
+	if err != nil {
+		return fmt.Errorf("failed to initialize tracing: %w", err)
+	}
+	defer func() {
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		if err := shutdownTracing(shutdownCtx); err != nil {
+			logger.Error("failed to flush traces", "error", err)
+		}
+	}()
+	if err := database.Use(tracing.GormPlugin(tp)); err != nil {
+		return fmt.Errorf("failed to register tracing plugin: %w", err)
+	}
+
+	appMetrics := metrics.New()
+	if err := database.Use(metrics.GormPlugin(appMetrics)); err != nil {
+		return fmt.Errorf("failed to register metrics plugin: %w", err)
 	}
-	defer sqlDB.Close()
 
 	if err := i18n.Load(); err != nil {
-		log.Fatalf("Failed to load translations: %v", err)
+		return fmt.Errorf("failed to load translations: %w", err)
 	}
 
 	var store storage.Storage
-	if config.Env.STORAGE_TYPE == "s3" {
+	if cfg.StorageType == "s3" {
 		s, err := storage.NewS3Storage(
-			config.Env.S3_ENDPOINT,
-			config.Env.S3_REGION,
-			config.Env.S3_BUCKET,
-			config.Env.S3_KEY_ID,
-			config.Env.S3_APPLICATION_KEY,
-			config.Env.S3_BASE_URL,
+			cfg.S3.Endpoint,
+			cfg.S3.Region,
+			cfg.S3.Bucket,
+			cfg.S3.KeyID,
+			cfg.S3.ApplicationKey,
+			cfg.S3.BaseURL,
 		)
 		if err != nil {
-			log.Fatalf("Failed to create S3 storage: %v", err)
+			return fmt.Errorf("failed to create S3 storage: %w", err)
 		}
-		store = s
-		log.Print("Using S3 as storage")
+		store = storage.WithTracing(storage.WithMetrics(s, "s3", appMetrics), "s3", tp)
+		logger.Info("using S3 storage", "bucket", cfg.S3.Bucket)
 	} else {
-		s, err := storage.NewLocalStorage("./uploads", config.Env.APP_URL+"/uploads")
+		s, err := storage.NewLocalStorage("./uploads", cfg.AppURL+"/uploads")
 		if err != nil {
-			log.Fatalf("Failed to create local storage: %v", err)
+			return fmt.Errorf("failed to create local storage: %w", err)
 		}
-		store = s
+		store = storage.WithTracing(storage.WithMetrics(s, "local", appMetrics), "local", tp)
 	}
 
 	userRepo := model.NewUserRepository(database)
-	authService := services.NewAuthService(userRepo)
-	userService := services.NewUserService(userRepo)
-	authHandler := handlers.NewAuthHandler(authService)
-	userHandler := handlers.NewUserHandler(userService, authService, store)
+	tokenService := services.NewTokenService(model.NewTokenRepository(database), userRepo)
+	handlePolicy := services.NewHandlePolicy(cfg.ReservedHandles...)
+	emailPolicy := services.EmailPolicy{StripPlusTags: cfg.StripEmailPlusTags}
+	authService := services.NewAuthService(userRepo, cfg.JWTSecret, handlePolicy, emailPolicy, tokenService, appMetrics)
+	userService := services.NewUserService(userRepo, handlePolicy)
+	followService := services.NewFollowService(model.NewFollowRepository(database), model.NewBlockRepository(database), userRepo)
+	privacyService := services.NewPrivacyService(userRepo, followService)
+	moderationService := services.NewModerationService(model.NewReportRepository(database), userRepo, emailPolicy, cfg.AdminEmails...)
+
+	// Re-normalize stored emails in Go; the SQL backfill only folded ASCII.
+	updated, conflicts, err := authService.NormalizeStoredEmails(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to normalize stored emails: %w", err)
+	}
+	if updated > 0 || conflicts > 0 {
+		logger.Info("normalized stored emails", "updated", updated, "conflicts", conflicts)
+	}
+
+	flasher := handlers.NewFlasher(cfg.JWTSecret)
+	authHandler := handlers.NewAuthHandler(authService, flasher)
+	userHandler := handlers.NewUserHandler(userService, authService, store, flasher)
+	healthHandler := handlers.NewHealthHandler(database, store)
+	apiHandler := handlers.NewAPIHandler(authService, userService, privacyService, store)
+	tokenHandler := handlers.NewTokenHandler(tokenService, authService, flasher)
+	followHandler := handlers.NewFollowHandler(followService, authService, flasher)
+	privacyHandler := handlers.NewPrivacyHandler(privacyService, authService, flasher)
+	moderationHandler := handlers.NewModerationHandler(moderationService, authService, flasher)
+
+	// loader traces a page loader and lets its errors answer with their own
+	// status and a localized message.
+	loader := func(path string, load tracing.PropsLoader) bifrost.PageOption {
+		return bifrost.WithLoader(tracing.Loader(tp, path, handlers.PageLoader(load)))
+	}
 
 	userProps := func(req *http.Request) map[string]any {
 		if u := authService.GetUserFromRequest(req); u != nil {
@@ -68,26 +163,86 @@ func main() {
 		return nil
 	}
 
-	profileProps := func(p *model.User) map[string]any {
+	// followListLoader loads one page of a profile's followers or following,
+	// chosen by list. The page comes from the "page" query parameter. Like
+	// the profile, the lists are hidden from users the owner blocked.
+	followListLoader := func(path string, list func(context.Context, *model.User, int) (services.FollowPage, error)) bifrost.PageOption {
+		return loader(path, func(req *http.Request) (map[string]any, error) {
+			profile, err := handlers.LookupProfile(req, userService, "/user/")
+			if err != nil {
+				return nil, err
+			}
+			currentUser := authService.GetUserFromRequest(req)
+			blocked, err := followService.HasBlocked(req.Context(), profile, currentUser)
+			if err != nil {
+				return nil, err
+			}
+			if blocked {
+				return nil, model.ErrNotFound
+			}
+			page, _ := strconv.Atoi(req.URL.Query().Get("page"))
+			result, err := list(req.Context(), profile, page)
+			if err != nil {
+				return nil, err
+			}
+			locale := i18n.DetectLocale(req)
+			props := map[string]any{
+				"locale":  locale,
+				"t":       i18n.Translations(locale),
+				"profile": map[string]any{"handle": profile.Name, "displayName": profile.DisplayName},
+				"users":   listedUsersProps(result.Users),
+				"page":    result.Page,
+				"hasNext": result.HasNext,
+			}
+			if currentUser != nil {
+				props["user"] = map[string]any{"email": currentUser.Email, "handle": currentUser.Name}
+			}
+			return props, nil
+		})
+	}
+
+	tokenProps := func(tok model.PersonalAccessToken) map[string]any {
+		date := func(t *time.Time) string {
+			if t == nil {
+				return ""
+			}
+			return t.Format(time.DateOnly)
+		}
 		return map[string]any{
-			"handle":      p.Name,
-			"displayName": p.DisplayName,
-			"bio":         p.Bio,
-			"country":     p.Country,
-			"email":       p.Email,
-			"avatarURL":   p.AvatarURL,
-			"socialLinks": map[string]any{
-				"instagram": p.SocialLinks.Instagram,
-				"facebook":  p.SocialLinks.Facebook,
-				"linkedin":  p.SocialLinks.Linkedin,
-				"x":         p.SocialLinks.X,
+			"id":         tok.ID.String(),
+			"name":       tok.Name,
+			"prefix":     tok.Prefix,
+			"scopes":     strings.Fields(tok.Scopes),
+			"createdAt":  tok.CreatedAt.Format(time.DateOnly),
+			"expiresAt":  date(tok.ExpiresAt),
+			"lastUsedAt": date(tok.LastUsedAt),
+			"revoked":    tok.RevokedAt != nil,
+			"active":     tok.Active(time.Now()),
+		}
+	}
+
+	// reportProps describes an open report for the moderation queue. Admins
+	// see the reported user's whole profile and warning count, whatever their
+	// privacy settings.
+	reportProps := func(rep model.Report) map[string]any {
+		return map[string]any{
+			"id":        rep.ID.String(),
+			"reason":    rep.Reason,
+			"evidence":  rep.Evidence,
+			"createdAt": rep.CreatedAt.Format(time.DateOnly),
+			"reporter":  map[string]any{"handle": rep.Reporter.Name, "displayName": rep.Reporter.DisplayName},
+			"reported": map[string]any{
+				"handle":      rep.Reported.Name,
+				"displayName": rep.Reported.DisplayName,
+				"warnings":    rep.Reported.Warnings,
+				"suspended":   rep.Reported.Suspended(),
 			},
 		}
 	}
 
 	app := bifrost.New(
 		bifrostFS,
-		bifrost.Page("/", "./pages/home.tsx", bifrost.WithLoader(
+		bifrost.Page("/", "./pages/home.tsx", loader("/",
 			func(req *http.Request) (map[string]any, error) {
 				locale := i18n.DetectLocale(req)
 				props := map[string]any{
@@ -100,90 +255,453 @@ func main() {
 				return props, nil
 			},
 		)),
-		bifrost.Page("/login", "./pages/login.tsx", bifrost.WithLoader(func(req *http.Request) (map[string]any, error) {
+		bifrost.Page("/login", "./pages/login.tsx", loader("/login", func(req *http.Request) (map[string]any, error) {
 			locale := i18n.DetectLocale(req)
 			props := map[string]any{
 				"locale": locale,
 				"t":      i18n.Translations(locale),
 			}
-			if e := req.URL.Query().Get("error"); e != "" {
-				props["error"] = e
-			}
+			maps.Copy(props, handlers.FlashFrom(req.Context()).Props(locale))
 			if u := userProps(req); u != nil {
 				props["user"] = u
 			}
 			return props, nil
 		})),
-		bifrost.Page("/signup", "./pages/signup.tsx", bifrost.WithLoader(func(req *http.Request) (map[string]any, error) {
+		bifrost.Page("/signup", "./pages/signup.tsx", loader("/signup", func(req *http.Request) (map[string]any, error) {
 			locale := i18n.DetectLocale(req)
 			props := map[string]any{
 				"locale": locale,
 				"t":      i18n.Translations(locale),
 			}
-			if e := req.URL.Query().Get("error"); e != "" {
-				props["error"] = e
-			}
+			maps.Copy(props, handlers.FlashFrom(req.Context()).Props(locale))
 			if u := userProps(req); u != nil {
 				props["user"] = u
 			}
 			return props, nil
 		})),
-		bifrost.Page("/user/{handle}", "./pages/profile.tsx", bifrost.WithLoader(func(req *http.Request) (map[string]any, error) {
+		bifrost.Page("/users", "./pages/users.tsx", loader("/users", usersLoader(authService, userService))),
+		bifrost.Page("/user/{handle}", "./pages/profile.tsx", loader("/user/{handle}", func(req *http.Request) (map[string]any, error) {
 			locale := i18n.DetectLocale(req)
 			handle := req.PathValue("handle")
-			profile, err := userService.GetByHandle(req.Context(), handle)
+			profile, err := handlers.LookupProfile(req, userService, "/user/")
 			if err != nil {
 				return nil, err
 			}
 			currentUser := authService.GetUserFromRequest(req)
 			isOwner := currentUser != nil && currentUser.Name == handle
+			view, err := privacyService.View(req.Context(), currentUser, profile)
+			if err != nil {
+				return nil, err
+			}
+			stats, err := followService.Stats(req.Context(), profile)
+			if err != nil {
+				return nil, err
+			}
+			isFollowing, err := followService.IsFollowing(req.Context(), currentUser, profile)
+			if err != nil {
+				return nil, err
+			}
+			isBlocking := false
+			if currentUser != nil {
+				if isBlocking, err = followService.HasBlocked(req.Context(), currentUser, profile); err != nil {
+					return nil, err
+				}
+			}
 			props := map[string]any{
-				"locale":  locale,
-				"t":       i18n.Translations(locale),
-				"profile": profileProps(profile),
-				"isOwner": isOwner,
+				"locale":        locale,
+				"t":             i18n.Translations(locale),
+				"profile":       profileProps(view),
+				"noindex":       profile.HideFromSearch,
+				"isOwner":       isOwner,
+				"followers":     stats.Followers,
+				"following":     stats.Following,
+				"isFollowing":   isFollowing,
+				"isBlocking":    isBlocking,
+				"reportReasons": services.ReportReasons,
+			}
+			maps.Copy(props, handlers.FlashFrom(req.Context()).Props(locale))
+			if req.URL.Query().Get("reported") == "1" {
+				props["reported"] = true
 			}
 			if currentUser != nil {
 				props["user"] = map[string]any{"email": currentUser.Email, "handle": currentUser.Name}
 			}
 			return props, nil
 		})),
-		bifrost.Page("/user/{handle}/edit", "./pages/profile-edit.tsx", bifrost.WithLoader(func(req *http.Request) (map[string]any, error) {
+		bifrost.Page("/user/{handle}/followers", "./pages/followers.tsx",
+			followListLoader("/user/{handle}/followers", followService.Followers)),
+		bifrost.Page("/user/{handle}/following", "./pages/following.tsx",
+			followListLoader("/user/{handle}/following", followService.Following)),
+		bifrost.Page("/user/{handle}/edit", "./pages/profile-edit.tsx", loader("/user/{handle}/edit",
+			editProfileLoader(authService, userService))),
+		bifrost.Page("/settings/privacy", "./pages/privacy.tsx", loader("/settings/privacy", func(req *http.Request) (map[string]any, error) {
+			currentUser := authService.GetUserFromRequest(req)
+			if currentUser == nil {
+				return nil, &handlers.Redirect{URL: "/login"}
+			}
+			visibility := map[string]model.Visibility{}
+			for _, field := range services.PrivateFields {
+				visibility[field] = currentUser.Visibility.Of(field)
+			}
 			locale := i18n.DetectLocale(req)
-			handle := req.PathValue("handle")
-			profile, err := userService.GetByHandle(req.Context(), handle)
+			props := map[string]any{
+				"locale":         locale,
+				"t":              i18n.Translations(locale),
+				"user":           map[string]any{"email": currentUser.Email, "handle": currentUser.Name},
+				"fields":         services.PrivateFields,
+				"visibilities":   services.Visibilities,
+				"visibility":     visibility,
+				"hideFromSearch": currentUser.HideFromSearch,
+			}
+			maps.Copy(props, handlers.FlashFrom(req.Context()).Props(locale))
+			if req.URL.Query().Get("success") == "1" {
+				props["success"] = true
+			}
+			return props, nil
+		})),
+		bifrost.Page("/admin/reports", "./pages/reports.tsx", loader("/admin/reports", func(req *http.Request) (map[string]any, error) {
+			currentUser := authService.GetUserFromRequest(req)
+			if currentUser == nil {
+				return nil, &handlers.Redirect{URL: "/login"}
+			}
+			// To everyone else the queue does not exist.
+			if !moderationService.IsAdmin(currentUser) {
+				return nil, model.ErrNotFound
+			}
+			reports, err := moderationService.Queue(req.Context(), currentUser)
 			if err != nil {
 				return nil, err
 			}
+			list := make([]map[string]any, 0, len(reports))
+			for _, rep := range reports {
+				list = append(list, reportProps(rep))
+			}
+			locale := i18n.DetectLocale(req)
 			props := map[string]any{
 				"locale":  locale,
 				"t":       i18n.Translations(locale),
-				"profile": profileProps(profile),
+				"user":    map[string]any{"email": currentUser.Email, "handle": currentUser.Name},
+				"reports": list,
+				"actions": services.ModerationActions,
 			}
-			if e := req.URL.Query().Get("error"); e != "" {
-				props["error"] = e
+			maps.Copy(props, handlers.FlashFrom(req.Context()).Props(locale))
+			if req.URL.Query().Get("resolved") == "1" {
+				props["resolved"] = true
 			}
-			if req.URL.Query().Get("success") == "1" {
-				props["success"] = true
+			return props, nil
+		})),
+		bifrost.Page("/settings/tokens", "./pages/tokens.tsx", loader("/settings/tokens", func(req *http.Request) (map[string]any, error) {
+			currentUser := authService.GetUserFromRequest(req)
+			if currentUser == nil {
+				return nil, &handlers.Redirect{URL: "/login"}
 			}
-			if u := userProps(req); u != nil {
-				props["user"] = u
+			tokens, err := tokenService.List(req.Context(), currentUser.ID.String())
+			if err != nil {
+				return nil, err
+			}
+			list := make([]map[string]any, 0, len(tokens))
+			for _, tok := range tokens {
+				list = append(list, tokenProps(tok))
+			}
+			locale := i18n.DetectLocale(req)
+			props := map[string]any{
+				"locale": locale,
+				"t":      i18n.Translations(locale),
+				"user":   map[string]any{"email": currentUser.Email, "handle": currentUser.Name},
+				"tokens": list,
+				"scopes": services.TokenScopes,
+			}
+			if token := handlers.NewToken(req.Context()); token != "" {
+				props["newToken"] = token
+			}
+			maps.Copy(props, handlers.FlashFrom(req.Context()).Props(locale))
+			if req.URL.Query().Get("revoked") == "1" {
+				props["revoked"] = true
 			}
 			return props, nil
 		})),
 	)
 
-	defer app.Stop()
+	defer func() {
+		if err := app.Stop(); err != nil {
+			logger.Error("failed to stop bifrost", "error", err)
+		}
+	}()
 
-	api := http.NewServeMux()
+	var limitStore ratelimit.Store
+	switch cfg.RateLimit.Backend {
+	case "database":
+		dbStore := ratelimit.NewDBStore(model.NewRateLimitRepository(database))
+		go pruneRateLimits(ctx, dbStore)
+		limitStore = dbStore
+	case "none":
+		limitStore = ratelimit.Unlimited()
+	default:
+		limitStore = ratelimit.NewMemoryStore()
+	}
+	limiter := handlers.NewRateLimiter(limitStore, cfg.HTTP.TrustProxyHeaders, flasher)
+
+	api := handlers.NewRouteMux()
+	registerRoutes(api, routeHandlers{
+		auth:        authHandler,
+		user:        userHandler,
+		tokens:      tokenHandler,
+		follows:     followHandler,
+		privacy:     privacyHandler,
+		moderation:  moderationHandler,
+		api:         apiHandler,
+		health:      healthHandler,
+		limiter:     limiter,
+		authService: authService,
+		emails:      emailPolicy,
+		metrics:     appMetrics,
+		openAPI:     handlers.OpenAPI(handlers.OpenAPISpec()),
+	})
 
-	api.HandleFunc("POST /api/signup", authHandler.Signup())
-	api.HandleFunc("POST /api/login", authHandler.Login())
-	api.HandleFunc("POST /api/logout", authHandler.Logout)
-	api.HandleFunc("POST /api/user/update", userHandler.UpdateProfile())
+	srv := &http.Server{
+		Addr: cfg.HTTP.Addr,
+		Handler: handlers.RequestTracing(tp)(
+			handlers.RequestLogger(logger)(handlers.RequestMetrics(appMetrics)(flasher.Reveal(handlers.RevealNewToken(handlers.PageErrors(app.Wrap(api)))))),
+		),
+		ErrorLog:          slog.NewLogLogger(logger.Handler(), slog.LevelWarn),
+		ReadTimeout:       cfg.HTTP.ReadTimeout,
+		ReadHeaderTimeout: cfg.HTTP.ReadHeaderTimeout,
+		WriteTimeout:      cfg.HTTP.WriteTimeout,
+		IdleTimeout:       cfg.HTTP.IdleTimeout,
+	}
+	return serve(ctx, srv, cfg.HTTP)
+}
+
+// routeHandlers bundles the dependencies registerRoutes wires together.
+type routeHandlers struct {
+	auth        *handlers.AuthHandler
+	user        *handlers.UserHandler
+	tokens      *handlers.TokenHandler
+	follows     *handlers.FollowHandler
+	privacy     *handlers.PrivacyHandler
+	moderation  *handlers.ModerationHandler
+	api         *handlers.APIHandler
+	health      *handlers.HealthHandler
+	limiter     *handlers.RateLimiter
+	authService *services.AuthService
+	emails      services.EmailPolicy
+	metrics     *metrics.Metrics
+	openAPI     http.Handler
+}
+
+// routeMux is the part of *http.ServeMux that registerRoutes uses, so tests
+// can record the registered patterns.
+type routeMux interface {
+	Handle(pattern string, handler http.Handler)
+	HandleFunc(pattern string, handler func(http.ResponseWriter, *http.Request))
+}
+
+// registerRoutes registers the form, JSON API and operational routes. Every
+// pattern must be documented in handlers.OpenAPISpec.
+func registerRoutes(api routeMux, h routeHandlers) {
+	api.Handle("POST /api/signup", h.limiter.Limit("/signup",
+		h.limiter.PerIP(signupPerIP),
+	)(h.auth.Signup()))
+	api.Handle("POST /api/login", h.limiter.Limit("/login",
+		h.limiter.PerIP(loginPerIP),
+		handlers.PerEmail(handlers.PerFormValue(loginPerEmail, "email"), h.emails),
+	)(h.auth.Login()))
+	api.HandleFunc("POST /api/logout", h.auth.Logout)
+	api.Handle("POST /api/user/update", h.limiter.Limit("/",
+		handlers.PerUser(profileUpdatePerUser, h.authService),
+	)(h.user.UpdateProfile()))
 	api.HandleFunc("POST /api/set-lang", handleSetLang)
+	api.HandleFunc("POST /api/user/privacy", h.privacy.Update())
+	api.HandleFunc("POST /api/tokens", h.tokens.Create())
+	api.HandleFunc("POST /api/tokens/{id}/revoke", h.tokens.Revoke())
+	api.Handle("POST /api/user/{handle}/follow", h.limiter.Limit("/",
+		handlers.PerUser(followPerUser, h.authService),
+	)(h.follows.Follow()))
+	api.Handle("POST /api/user/{handle}/unfollow", h.limiter.Limit("/",
+		handlers.PerUser(followPerUser, h.authService),
+	)(h.follows.Unfollow()))
+	api.Handle("POST /api/user/{handle}/block", h.limiter.Limit("/",
+		handlers.PerUser(followPerUser, h.authService),
+	)(h.follows.Block()))
+	api.Handle("POST /api/user/{handle}/unblock", h.limiter.Limit("/",
+		handlers.PerUser(followPerUser, h.authService),
+	)(h.follows.Unblock()))
+	api.Handle("POST /api/user/{handle}/report", h.limiter.Limit("/",
+		handlers.PerUser(reportPerUser, h.authService),
+	)(h.moderation.Report()))
+	api.HandleFunc("POST /api/admin/reports/{id}/resolve", h.moderation.Resolve())
 
-	log.Fatal(http.ListenAndServe(":8080", app.Wrap(api)))
+	api.Handle("POST /api/v1/auth/signup", h.limiter.LimitAPI(
+		h.limiter.PerIP(signupPerIP),
+	)(http.HandlerFunc(h.api.Signup)))
+	api.Handle("POST /api/v1/auth/login", h.limiter.LimitAPI(
+		h.limiter.PerIP(loginPerIP),
+		handlers.PerEmail(handlers.PerJSONField(loginPerEmail, "email"), h.emails),
+	)(http.HandlerFunc(h.api.Login)))
+	api.HandleFunc("POST /api/v1/auth/logout", h.api.Logout)
+	api.HandleFunc("GET /api/v1/me", h.api.Me)
+	api.Handle("PATCH /api/v1/me", h.limiter.LimitAPI(
+		handlers.PerUser(profileUpdatePerUser, h.authService),
+	)(http.HandlerFunc(h.api.UpdateMe)))
+	api.Handle("PUT /api/v1/me/avatar", h.limiter.LimitAPI(
+		handlers.PerUser(profileUpdatePerUser, h.authService),
+	)(http.HandlerFunc(h.api.UploadAvatar)))
+	api.HandleFunc("GET /api/v1/users/{handle}", h.api.Profile)
+	api.Handle("GET /api/openapi.json", h.openAPI)
+
+	api.HandleFunc("GET /healthz", h.health.Healthz)
+	api.HandleFunc("GET /readyz", h.health.Readyz)
+	api.HandleFunc("GET /version", h.health.Version)
+	api.Handle("GET /metrics", h.metrics.Handler())
+}
+
+// serve runs srv until ctx is cancelled, then stops accepting connections
+// and waits up to ShutdownTimeout for in-flight requests to finish.
+func serve(ctx context.Context, srv *http.Server, cfg config.HTTPConfig) error {
+	errCh := make(chan error, 1)
+	go func() {
+		slog.Info("listening", "addr", srv.Addr, "tls", cfg.TLSEnabled())
+		if cfg.TLSEnabled() {
+			errCh <- srv.ListenAndServeTLS(cfg.TLSCertFile, cfg.TLSKeyFile)
+		} else {
+			errCh <- srv.ListenAndServe()
+		}
+	}()
+
+	select {
+	case err := <-errCh:
+		return fmt.Errorf("http server: %w", err)
+	case <-ctx.Done():
+	}
+
+	slog.Info("shutting down, draining in-flight requests")
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), cfg.ShutdownTimeout)
+	defer cancel()
+	if err := srv.Shutdown(shutdownCtx); err != nil {
+		return fmt.Errorf("http shutdown: %w", err)
+	}
+	return nil
+}
+
+// profileProps describes p for the profile pages. Pass it the view
+// PrivacyService.View returns unless the viewer is the owner; the email
+// is left out when it is hidden.
+func profileProps(p *model.User) map[string]any {
+	links := make([]map[string]any, 0, len(p.Links))
+	for _, l := range p.Links {
+		links = append(links, map[string]any{"type": l.Type, "url": l.URL, "label": l.Label})
+	}
+	props := map[string]any{
+		"handle":      p.Name,
+		"displayName": p.DisplayName,
+		"bio":         p.Bio,
+		"bioHTML":     markdown.Render(p.Bio),
+		"pronouns":    p.Pronouns,
+		"location":    p.Location,
+		"country":     p.Country,
+		"avatarURL":   p.AvatarURL,
+		"links":       links,
+	}
+	if p.Email != "" {
+		props["email"] = p.Email
+	}
+	return props
+}
+
+// usersLoader loads the user directory at /users. A malformed after cursor
+// fails with model.ErrInvalidCursor.
+func usersLoader(authService *services.AuthService, userService *services.UserService) tracing.PropsLoader {
+	return func(req *http.Request) (map[string]any, error) {
+		query := req.URL.Query()
+		search := services.SearchInput{
+			Query:   query.Get("q"),
+			Country: query.Get("country"),
+			Sort:    query.Get("sort"),
+			After:   query.Get("after"),
+		}
+		result, err := userService.Search(req.Context(), search)
+		if err != nil {
+			return nil, err
+		}
+		locale := i18n.DetectLocale(req)
+		props := map[string]any{
+			"locale": locale,
+			"t":      i18n.Translations(locale),
+			"users":  listedUsersProps(result.Users),
+			"next":   result.Next,
+			"search": map[string]any{"q": search.Query, "country": search.Country, "sort": search.Sort},
+		}
+		if u := authService.GetUserFromRequest(req); u != nil {
+			props["user"] = map[string]any{"email": u.Email, "handle": u.Name}
+		}
+		return props, nil
+	}
+}
+
+// listedUsersProps describes users for the directory and follow lists,
+// using only fields that are always public.
+func listedUsersProps(users []model.User) []map[string]any {
+	list := make([]map[string]any, 0, len(users))
+	for _, u := range users {
+		list = append(list, map[string]any{
+			"handle":      u.Name,
+			"displayName": u.DisplayName,
+			"avatarURL":   u.AvatarURL,
+		})
+	}
+	return list
+}
+
+// editProfileLoader loads the profile edit page. The page shows every field,
+// hidden or not, so only the signed-in owner gets it: signed-out visitors
+// are sent to log in and everyone else to the public profile.
+func editProfileLoader(authService *services.AuthService, userService *services.UserService) tracing.PropsLoader {
+	return func(req *http.Request) (map[string]any, error) {
+		currentUser := authService.GetUserFromRequest(req)
+		if currentUser == nil {
+			return nil, &handlers.Redirect{URL: "/login"}
+		}
+		profile, err := handlers.LookupProfile(req, userService, "/user/")
+		if err != nil {
+			return nil, err
+		}
+		if profile.ID != currentUser.ID {
+			return nil, &handlers.Redirect{URL: "/user/" + profile.Name}
+		}
+		locale := i18n.DetectLocale(req)
+		props := map[string]any{
+			"locale":    locale,
+			"t":         i18n.Translations(locale),
+			"user":      map[string]any{"email": currentUser.Email, "handle": currentUser.Name},
+			"profile":   profileProps(profile),
+			"linkTypes": services.LinkTypes,
+			"maxLinks":  services.MaxLinks,
+		}
+		maps.Copy(props, handlers.FlashFrom(req.Context()).Props(locale))
+		if req.URL.Query().Get("success") == "1" {
+			props["success"] = true
+		}
+		return props, nil
+	}
+}
+
+// pruneRateLimits periodically deletes database rate limit buckets that have
+// refilled completely, until ctx is cancelled.
+func pruneRateLimits(ctx context.Context, store *ratelimit.DBStore) {
+	ticker := time.NewTicker(10 * time.Minute)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if n, err := store.Prune(ctx); err != nil {
+				slog.Warn("failed to prune rate limit buckets", "error", err)
+			} else if n > 0 {
+				slog.Debug("pruned rate limit buckets", "count", n)
+			}
+		}
+	}
 }
 
 func handleSetLang(w http.ResponseWriter, r *http.Request) {
@@ -0,0 +1,168 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"myapp/handlers"
+	"myapp/i18n"
+	"myapp/metrics"
+	"myapp/model"
+	"myapp/ratelimit"
+	"myapp/services"
+	"myapp/storage"
+	"myapp/testutil"
+)
+
+// recordingMux records the patterns registered on it.
+type recordingMux struct {
+	patterns []string
+}
+
+func (m *recordingMux) Handle(pattern string, _ http.Handler) {
+	m.patterns = append(m.patterns, pattern)
+}
+
+func (m *recordingMux) HandleFunc(pattern string, _ func(http.ResponseWriter, *http.Request)) {
+	m.patterns = append(m.patterns, pattern)
+}
+
+func TestRoutesDocumented(t *testing.T) {
+	db := testutil.NewTestDB(t, &model.User{}, &model.HandleHistory{}, &model.PersonalAccessToken{}, &model.Follow{}, &model.Block{})
+	users := model.NewUserRepository(db)
+	tokenSvc := services.NewTokenService(model.NewTokenRepository(db), users)
+	authSvc := services.NewAuthService(users, "test-secret", nil, services.EmailPolicy{}, tokenSvc, nil)
+	userSvc := services.NewUserService(users, nil)
+	followSvc := services.NewFollowService(model.NewFollowRepository(db), model.NewBlockRepository(db), users)
+	privacySvc := services.NewPrivacyService(users, followSvc)
+	moderationSvc := services.NewModerationService(model.NewReportRepository(db), users, services.EmailPolicy{})
+	flasher := handlers.NewFlasher("test-secret")
+	doc := handlers.OpenAPISpec()
+
+	mux := &recordingMux{}
+	registerRoutes(mux, routeHandlers{
+		auth:        handlers.NewAuthHandler(authSvc, flasher),
+		user:        handlers.NewUserHandler(userSvc, authSvc, storage.Noop(), flasher),
+		tokens:      handlers.NewTokenHandler(tokenSvc, authSvc, flasher),
+		follows:     handlers.NewFollowHandler(followSvc, authSvc, flasher),
+		privacy:     handlers.NewPrivacyHandler(privacySvc, authSvc, flasher),
+		moderation:  handlers.NewModerationHandler(moderationSvc, authSvc, flasher),
+		api:         handlers.NewAPIHandler(authSvc, userSvc, privacySvc, storage.Noop()),
+		health:      handlers.NewHealthHandler(db, storage.Noop()),
+		limiter:     handlers.NewRateLimiter(ratelimit.Unlimited(), false, flasher),
+		authService: authSvc,
+		metrics:     metrics.New(),
+		openAPI:     handlers.OpenAPI(doc),
+	})
+
+	registered := map[string]bool{}
+	for _, pattern := range mux.patterns {
+		registered[pattern] = true
+		method, path, ok := strings.Cut(pattern, " ")
+		if !ok {
+			t.Errorf("route %q has no method", pattern)
+			continue
+		}
+		if doc.Operation(method, path) == nil {
+			t.Errorf("route %q is not documented in the OpenAPI spec", pattern)
+		}
+	}
+
+	for path, item := range doc.Paths {
+		for method := range *item {
+			if pattern := strings.ToUpper(method) + " " + path; !registered[pattern] {
+				t.Errorf("documented operation %q is not registered", pattern)
+			}
+		}
+	}
+}
+
+func TestEditProfileLoader(t *testing.T) {
+	if err := i18n.Load(); err != nil {
+		t.Fatalf("failed to load translations: %v", err)
+	}
+	ctx := context.Background()
+	db := testutil.NewTestDB(t, &model.User{}, &model.HandleHistory{})
+	users := model.NewUserRepository(db)
+	authSvc := services.NewAuthService(users, "test-secret", nil, services.EmailPolicy{}, nil, nil)
+	userSvc := services.NewUserService(users, nil)
+	load := editProfileLoader(authSvc, userSvc)
+
+	owner, _ := authSvc.Signup(ctx, "alice@example.com", "password123", "alice")
+	other, _ := authSvc.Signup(ctx, "bob@example.com", "password123", "bob")
+
+	request := func(session string) *http.Request {
+		req := httptest.NewRequest(http.MethodGet, "/user/alice/edit", nil)
+		req.SetPathValue("handle", "alice")
+		if session != "" {
+			req.AddCookie(&http.Cookie{Name: "session", Value: session})
+		}
+		return req
+	}
+
+	cases := []struct {
+		name    string
+		session string
+		target  string
+	}{
+		{"signed out", "", "/login"},
+		{"other user", other, "/user/alice"},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name+" is redirected", func(t *testing.T) {
+			props, err := load(request(tc.session))
+			var redirect *handlers.Redirect
+			if !errors.As(err, &redirect) || redirect.URL != tc.target {
+				t.Fatalf("expected redirect to %s, got %v", tc.target, err)
+			}
+			if props != nil {
+				t.Errorf("expected no props, got %v", props)
+			}
+		})
+	}
+
+	t.Run("owner sees the whole profile", func(t *testing.T) {
+		props, err := load(request(owner))
+		if err != nil {
+			t.Fatalf("load failed: %v", err)
+		}
+		profile, _ := props["profile"].(map[string]any)
+		if profile["email"] != "alice@example.com" {
+			t.Errorf("expected the owner's email, got %v", profile)
+		}
+	})
+}
+
+func TestUsersPageBadCursor(t *testing.T) {
+	if err := i18n.Load(); err != nil {
+		t.Fatalf("failed to load translations: %v", err)
+	}
+	db := testutil.NewTestDB(t, &model.User{}, &model.HandleHistory{})
+	users := model.NewUserRepository(db)
+	authSvc := services.NewAuthService(users, "test-secret", nil, services.EmailPolicy{}, nil, nil)
+	load := handlers.PageLoader(usersLoader(authSvc, services.NewUserService(users, nil)))
+
+	// Like bifrost, the page answers any loader error with a 500.
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /users", func(w http.ResponseWriter, r *http.Request) {
+		if _, err := load(r); err != nil {
+			w.WriteHeader(http.StatusInternalServerError)
+			_, _ = w.Write([]byte(err.Error()))
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	})
+
+	w := httptest.NewRecorder()
+	handlers.PageErrors(mux).ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/users?after=garbage", nil))
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("expected 400, got %d", w.Code)
+	}
+	if strings.Contains(w.Body.String(), "error.invalidRequest") {
+		t.Errorf("expected a localized message, got %s", w.Body.String())
+	}
+}
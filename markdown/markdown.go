@@ -0,0 +1,229 @@
+// Package markdown renders the small Markdown subset allowed in profile bios
+// to HTML: paragraphs and line breaks, bulleted and numbered lists, emphasis
+// and links. Everything else is shown as typed.
+//
+// The output is safe to insert into a page as is. All input text is escaped,
+// and the only tags in the output are the ones the renderer writes itself.
+// Links must be absolute http(s) URLs and carry rel="nofollow ugc", since
+// the text comes from users.
+package markdown
+
+import (
+	"html"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+var (
+	bulletItem   = regexp.MustCompile(`^ {0,3}[-*+][ \t]+(.*)$`)
+	numberedItem = regexp.MustCompile(`^ {0,3}([0-9]{1,9})[.)][ \t]+(.*)$`)
+)
+
+// escapable lists the characters a backslash makes literal.
+const escapable = "\\`*_[]()#+-.!"
+
+// Render converts src to HTML.
+func Render(src string) string {
+	src = strings.ReplaceAll(src, "\r\n", "\n")
+	r := &renderer{}
+	for _, line := range strings.Split(src, "\n") {
+		r.line(line)
+	}
+	r.close()
+	return r.out.String()
+}
+
+// blockKind is the block the renderer is in the middle of.
+type blockKind int
+
+const (
+	blockNone blockKind = iota
+	blockParagraph
+	blockBullets
+	blockNumbers
+)
+
+type renderer struct {
+	out   strings.Builder
+	block blockKind
+}
+
+func (r *renderer) line(line string) {
+	if strings.TrimSpace(line) == "" {
+		r.close()
+		return
+	}
+	if m := bulletItem.FindStringSubmatch(line); m != nil {
+		r.item(blockBullets, "<ul>", m[1])
+		return
+	}
+	if m := numberedItem.FindStringSubmatch(line); m != nil {
+		open := "<ol>"
+		if n, _ := strconv.Atoi(m[1]); n != 1 {
+			open = `<ol start="` + strconv.Itoa(n) + `">`
+		}
+		r.item(blockNumbers, open, m[2])
+		return
+	}
+
+	// Lines that are not list items end a list and break a paragraph.
+	switch r.block {
+	case blockParagraph:
+		r.out.WriteString("<br>\n")
+	default:
+		r.close()
+		r.out.WriteString("<p>")
+		r.block = blockParagraph
+	}
+	inline(&r.out, strings.TrimSpace(line), true)
+}
+
+// item adds a list item, opening a list of kind with open unless one is
+// already open.
+func (r *renderer) item(kind blockKind, open, text string) {
+	if r.block != kind {
+		r.close()
+		r.out.WriteString(open)
+		r.block = kind
+	}
+	r.out.WriteString("<li>")
+	inline(&r.out, strings.TrimSpace(text), true)
+	r.out.WriteString("</li>")
+}
+
+func (r *renderer) close() {
+	switch r.block {
+	case blockParagraph:
+		r.out.WriteString("</p>\n")
+	case blockBullets:
+		r.out.WriteString("</ul>\n")
+	case blockNumbers:
+		r.out.WriteString("</ol>\n")
+	}
+	r.block = blockNone
+}
+
+// inline writes s with its emphasis and, if links is set, its links
+// rendered. Links are not allowed inside link text.
+func inline(b *strings.Builder, s string, links bool) {
+	start := 0 // start of the pending run of plain text
+	flush := func(end int) {
+		b.WriteString(html.EscapeString(s[start:end]))
+	}
+
+	for i := 0; i < len(s); {
+		switch c := s[i]; {
+		case c == '\\' && i+1 < len(s) && strings.IndexByte(escapable, s[i+1]) >= 0:
+			flush(i)
+			b.WriteString(html.EscapeString(s[i+1 : i+2]))
+			i += 2
+			start = i
+
+		case c == '[' && links:
+			text, href, end, ok := link(s, i)
+			if !ok {
+				i++
+				continue
+			}
+			flush(i)
+			b.WriteString(`<a href="` + html.EscapeString(href) + `" rel="nofollow ugc">`)
+			inline(b, text, false)
+			b.WriteString("</a>")
+			i = end
+			start = i
+
+		case c == '*' || c == '_':
+			tag, inner, end, ok := emphasis(s, i)
+			if !ok {
+				// Skip the whole run so "**" is not retried as "*".
+				for i < len(s) && s[i] == c {
+					i++
+				}
+				continue
+			}
+			flush(i)
+			b.WriteString("<" + tag + ">")
+			inline(b, inner, links)
+			b.WriteString("</" + tag + ">")
+			i = end
+			start = i
+
+		default:
+			i++
+		}
+	}
+	flush(len(s))
+}
+
+// link parses a [text](url) link starting at s[i]. It reports false unless
+// the syntax is complete and url is safe to link to.
+func link(s string, i int) (text, href string, end int, ok bool) {
+	bracket := strings.IndexByte(s[i:], ']')
+	if bracket < 0 || !strings.HasPrefix(s[i+bracket:], "](") {
+		return "", "", 0, false
+	}
+	text = s[i+1 : i+bracket]
+	rest := s[i+bracket+2:]
+	paren := strings.IndexByte(rest, ')')
+	if text == "" || paren < 0 {
+		return "", "", 0, false
+	}
+	href = strings.TrimSpace(rest[:paren])
+	if !safeURL(href) {
+		return "", "", 0, false
+	}
+	return text, href, i + bracket + 2 + paren + 1, true
+}
+
+// safeURL reports whether href is an absolute http(s) URL. Anything else,
+// such as javascript: or data: URLs, or relative paths, is not linked.
+func safeURL(href string) bool {
+	if strings.ContainsAny(href, " \t\n<>\"'`") {
+		return false
+	}
+	u, err := url.Parse(href)
+	if err != nil || u.User != nil || u.Host == "" {
+		return false
+	}
+	return u.Scheme == "https" || u.Scheme == "http"
+}
+
+// emphasis parses emphasis opening at s[i]: *text* or _text_ for <em>, and
+// **text** or __text__ for <strong>. The delimiters must hug the text, and
+// an underscore inside a word, as in snake_case, is not emphasis.
+func emphasis(s string, i int) (tag, inner string, end int, ok bool) {
+	delim, tag := s[i:i+1], "em"
+	if strings.HasPrefix(s[i:], delim+delim) {
+		delim, tag = delim+delim, "strong"
+	}
+	if delim[0] == '_' && i > 0 && isWordByte(s[i-1]) {
+		return "", "", 0, false
+	}
+
+	open := i + len(delim)
+	for j := open + 1; j+len(delim) <= len(s); j++ {
+		if s[j:j+len(delim)] != delim || s[j-1] == delim[0] {
+			continue
+		}
+		after := j + len(delim)
+		if after < len(s) && s[after] == delim[0] {
+			// Part of a longer run; a closing delimiter ends it.
+			continue
+		}
+		if delim[0] == '_' && after < len(s) && isWordByte(s[after]) {
+			continue
+		}
+		inner = s[open:j]
+		if strings.TrimSpace(inner) != inner {
+			continue
+		}
+		return tag, inner, after, true
+	}
+	return "", "", 0, false
+}
+
+func isWordByte(c byte) bool {
+	return c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c >= 0x80
+}
@@ -0,0 +1,62 @@
+package metrics
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+const queryStartKey = "metrics:query_start"
+
+// GormPlugin records every statement's latency in db_query_duration_seconds.
+// Register it with db.Use(metrics.GormPlugin(m)).
+func GormPlugin(m *Metrics) gorm.Plugin {
+	return &gormPlugin{m: m}
+}
+
+type gormPlugin struct {
+	m *Metrics
+}
+
+func (p *gormPlugin) Name() string {
+	return "metrics"
+}
+
+func (p *gormPlugin) Initialize(db *gorm.DB) error {
+	cb := db.Callback()
+	hooks := []struct {
+		operation string
+		before    func(name string, fn func(*gorm.DB)) error
+		after     func(name string, fn func(*gorm.DB)) error
+	}{
+		{"create", cb.Create().Before("gorm:create").Register, cb.Create().After("gorm:create").Register},
+		{"query", cb.Query().Before("gorm:query").Register, cb.Query().After("gorm:query").Register},
+		{"update", cb.Update().Before("gorm:update").Register, cb.Update().After("gorm:update").Register},
+		{"delete", cb.Delete().Before("gorm:delete").Register, cb.Delete().After("gorm:delete").Register},
+		{"row", cb.Row().Before("gorm:row").Register, cb.Row().After("gorm:row").Register},
+		{"raw", cb.Raw().Before("gorm:raw").Register, cb.Raw().After("gorm:raw").Register},
+	}
+
+	for _, h := range hooks {
+		operation := h.operation
+		if err := h.before("metrics:before_"+operation, func(tx *gorm.DB) {
+			tx.InstanceSet(queryStartKey, time.Now())
+		}); err != nil {
+			return err
+		}
+		if err := h.after("metrics:after_"+operation, func(tx *gorm.DB) {
+			start, ok := tx.InstanceGet(queryStartKey)
+			if !ok {
+				return
+			}
+			table := tx.Statement.Table
+			if table == "" {
+				table = "unknown"
+			}
+			p.m.ObserveQuery(operation, table, time.Since(start.(time.Time)))
+		}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,141 @@
+package metrics
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const namespace = "myapp"
+
+// Metrics owns a private Prometheus registry and every collector the app
+// exports. All recording methods are safe to call on a nil *Metrics, so
+// components can be constructed without metrics in tests.
+type Metrics struct {
+	registry *prometheus.Registry
+
+	httpRequests       *prometheus.CounterVec
+	httpDuration       *prometheus.HistogramVec
+	signups            *prometheus.CounterVec
+	logins             *prometheus.CounterVec
+	storageUploadBytes *prometheus.CounterVec
+	storageUploadTime  *prometheus.HistogramVec
+	dbQueryDuration    *prometheus.HistogramVec
+}
+
+func New() *Metrics {
+	m := &Metrics{
+		registry: prometheus.NewRegistry(),
+		httpRequests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "http_requests_total",
+			Help:      "HTTP requests by method, route pattern and status code.",
+		}, []string{"method", "route", "status"}),
+		httpDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "http_request_duration_seconds",
+			Help:      "HTTP request latency by method and route pattern.",
+			Buckets:   prometheus.DefBuckets,
+		}, []string{"method", "route"}),
+		signups: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "auth_signups_total",
+			Help:      "Signup attempts by result.",
+		}, []string{"result"}),
+		logins: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "auth_logins_total",
+			Help:      "Login attempts by result.",
+		}, []string{"result"}),
+		storageUploadBytes: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "storage_upload_bytes_total",
+			Help:      "Bytes successfully uploaded by storage backend.",
+		}, []string{"backend"}),
+		storageUploadTime: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "storage_upload_duration_seconds",
+			Help:      "Upload latency by storage backend and result.",
+			Buckets:   prometheus.DefBuckets,
+		}, []string{"backend", "result"}),
+		dbQueryDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "db_query_duration_seconds",
+			Help:      "GORM statement latency by operation and table.",
+			Buckets:   []float64{.0005, .001, .0025, .005, .01, .025, .05, .1, .25, .5, 1},
+		}, []string{"operation", "table"}),
+	}
+
+	m.registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		m.httpRequests,
+		m.httpDuration,
+		m.signups,
+		m.logins,
+		m.storageUploadBytes,
+		m.storageUploadTime,
+		m.dbQueryDuration,
+	)
+	return m
+}
+
+// Handler serves the registry in the Prometheus exposition format.
+func (m *Metrics) Handler() http.Handler {
+	return promhttp.HandlerFor(m.registry, promhttp.HandlerOpts{Registry: m.registry})
+}
+
+// Registry exposes the underlying registry, mainly for tests.
+func (m *Metrics) Registry() *prometheus.Registry {
+	return m.registry
+}
+
+func (m *Metrics) ObserveHTTPRequest(method, route string, status int, d time.Duration) {
+	if m == nil {
+		return
+	}
+	m.httpRequests.WithLabelValues(method, route, strconv.Itoa(status)).Inc()
+	m.httpDuration.WithLabelValues(method, route).Observe(d.Seconds())
+}
+
+func (m *Metrics) ObserveSignup(err error) {
+	if m == nil {
+		return
+	}
+	m.signups.WithLabelValues(result(err)).Inc()
+}
+
+func (m *Metrics) ObserveLogin(err error) {
+	if m == nil {
+		return
+	}
+	m.logins.WithLabelValues(result(err)).Inc()
+}
+
+func (m *Metrics) ObserveUpload(backend string, bytes int64, d time.Duration, err error) {
+	if m == nil {
+		return
+	}
+	m.storageUploadTime.WithLabelValues(backend, result(err)).Observe(d.Seconds())
+	if err == nil && bytes > 0 {
+		m.storageUploadBytes.WithLabelValues(backend).Add(float64(bytes))
+	}
+}
+
+func (m *Metrics) ObserveQuery(operation, table string, d time.Duration) {
+	if m == nil {
+		return
+	}
+	m.dbQueryDuration.WithLabelValues(operation, table).Observe(d.Seconds())
+}
+
+func result(err error) string {
+	if err != nil {
+		return "failure"
+	}
+	return "success"
+}
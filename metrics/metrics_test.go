@@ -0,0 +1,90 @@
+package metrics
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+func scrape(t *testing.T, m *Metrics) string {
+	t.Helper()
+	w := httptest.NewRecorder()
+	m.Handler().ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	body, _ := io.ReadAll(w.Body)
+	return string(body)
+}
+
+func TestNilMetricsIsNoop(t *testing.T) {
+	var m *Metrics
+	m.ObserveHTTPRequest("GET", "/", 200, time.Millisecond)
+	m.ObserveSignup(nil)
+	m.ObserveLogin(errors.New("nope"))
+	m.ObserveUpload("local", 10, time.Millisecond, nil)
+	m.ObserveQuery("query", "users", time.Millisecond)
+}
+
+func TestObserve(t *testing.T) {
+	m := New()
+	m.ObserveHTTPRequest("GET", "GET /user/{handle}", 200, 20*time.Millisecond)
+	m.ObserveSignup(nil)
+	m.ObserveLogin(errors.New("invalid credentials"))
+	m.ObserveUpload("s3", 1024, 50*time.Millisecond, nil)
+	m.ObserveUpload("s3", 2048, 50*time.Millisecond, errors.New("boom"))
+
+	if got := testutil.ToFloat64(m.storageUploadBytes.WithLabelValues("s3")); got != 1024 {
+		t.Errorf("got %v uploaded bytes, want only the successful 1024", got)
+	}
+
+	body := scrape(t, m)
+	for _, want := range []string{
+		`myapp_http_requests_total{method="GET",route="GET /user/{handle}",status="200"} 1`,
+		`myapp_auth_signups_total{result="success"} 1`,
+		`myapp_auth_logins_total{result="failure"} 1`,
+		`myapp_storage_upload_duration_seconds_count{backend="s3",result="failure"} 1`,
+		`go_goroutines`,
+	} {
+		if !strings.Contains(body, want) {
+			t.Errorf("metrics output missing %q", want)
+		}
+	}
+}
+
+func TestGormPlugin(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("failed to open db: %v", err)
+	}
+	m := New()
+	if err := db.Use(GormPlugin(m)); err != nil {
+		t.Fatalf("failed to register plugin: %v", err)
+	}
+
+	type widget struct {
+		ID   uint
+		Name string
+	}
+	if err := db.AutoMigrate(&widget{}); err != nil {
+		t.Fatal(err)
+	}
+	db.Create(&widget{Name: "a"})
+	var got []widget
+	db.Find(&got)
+
+	body := scrape(t, m)
+	for _, want := range []string{
+		`myapp_db_query_duration_seconds_count{operation="create",table="widgets"} 1`,
+		`myapp_db_query_duration_seconds_count{operation="query",table="widgets"} 1`,
+	} {
+		if !strings.Contains(body, want) {
+			t.Errorf("metrics output missing %q", want)
+		}
+	}
+}
@@ -0,0 +1,11 @@
+-- Create "rate_limit_buckets" table
+CREATE TABLE `rate_limit_buckets` (
+  `id` text NOT NULL,
+  `tokens` real NOT NULL,
+  `refilled_at` integer NOT NULL,
+  `version` integer NOT NULL,
+  `expires_at` integer NOT NULL,
+  PRIMARY KEY (`id`)
+);
+-- Create index "idx_rate_limit_buckets_expires_at" to table: "rate_limit_buckets"
+CREATE INDEX `idx_rate_limit_buckets_expires_at` ON `rate_limit_buckets` (`expires_at`);
@@ -0,0 +1,22 @@
+-- Create "personal_access_tokens" table
+CREATE TABLE `personal_access_tokens` (
+  `id` text NULL,
+  `created_at` datetime NULL,
+  `updated_at` datetime NULL,
+  `deleted_at` datetime NULL,
+  `user_id` text NOT NULL,
+  `name` text NOT NULL,
+  `token_hash` text NOT NULL,
+  `prefix` text NOT NULL,
+  `scopes` text NOT NULL,
+  `expires_at` datetime NULL,
+  `last_used_at` datetime NULL,
+  `revoked_at` datetime NULL,
+  PRIMARY KEY (`id`)
+);
+-- Create index "idx_personal_access_tokens_token_hash" to table: "personal_access_tokens"
+CREATE UNIQUE INDEX `idx_personal_access_tokens_token_hash` ON `personal_access_tokens` (`token_hash`);
+-- Create index "idx_personal_access_tokens_user_id" to table: "personal_access_tokens"
+CREATE INDEX `idx_personal_access_tokens_user_id` ON `personal_access_tokens` (`user_id`);
+-- Create index "idx_personal_access_tokens_deleted_at" to table: "personal_access_tokens"
+CREATE INDEX `idx_personal_access_tokens_deleted_at` ON `personal_access_tokens` (`deleted_at`);
@@ -0,0 +1,12 @@
+-- Create "follows" table
+CREATE TABLE `follows` (
+  `follower_id` text NOT NULL,
+  `followee_id` text NOT NULL,
+  `created_at` datetime NOT NULL,
+  PRIMARY KEY (`follower_id`, `followee_id`),
+  CONSTRAINT `fk_follows_follower` FOREIGN KEY (`follower_id`) REFERENCES `users` (`id`) ON UPDATE NO ACTION ON DELETE CASCADE,
+  CONSTRAINT `fk_follows_followee` FOREIGN KEY (`followee_id`) REFERENCES `users` (`id`) ON UPDATE NO ACTION ON DELETE CASCADE,
+  CONSTRAINT `chk_follows_not_self` CHECK (follower_id <> followee_id)
+);
+-- Create index "idx_follows_followee_id" to table: "follows"
+CREATE INDEX `idx_follows_followee_id` ON `follows` (`followee_id`);
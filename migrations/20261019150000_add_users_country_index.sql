@@ -0,0 +1,2 @@
+-- Create index "idx_users_country" to table: "users"
+CREATE INDEX `idx_users_country` ON `users` (`country`);
@@ -0,0 +1,18 @@
+-- Written by hand: GORM models cannot describe FTS5 tables or triggers.
+-- Create "users_fts" table, a trigram index over handles and display names
+CREATE VIRTUAL TABLE `users_fts` USING fts5(`user_id` UNINDEXED, `name`, `display_name`, tokenize = 'trigram');
+-- Index the existing users
+INSERT INTO `users_fts` (`user_id`, `name`, `display_name`) SELECT `id`, `name`, coalesce(`display_name`, '') FROM `users` WHERE `deleted_at` IS NULL;
+-- Create trigger "users_fts_insert" to table: "users"
+CREATE TRIGGER `users_fts_insert` AFTER INSERT ON `users` WHEN new.`deleted_at` IS NULL BEGIN
+  INSERT INTO `users_fts` (`user_id`, `name`, `display_name`) VALUES (new.`id`, new.`name`, coalesce(new.`display_name`, ''));
+END;
+-- Create trigger "users_fts_update" to table: "users"
+CREATE TRIGGER `users_fts_update` AFTER UPDATE OF `name`, `display_name`, `deleted_at` ON `users` BEGIN
+  DELETE FROM `users_fts` WHERE `user_id` = old.`id`;
+  INSERT INTO `users_fts` (`user_id`, `name`, `display_name`) SELECT new.`id`, new.`name`, coalesce(new.`display_name`, '') WHERE new.`deleted_at` IS NULL;
+END;
+-- Create trigger "users_fts_delete" to table: "users"
+CREATE TRIGGER `users_fts_delete` AFTER DELETE ON `users` BEGIN
+  DELETE FROM `users_fts` WHERE `user_id` = old.`id`;
+END;
@@ -0,0 +1,17 @@
+-- Create "handle_histories" table
+CREATE TABLE `handle_histories` (
+  `id` text NULL,
+  `created_at` datetime NULL,
+  `updated_at` datetime NULL,
+  `deleted_at` datetime NULL,
+  `user_id` text NOT NULL,
+  `handle` text NOT NULL,
+  PRIMARY KEY (`id`),
+  CONSTRAINT `fk_handle_histories_user` FOREIGN KEY (`user_id`) REFERENCES `users` (`id`) ON UPDATE NO ACTION ON DELETE CASCADE
+);
+-- Create index "idx_handle_histories_handle" to table: "handle_histories"
+CREATE INDEX `idx_handle_histories_handle` ON `handle_histories` (`handle`);
+-- Create index "idx_handle_histories_user_id" to table: "handle_histories"
+CREATE INDEX `idx_handle_histories_user_id` ON `handle_histories` (`user_id`);
+-- Create index "idx_handle_histories_deleted_at" to table: "handle_histories"
+CREATE INDEX `idx_handle_histories_deleted_at` ON `handle_histories` (`deleted_at`);
@@ -0,0 +1,13 @@
+-- Rename all but the oldest live user sharing a handle, which only racing
+-- signups could create, so the unique index can be built. The new handle is
+-- the old one cut to 21 characters plus the first 8 of the user id.
+UPDATE `users` SET `name` = substr(`name`, 1, 21) || '-' || substr(`id`, 1, 8)
+WHERE `deleted_at` IS NULL AND EXISTS (
+  SELECT 1 FROM `users` AS `older`
+  WHERE `older`.`name` = `users`.`name`
+    AND `older`.`deleted_at` IS NULL
+    AND (`older`.`created_at` < `users`.`created_at`
+      OR (`older`.`created_at` = `users`.`created_at` AND `older`.`id` < `users`.`id`))
+);
+-- Create index "idx_users_name" to table: "users"
+CREATE UNIQUE INDEX `idx_users_name` ON `users` (`name`) WHERE deleted_at IS NULL;
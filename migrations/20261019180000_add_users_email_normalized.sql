@@ -0,0 +1,13 @@
+-- Add column "email_normalized" to table: "users"
+ALTER TABLE `users` ADD COLUMN `email_normalized` text NULL;
+-- A first pass with the default policy, which keeps plus tags. SQLite's
+-- lower() only folds ASCII, so the app finishes the job with EmailPolicy
+-- when it starts (AuthService.NormalizeStoredEmails).
+UPDATE `users` SET `email_normalized` = lower(trim(`email`));
+-- Accounts whose emails differ only in case make the unique index below
+-- fail, and the migration with it, rather than silently losing their
+-- normalized email. Resolve them by hand first; this lists them:
+-- SELECT lower(trim(email)), group_concat(id) FROM users
+--   GROUP BY lower(trim(email)) HAVING count(*) > 1;
+-- Create index "idx_users_email_normalized" to table: "users"
+CREATE UNIQUE INDEX `idx_users_email_normalized` ON `users` (`email_normalized`);
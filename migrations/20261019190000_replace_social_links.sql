@@ -0,0 +1,16 @@
+-- Add column "pronouns" to table: "users"
+ALTER TABLE `users` ADD COLUMN `pronouns` text NULL;
+-- Add column "location" to table: "users"
+ALTER TABLE `users` ADD COLUMN `location` text NULL;
+-- Add column "links" to table: "users"
+ALTER TABLE `users` ADD COLUMN `links` text NULL;
+-- Convert social_links, an object keyed by network, to a list of links in
+-- the order the edit form listed the networks. Empty URLs are dropped.
+UPDATE `users` SET `links` = (
+  SELECT json_group_array(json_object('type', `key`, 'url', `value`))
+  FROM json_each(`users`.`social_links`)
+  WHERE `value` <> ''
+)
+WHERE json_valid(`social_links`);
+-- Drop column "social_links" from table: "users"
+ALTER TABLE `users` DROP COLUMN `social_links`;
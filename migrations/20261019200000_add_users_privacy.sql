@@ -0,0 +1,4 @@
+-- Add column "visibility" to table: "users"
+ALTER TABLE `users` ADD COLUMN `visibility` text NULL;
+-- Add column "hide_from_search" to table: "users"
+ALTER TABLE `users` ADD COLUMN `hide_from_search` numeric NOT NULL DEFAULT false;
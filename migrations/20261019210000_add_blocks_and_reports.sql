@@ -0,0 +1,41 @@
+-- Add column "warnings" to table: "users"
+ALTER TABLE `users` ADD COLUMN `warnings` integer NOT NULL DEFAULT 0;
+-- Add column "suspended_at" to table: "users"
+ALTER TABLE `users` ADD COLUMN `suspended_at` datetime NULL;
+-- Create "blocks" table
+CREATE TABLE `blocks` (
+  `blocker_id` text NOT NULL,
+  `blocked_id` text NOT NULL,
+  `created_at` datetime NOT NULL,
+  PRIMARY KEY (`blocker_id`, `blocked_id`),
+  CONSTRAINT `fk_blocks_blocker` FOREIGN KEY (`blocker_id`) REFERENCES `users` (`id`) ON UPDATE NO ACTION ON DELETE CASCADE,
+  CONSTRAINT `fk_blocks_blocked` FOREIGN KEY (`blocked_id`) REFERENCES `users` (`id`) ON UPDATE NO ACTION ON DELETE CASCADE,
+  CONSTRAINT `chk_blocks_not_self` CHECK (blocker_id <> blocked_id)
+);
+-- Create index "idx_blocks_blocked_id" to table: "blocks"
+CREATE INDEX `idx_blocks_blocked_id` ON `blocks` (`blocked_id`);
+-- Create "reports" table
+CREATE TABLE `reports` (
+  `id` text NULL,
+  `created_at` datetime NULL,
+  `updated_at` datetime NULL,
+  `deleted_at` datetime NULL,
+  `reporter_id` text NOT NULL,
+  `reported_id` text NOT NULL,
+  `reason` text NOT NULL,
+  `evidence` text NOT NULL,
+  `status` text NOT NULL,
+  `resolved_by_id` text NULL,
+  `resolved_at` datetime NULL,
+  PRIMARY KEY (`id`),
+  CONSTRAINT `fk_reports_reporter` FOREIGN KEY (`reporter_id`) REFERENCES `users` (`id`) ON UPDATE NO ACTION ON DELETE CASCADE,
+  CONSTRAINT `fk_reports_reported` FOREIGN KEY (`reported_id`) REFERENCES `users` (`id`) ON UPDATE NO ACTION ON DELETE CASCADE
+);
+-- Create index "idx_reports_reporter_id" to table: "reports"
+CREATE INDEX `idx_reports_reporter_id` ON `reports` (`reporter_id`);
+-- Create index "idx_reports_reported_id" to table: "reports"
+CREATE INDEX `idx_reports_reported_id` ON `reports` (`reported_id`);
+-- Create index "idx_reports_status" to table: "reports"
+CREATE INDEX `idx_reports_status` ON `reports` (`status`);
+-- Create index "idx_reports_deleted_at" to table: "reports"
+CREATE INDEX `idx_reports_deleted_at` ON `reports` (`deleted_at`);
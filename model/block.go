@@ -0,0 +1,73 @@
+package model
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Block records that Blocker blocked Blocked. As with follows, the pair is
+// the primary key, so blocking someone twice is a no-op.
+type Block struct {
+	BlockerID uuid.UUID `gorm:"primaryKey"`
+	BlockedID uuid.UUID `gorm:"primaryKey;index;check:chk_blocks_not_self,blocker_id <> blocked_id"`
+	Blocker   User      `gorm:"foreignKey:BlockerID;constraint:OnDelete:CASCADE"`
+	Blocked   User      `gorm:"foreignKey:BlockedID;constraint:OnDelete:CASCADE"`
+	CreatedAt time.Time `gorm:"not null"`
+}
+
+type BlockRepository struct {
+	db *gorm.DB
+}
+
+func NewBlockRepository(db *gorm.DB) *BlockRepository {
+	return &BlockRepository{db: db}
+}
+
+// Transaction runs fn with block and follow repositories bound to one
+// transaction, which is committed if fn returns nil and rolled back
+// otherwise.
+func (r *BlockRepository) Transaction(ctx context.Context, fn func(blocks *BlockRepository, follows *FollowRepository) error) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		return fn(&BlockRepository{db: tx}, &FollowRepository{db: tx})
+	})
+}
+
+func (r *BlockRepository) Create(ctx context.Context, blockerID, blockedID uuid.UUID) error {
+	block := Block{BlockerID: blockerID, BlockedID: blockedID, CreatedAt: time.Now()}
+	err := r.db.WithContext(ctx).
+		Omit(clause.Associations).
+		Clauses(clause.OnConflict{DoNothing: true}).
+		Create(&block).Error
+	if err != nil {
+		return fmt.Errorf("failed to create block: %w", err)
+	}
+	return nil
+}
+
+// Delete removes the block if it exists.
+func (r *BlockRepository) Delete(ctx context.Context, blockerID, blockedID uuid.UUID) error {
+	err := r.db.WithContext(ctx).
+		Where("blocker_id = ? AND blocked_id = ?", blockerID, blockedID).
+		Delete(&Block{}).Error
+	if err != nil {
+		return fmt.Errorf("failed to delete block: %w", err)
+	}
+	return nil
+}
+
+func (r *BlockRepository) Exists(ctx context.Context, blockerID, blockedID uuid.UUID) (bool, error) {
+	var count int64
+	err := r.db.WithContext(ctx).
+		Model(&Block{}).
+		Where("blocker_id = ? AND blocked_id = ?", blockerID, blockedID).
+		Count(&count).Error
+	if err != nil {
+		return false, fmt.Errorf("failed to check block: %w", err)
+	}
+	return count > 0, nil
+}
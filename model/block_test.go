@@ -0,0 +1,61 @@
+package model
+
+import (
+	"context"
+	"testing"
+
+	"myapp/testutil"
+)
+
+func TestBlockRepository(t *testing.T) {
+	ctx := context.Background()
+	db := testutil.NewTestDB(t, &User{}, &Block{})
+	users := NewUserRepository(db)
+	repo := NewBlockRepository(db)
+
+	newUser := func(handle string) *User {
+		u := &User{Email: handle + "@example.com", PasswordHash: "x", Name: handle}
+		if err := users.Create(ctx, u); err != nil {
+			t.Fatalf("Create user failed: %v", err)
+		}
+		return u
+	}
+	alice, bob := newUser("alice"), newUser("bob")
+
+	if err := repo.Create(ctx, alice.ID, bob.ID); err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+
+	t.Run("blocking twice is a no-op", func(t *testing.T) {
+		if err := repo.Create(ctx, alice.ID, bob.ID); err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+	})
+
+	t.Run("self blocks are rejected by the schema", func(t *testing.T) {
+		if err := repo.Create(ctx, alice.ID, alice.ID); err == nil {
+			t.Error("expected the check constraint to reject a self block")
+		}
+	})
+
+	t.Run("exists is one way", func(t *testing.T) {
+		if ok, err := repo.Exists(ctx, alice.ID, bob.ID); err != nil || !ok {
+			t.Errorf("expected alice to block bob, got %v, %v", ok, err)
+		}
+		if ok, _ := repo.Exists(ctx, bob.ID, alice.ID); ok {
+			t.Error("expected blocks to be one way")
+		}
+	})
+
+	t.Run("delete", func(t *testing.T) {
+		if err := repo.Delete(ctx, alice.ID, bob.ID); err != nil {
+			t.Fatalf("Delete failed: %v", err)
+		}
+		if ok, _ := repo.Exists(ctx, alice.ID, bob.ID); ok {
+			t.Error("expected the block to be gone")
+		}
+		if err := repo.Delete(ctx, alice.ID, bob.ID); err != nil {
+			t.Errorf("expected deleting a missing block to be a no-op, got %v", err)
+		}
+	})
+}
@@ -0,0 +1,19 @@
+package model
+
+import (
+	"strings"
+
+	"myapp/util"
+)
+
+// ErrNotFound is returned by repositories when no live row matches. It is an
+// AppError, so handlers turn it into a 404 without a mapping of their own.
+var ErrNotFound = util.NotFound("error.notFound")
+
+// isUniqueViolation reports whether err is SQLite rejecting a write because
+// of a unique index on column, given as table.column. The cgo driver and
+// libSQL both pass SQLite's message through, so matching on it works for
+// either.
+func isUniqueViolation(err error, column string) bool {
+	return err != nil && strings.Contains(err.Error(), "UNIQUE constraint failed: "+column)
+}
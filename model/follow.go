@@ -0,0 +1,121 @@
+package model
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Follow records that Follower follows Followee. The pair is the primary
+// key, so following someone twice is a no-op.
+type Follow struct {
+	FollowerID uuid.UUID `gorm:"primaryKey"`
+	FolloweeID uuid.UUID `gorm:"primaryKey;index;check:chk_follows_not_self,follower_id <> followee_id"`
+	Follower   User      `gorm:"foreignKey:FollowerID;constraint:OnDelete:CASCADE"`
+	Followee   User      `gorm:"foreignKey:FolloweeID;constraint:OnDelete:CASCADE"`
+	CreatedAt  time.Time `gorm:"not null"`
+}
+
+type FollowRepository struct {
+	db *gorm.DB
+}
+
+func NewFollowRepository(db *gorm.DB) *FollowRepository {
+	return &FollowRepository{db: db}
+}
+
+func (r *FollowRepository) Create(ctx context.Context, followerID, followeeID uuid.UUID) error {
+	follow := Follow{FollowerID: followerID, FolloweeID: followeeID, CreatedAt: time.Now()}
+	err := r.db.WithContext(ctx).
+		Omit(clause.Associations).
+		Clauses(clause.OnConflict{DoNothing: true}).
+		Create(&follow).Error
+	if err != nil {
+		return fmt.Errorf("failed to create follow: %w", err)
+	}
+	return nil
+}
+
+// Delete removes the follow if it exists.
+func (r *FollowRepository) Delete(ctx context.Context, followerID, followeeID uuid.UUID) error {
+	err := r.db.WithContext(ctx).
+		Where("follower_id = ? AND followee_id = ?", followerID, followeeID).
+		Delete(&Follow{}).Error
+	if err != nil {
+		return fmt.Errorf("failed to delete follow: %w", err)
+	}
+	return nil
+}
+
+func (r *FollowRepository) Exists(ctx context.Context, followerID, followeeID uuid.UUID) (bool, error) {
+	var count int64
+	err := r.db.WithContext(ctx).
+		Model(&Follow{}).
+		Where("follower_id = ? AND followee_id = ?", followerID, followeeID).
+		Count(&count).Error
+	if err != nil {
+		return false, fmt.Errorf("failed to check follow: %w", err)
+	}
+	return count > 0, nil
+}
+
+// CountFollowers counts the users following userID, ignoring deleted ones.
+func (r *FollowRepository) CountFollowers(ctx context.Context, userID uuid.UUID) (int64, error) {
+	return r.count(ctx, "followee_id", "follower_id", userID)
+}
+
+// CountFollowing counts the users userID follows, ignoring deleted ones.
+func (r *FollowRepository) CountFollowing(ctx context.Context, userID uuid.UUID) (int64, error) {
+	return r.count(ctx, "follower_id", "followee_id", userID)
+}
+
+// ListFollowers returns up to limit users following userID, most recent
+// first, skipping offset. Deleted users are left out.
+func (r *FollowRepository) ListFollowers(ctx context.Context, userID uuid.UUID, offset, limit int) ([]User, error) {
+	return r.list(ctx, "followee_id", "follower_id", userID, offset, limit)
+}
+
+// ListFollowing returns up to limit users userID follows, most recent first,
+// skipping offset. Deleted users are left out.
+func (r *FollowRepository) ListFollowing(ctx context.Context, userID uuid.UUID, offset, limit int) ([]User, error) {
+	return r.list(ctx, "follower_id", "followee_id", userID, offset, limit)
+}
+
+// count and list select the follows where column is userID and join the
+// users on the other side through other. The column names are constants
+// from this file, never input.
+func (r *FollowRepository) count(ctx context.Context, column, other string, userID uuid.UUID) (int64, error) {
+	var count int64
+	err := r.db.WithContext(ctx).
+		Model(&Follow{}).
+		Joins("JOIN users ON users.id = follows."+other).
+		Where("follows."+column+" = ?", userID).
+		Where("users.deleted_at is null").
+		Count(&count).Error
+	if err != nil {
+		return 0, fmt.Errorf("failed to count follows: %w", err)
+	}
+	return count, nil
+}
+
+func (r *FollowRepository) list(ctx context.Context, column, other string, userID uuid.UUID, offset, limit int) ([]User, error) {
+	var users []User
+	err := r.db.WithContext(ctx).
+		Model(&User{}).
+		Joins("JOIN follows ON follows."+other+" = users.id").
+		Where("follows."+column+" = ?", userID).
+		Where("users.deleted_at is null").
+		Order("follows.created_at desc").
+		Order("users.id").
+		Offset(offset).
+		Limit(limit).
+		Find(&users).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to list follows: %w", err)
+	}
+	return users, nil
+}
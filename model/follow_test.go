@@ -0,0 +1,94 @@
+package model
+
+import (
+	"context"
+	"testing"
+
+	"myapp/testutil"
+)
+
+func TestFollowRepository(t *testing.T) {
+	ctx := context.Background()
+	db := testutil.NewTestDB(t, &User{}, &Follow{})
+	users := NewUserRepository(db)
+	repo := NewFollowRepository(db)
+
+	newUser := func(handle string) *User {
+		u := &User{Email: handle + "@example.com", PasswordHash: "x", Name: handle}
+		if err := users.Create(ctx, u); err != nil {
+			t.Fatalf("Create user failed: %v", err)
+		}
+		return u
+	}
+	alice, bob, carol := newUser("alice"), newUser("bob"), newUser("carol")
+
+	for _, f := range []*User{bob, carol} {
+		if err := repo.Create(ctx, f.ID, alice.ID); err != nil {
+			t.Fatalf("Create failed: %v", err)
+		}
+	}
+
+	t.Run("following twice is a no-op", func(t *testing.T) {
+		if err := repo.Create(ctx, bob.ID, alice.ID); err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+		if n, _ := repo.CountFollowers(ctx, alice.ID); n != 2 {
+			t.Errorf("expected 2 followers, got %d", n)
+		}
+	})
+
+	t.Run("self follows are rejected by the schema", func(t *testing.T) {
+		if err := repo.Create(ctx, alice.ID, alice.ID); err == nil {
+			t.Error("expected the check constraint to reject a self follow")
+		}
+	})
+
+	t.Run("exists and counts", func(t *testing.T) {
+		if ok, err := repo.Exists(ctx, bob.ID, alice.ID); err != nil || !ok {
+			t.Errorf("expected bob to follow alice, got %v, %v", ok, err)
+		}
+		if ok, _ := repo.Exists(ctx, alice.ID, bob.ID); ok {
+			t.Error("expected follows to be one way")
+		}
+		if n, _ := repo.CountFollowing(ctx, bob.ID); n != 1 {
+			t.Errorf("expected bob to follow 1 user, got %d", n)
+		}
+	})
+
+	t.Run("lists newest first and pages", func(t *testing.T) {
+		got, err := repo.ListFollowers(ctx, alice.ID, 0, 10)
+		if err != nil || len(got) != 2 || got[0].Name != "carol" || got[1].Name != "bob" {
+			t.Fatalf("expected [carol bob], got %v, %v", got, err)
+		}
+		if got, _ := repo.ListFollowers(ctx, alice.ID, 1, 1); len(got) != 1 || got[0].Name != "bob" {
+			t.Errorf("expected the second page to hold bob, got %v", got)
+		}
+		if got, _ := repo.ListFollowing(ctx, carol.ID, 0, 10); len(got) != 1 || got[0].Name != "alice" {
+			t.Errorf("expected carol to follow alice, got %v", got)
+		}
+	})
+
+	t.Run("deleted users are left out", func(t *testing.T) {
+		if err := users.Delete(ctx, carol.ID.String()); err != nil {
+			t.Fatalf("Delete failed: %v", err)
+		}
+		if n, _ := repo.CountFollowers(ctx, alice.ID); n != 1 {
+			t.Errorf("expected 1 follower, got %d", n)
+		}
+		if got, _ := repo.ListFollowers(ctx, alice.ID, 0, 10); len(got) != 1 || got[0].Name != "bob" {
+			t.Errorf("expected only bob, got %v", got)
+		}
+	})
+
+	t.Run("unfollow", func(t *testing.T) {
+		if err := repo.Delete(ctx, bob.ID, alice.ID); err != nil {
+			t.Fatalf("Delete failed: %v", err)
+		}
+		if ok, _ := repo.Exists(ctx, bob.ID, alice.ID); ok {
+			t.Error("expected the follow to be gone")
+		}
+		if err := repo.Delete(ctx, bob.ID, alice.ID); err != nil {
+			t.Errorf("expected unfollowing again to be a no-op, got %v", err)
+		}
+	})
+}
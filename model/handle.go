@@ -0,0 +1,90 @@
+package model
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"myapp/util"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// HandleHistory records a handle a user gave up by renaming. CreatedAt is
+// when it was released. Old handles redirect to the user's current profile.
+type HandleHistory struct {
+	util.Entity
+	UserID uuid.UUID `gorm:"not null;index"`
+	User   User      `gorm:"constraint:OnDelete:CASCADE"`
+	Handle string    `gorm:"not null;index"`
+}
+
+// Rename saves user, whose Name has changed from oldHandle, and records
+// oldHandle in the handle history in the same transaction.
+func (r *UserRepository) Rename(ctx context.Context, user *User, oldHandle string) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := duplicateUser(tx.Save(user).Error); err != nil {
+			return err
+		}
+		release := &HandleHistory{UserID: user.ID, Handle: oldHandle}
+		if err := tx.Omit("User").Create(release).Error; err != nil {
+			return fmt.Errorf("failed to record handle history: %w", err)
+		}
+		return nil
+	})
+}
+
+// LatestRelease returns the most recent release of handle by any user.
+func (r *UserRepository) LatestRelease(ctx context.Context, handle string) (*HandleHistory, error) {
+	var release HandleHistory
+	err := r.db.WithContext(ctx).
+		Where("handle = ?", handle).
+		Where("deleted_at is null").
+		Order("created_at desc").
+		First(&release).Error
+
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrNotFound
+		}
+		return nil, fmt.Errorf("failed to get handle release: %w", err)
+	}
+	return &release, nil
+}
+
+// GetByPastHandle returns the live user who most recently released handle.
+func (r *UserRepository) GetByPastHandle(ctx context.Context, handle string) (*User, error) {
+	var user User
+	err := r.db.WithContext(ctx).
+		Joins("JOIN handle_histories ON handle_histories.user_id = users.id").
+		Where("handle_histories.handle = ?", handle).
+		Where("handle_histories.deleted_at is null").
+		Where("users.deleted_at is null").
+		Order("handle_histories.created_at desc").
+		First(&user).Error
+
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrNotFound
+		}
+		return nil, fmt.Errorf("failed to get user by past handle: %w", err)
+	}
+	return &user, nil
+}
+
+// CountRenamesSince counts the handles userID released after since.
+func (r *UserRepository) CountRenamesSince(ctx context.Context, userID uuid.UUID, since time.Time) (int64, error) {
+	var count int64
+	err := r.db.WithContext(ctx).
+		Model(&HandleHistory{}).
+		Where("user_id = ?", userID).
+		Where("created_at > ?", since).
+		Where("deleted_at is null").
+		Count(&count).Error
+	if err != nil {
+		return 0, fmt.Errorf("failed to count renames: %w", err)
+	}
+	return count, nil
+}
@@ -0,0 +1,75 @@
+package model
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"myapp/testutil"
+)
+
+func TestHandleHistory(t *testing.T) {
+	ctx := context.Background()
+	db := testutil.NewTestDB(t, &User{}, &HandleHistory{})
+	repo := NewUserRepository(db)
+
+	user := newTestUser()
+	if err := repo.Create(ctx, user); err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+	rename := func(handle string) {
+		t.Helper()
+		old := user.Name
+		user.Name = handle
+		if err := repo.Rename(ctx, user, old); err != nil {
+			t.Fatalf("Rename failed: %v", err)
+		}
+	}
+	start := time.Now()
+	rename("second")
+	rename("third")
+
+	t.Run("rename saves the user", func(t *testing.T) {
+		if got, err := repo.GetByHandle(ctx, "third"); err != nil || got.ID != user.ID {
+			t.Errorf("expected the user under the new handle, got %v, %v", got, err)
+		}
+	})
+
+	t.Run("past handles resolve to the current user", func(t *testing.T) {
+		for _, handle := range []string{"testuser", "second"} {
+			got, err := repo.GetByPastHandle(ctx, handle)
+			if err != nil || got.Name != "third" {
+				t.Errorf("%s: expected the user now called third, got %v, %v", handle, got, err)
+			}
+		}
+		if _, err := repo.GetByPastHandle(ctx, "never"); !errors.Is(err, ErrNotFound) {
+			t.Errorf("expected ErrNotFound, got %v", err)
+		}
+	})
+
+	t.Run("latest release", func(t *testing.T) {
+		release, err := repo.LatestRelease(ctx, "second")
+		if err != nil || release.UserID != user.ID || release.CreatedAt.Before(start) {
+			t.Errorf("unexpected release %+v, %v", release, err)
+		}
+	})
+
+	t.Run("renames are counted in a window", func(t *testing.T) {
+		if n, _ := repo.CountRenamesSince(ctx, user.ID, start.Add(-time.Second)); n != 2 {
+			t.Errorf("expected 2 renames, got %d", n)
+		}
+		if n, _ := repo.CountRenamesSince(ctx, user.ID, time.Now().Add(time.Second)); n != 0 {
+			t.Errorf("expected no renames in the future, got %d", n)
+		}
+	})
+
+	t.Run("deleted users are not redirected to", func(t *testing.T) {
+		if err := repo.Delete(ctx, user.ID.String()); err != nil {
+			t.Fatalf("Delete failed: %v", err)
+		}
+		if _, err := repo.GetByPastHandle(ctx, "testuser"); !errors.Is(err, ErrNotFound) {
+			t.Errorf("expected ErrNotFound, got %v", err)
+		}
+	})
+}
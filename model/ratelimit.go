@@ -0,0 +1,82 @@
+package model
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// RateLimitBucket is the persisted state of one token bucket, shared by every
+// replica. Times are unix nanoseconds so comparisons stay exact in SQLite.
+type RateLimitBucket struct {
+	ID         string  `gorm:"primaryKey"`
+	Tokens     float64 `gorm:"not null"`
+	RefilledAt int64   `gorm:"not null"`
+	// Version is bumped on every write and used for compare-and-swap.
+	Version int64 `gorm:"not null"`
+	// ExpiresAt is when the bucket will be full again; after that the row
+	// carries no information and may be pruned.
+	ExpiresAt int64 `gorm:"index;not null"`
+}
+
+type RateLimitRepository struct {
+	db *gorm.DB
+}
+
+func NewRateLimitRepository(db *gorm.DB) *RateLimitRepository {
+	return &RateLimitRepository{db: db}
+}
+
+// Get returns the bucket with the given id, or nil if it does not exist.
+func (r *RateLimitRepository) Get(ctx context.Context, id string) (*RateLimitBucket, error) {
+	var bucket RateLimitBucket
+	err := r.db.WithContext(ctx).Where("id = ?", id).First(&bucket).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to get rate limit bucket: %w", err)
+	}
+	return &bucket, nil
+}
+
+// Insert creates bucket unless another writer created it first, in which
+// case it reports false.
+func (r *RateLimitRepository) Insert(ctx context.Context, bucket *RateLimitBucket) (bool, error) {
+	result := r.db.WithContext(ctx).Clauses(clause.OnConflict{DoNothing: true}).Create(bucket)
+	if result.Error != nil {
+		return false, fmt.Errorf("failed to insert rate limit bucket: %w", result.Error)
+	}
+	return result.RowsAffected == 1, nil
+}
+
+// CompareAndSwap stores bucket only if the row is still at bucket.Version,
+// then advances the version. It reports false when another writer won.
+func (r *RateLimitRepository) CompareAndSwap(ctx context.Context, bucket *RateLimitBucket) (bool, error) {
+	result := r.db.WithContext(ctx).
+		Model(&RateLimitBucket{}).
+		Where("id = ? AND version = ?", bucket.ID, bucket.Version).
+		Updates(map[string]any{
+			"tokens":      bucket.Tokens,
+			"refilled_at": bucket.RefilledAt,
+			"expires_at":  bucket.ExpiresAt,
+			"version":     bucket.Version + 1,
+		})
+	if result.Error != nil {
+		return false, fmt.Errorf("failed to update rate limit bucket: %w", result.Error)
+	}
+	return result.RowsAffected == 1, nil
+}
+
+// DeleteExpired removes buckets that have been full since before the given
+// unix-nanosecond timestamp.
+func (r *RateLimitRepository) DeleteExpired(ctx context.Context, before int64) (int64, error) {
+	result := r.db.WithContext(ctx).Where("expires_at < ?", before).Delete(&RateLimitBucket{})
+	if result.Error != nil {
+		return 0, fmt.Errorf("failed to prune rate limit buckets: %w", result.Error)
+	}
+	return result.RowsAffected, nil
+}
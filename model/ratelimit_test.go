@@ -0,0 +1,57 @@
+package model
+
+import (
+	"context"
+	"testing"
+
+	"myapp/testutil"
+)
+
+func TestRateLimitRepository(t *testing.T) {
+	ctx := context.Background()
+	repo := NewRateLimitRepository(testutil.NewTestDB(t, &RateLimitBucket{}))
+
+	if b, err := repo.Get(ctx, "login-ip:1.2.3.4"); err != nil || b != nil {
+		t.Fatalf("expected no bucket, got %v, %v", b, err)
+	}
+
+	bucket := &RateLimitBucket{ID: "login-ip:1.2.3.4", Tokens: 4, RefilledAt: 100, ExpiresAt: 200}
+	if ok, err := repo.Insert(ctx, bucket); err != nil || !ok {
+		t.Fatalf("Insert failed: %v, %v", ok, err)
+	}
+
+	t.Run("insert loses to existing row", func(t *testing.T) {
+		ok, err := repo.Insert(ctx, &RateLimitBucket{ID: "login-ip:1.2.3.4", Tokens: 9})
+		if err != nil || ok {
+			t.Errorf("expected conflicting insert to report false, got %v, %v", ok, err)
+		}
+	})
+
+	t.Run("compare and swap", func(t *testing.T) {
+		stale, _ := repo.Get(ctx, "login-ip:1.2.3.4")
+		fresh, _ := repo.Get(ctx, "login-ip:1.2.3.4")
+
+		fresh.Tokens = 3
+		if ok, err := repo.CompareAndSwap(ctx, fresh); err != nil || !ok {
+			t.Fatalf("CompareAndSwap failed: %v, %v", ok, err)
+		}
+		stale.Tokens = 1
+		if ok, err := repo.CompareAndSwap(ctx, stale); err != nil || ok {
+			t.Errorf("expected stale swap to report false, got %v, %v", ok, err)
+		}
+
+		got, _ := repo.Get(ctx, "login-ip:1.2.3.4")
+		if got.Tokens != 3 || got.Version != 1 {
+			t.Errorf("got tokens %v version %d, want 3 and 1", got.Tokens, got.Version)
+		}
+	})
+
+	t.Run("delete expired", func(t *testing.T) {
+		if n, err := repo.DeleteExpired(ctx, 150); err != nil || n != 0 {
+			t.Errorf("expected nothing pruned, got %d, %v", n, err)
+		}
+		if n, err := repo.DeleteExpired(ctx, 250); err != nil || n != 1 {
+			t.Errorf("expected one pruned, got %d, %v", n, err)
+		}
+	})
+}
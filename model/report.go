@@ -0,0 +1,107 @@
+package model
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"myapp/util"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// Report statuses. A report is open until an admin resolves it with one
+// of the other statuses, named after the action taken.
+const (
+	ReportOpen      = "open"
+	ReportDismissed = "dismissed"
+	ReportWarned    = "warned"
+	ReportSuspended = "suspended"
+)
+
+// Report is one user's complaint about another, waiting in or resolved
+// from the moderation queue.
+type Report struct {
+	util.Entity
+	ReporterID uuid.UUID `gorm:"index;not null"`
+	ReportedID uuid.UUID `gorm:"index;not null"`
+	Reporter   User      `gorm:"foreignKey:ReporterID;constraint:OnDelete:CASCADE"`
+	Reported   User      `gorm:"foreignKey:ReportedID;constraint:OnDelete:CASCADE"`
+	Reason     string    `gorm:"not null"`
+	// Evidence is what the reporter wrote to back the report up, such as
+	// links or a description.
+	Evidence string `gorm:"not null"`
+	Status   string `gorm:"index;not null"`
+	// ResolvedByID is the admin who resolved the report.
+	ResolvedByID *uuid.UUID
+	ResolvedAt   *time.Time
+}
+
+type ReportRepository struct {
+	db *gorm.DB
+}
+
+func NewReportRepository(db *gorm.DB) *ReportRepository {
+	return &ReportRepository{db: db}
+}
+
+func (r *ReportRepository) Create(ctx context.Context, report *Report) error {
+	if err := r.db.WithContext(ctx).Omit("Reporter", "Reported").Create(report).Error; err != nil {
+		return fmt.Errorf("failed to create report: %w", err)
+	}
+	return nil
+}
+
+// GetByID returns the report with its reporter and reported user loaded,
+// including users that have since been deleted.
+func (r *ReportRepository) GetByID(ctx context.Context, id string) (*Report, error) {
+	var report Report
+	err := r.db.WithContext(ctx).
+		Preload("Reporter").Preload("Reported").
+		Where("id = ? AND deleted_at is null", id).
+		First(&report).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get report: %w", err)
+	}
+	return &report, nil
+}
+
+// ListOpen returns up to limit open reports, oldest first, with their users
+// loaded.
+func (r *ReportRepository) ListOpen(ctx context.Context, limit int) ([]Report, error) {
+	var reports []Report
+	err := r.db.WithContext(ctx).
+		Preload("Reporter").Preload("Reported").
+		Where("status = ? AND deleted_at is null", ReportOpen).
+		Order("created_at").Order("id").
+		Limit(limit).
+		Find(&reports).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to list reports: %w", err)
+	}
+	return reports, nil
+}
+
+// Resolve records that adminID resolved the open report with status.
+// It reports ErrNotFound if the report is not open any more, so two
+// admins cannot both act on it.
+func (r *ReportRepository) Resolve(ctx context.Context, report *Report, status string, adminID uuid.UUID) error {
+	now := time.Now()
+	res := r.db.WithContext(ctx).
+		Model(&Report{}).
+		Where("id = ? AND status = ?", report.ID, ReportOpen).
+		Updates(map[string]any{"status": status, "resolved_by_id": adminID, "resolved_at": now})
+	if res.Error != nil {
+		return fmt.Errorf("failed to resolve report: %w", res.Error)
+	}
+	if res.RowsAffected == 0 {
+		return ErrNotFound
+	}
+	report.Status, report.ResolvedByID, report.ResolvedAt = status, &adminID, &now
+	return nil
+}
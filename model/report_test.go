@@ -0,0 +1,73 @@
+package model
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"myapp/testutil"
+)
+
+func TestReportRepository(t *testing.T) {
+	ctx := context.Background()
+	db := testutil.NewTestDB(t, &User{}, &Report{})
+	users := NewUserRepository(db)
+	repo := NewReportRepository(db)
+
+	newUser := func(handle string) *User {
+		u := &User{Email: handle + "@example.com", PasswordHash: "x", Name: handle}
+		if err := users.Create(ctx, u); err != nil {
+			t.Fatalf("Create user failed: %v", err)
+		}
+		return u
+	}
+	alice, bob, admin := newUser("alice"), newUser("bob"), newUser("admin")
+
+	var reports []*Report
+	for _, reason := range []string{"spam", "harassment"} {
+		r := &Report{ReporterID: alice.ID, ReportedID: bob.ID, Reason: reason, Evidence: "see posts", Status: ReportOpen}
+		if err := repo.Create(ctx, r); err != nil {
+			t.Fatalf("Create failed: %v", err)
+		}
+		reports = append(reports, r)
+	}
+
+	t.Run("get loads both users", func(t *testing.T) {
+		got, err := repo.GetByID(ctx, reports[0].ID.String())
+		if err != nil {
+			t.Fatalf("GetByID failed: %v", err)
+		}
+		if got.Reporter.Name != "alice" || got.Reported.Name != "bob" || got.Reason != "spam" {
+			t.Errorf("unexpected report %+v", got)
+		}
+		if _, err := repo.GetByID(ctx, "00000000-0000-0000-0000-000000000000"); !errors.Is(err, ErrNotFound) {
+			t.Errorf("expected ErrNotFound, got %v", err)
+		}
+	})
+
+	t.Run("resolve once", func(t *testing.T) {
+		if err := repo.Resolve(ctx, reports[0], ReportWarned, admin.ID); err != nil {
+			t.Fatalf("Resolve failed: %v", err)
+		}
+		if reports[0].Status != ReportWarned || reports[0].ResolvedAt == nil || *reports[0].ResolvedByID != admin.ID {
+			t.Errorf("expected the report to be updated, got %+v", reports[0])
+		}
+		if err := repo.Resolve(ctx, reports[0], ReportDismissed, admin.ID); !errors.Is(err, ErrNotFound) {
+			t.Errorf("expected ErrNotFound for a resolved report, got %v", err)
+		}
+		got, _ := repo.GetByID(ctx, reports[0].ID.String())
+		if got.Status != ReportWarned {
+			t.Errorf("expected the first resolution to stand, got %q", got.Status)
+		}
+	})
+
+	t.Run("list open reports only", func(t *testing.T) {
+		open, err := repo.ListOpen(ctx, 10)
+		if err != nil {
+			t.Fatalf("ListOpen failed: %v", err)
+		}
+		if len(open) != 1 || open[0].ID != reports[1].ID || open[0].Reported.Name != "bob" {
+			t.Errorf("expected the open report with its users, got %+v", open)
+		}
+	})
+}
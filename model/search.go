@@ -0,0 +1,226 @@
+package model
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"myapp/util"
+
+	"gorm.io/gorm"
+)
+
+// Sort orders for UserRepository.Search.
+const (
+	SortHandle    = "handle"
+	SortNewest    = "newest"
+	SortRelevance = "relevance"
+)
+
+// defaultSearchLimit is the page size of searches that do not set one.
+const defaultSearchLimit = 20
+
+// minTrigramTerm is the shortest term the trigram index can match. Queries
+// with shorter terms are answered with LIKE instead.
+const minTrigramTerm = 3
+
+var ErrInvalidCursor = util.BadRequest("error.invalidRequest")
+
+// UserSearch selects a page of the user directory. Query matches handles and
+// display names by substring, Country filters by ISO code among users whose
+// country is public, and After is the Next cursor of the previous page.
+// Relevance only applies with a Query; without one it sorts by handle. Limit
+// is the page size; zero or less means defaultSearchLimit (20).
+type UserSearch struct {
+	Query   string
+	Country string
+	Sort    string
+	After   string
+	Limit   int
+}
+
+// UserPage is one page of search results. Next is empty on the last page.
+type UserPage struct {
+	Users []User
+	Next  string
+}
+
+// searchRow is a user with the score it was ranked by; lower is better.
+type searchRow struct {
+	User  `gorm:"embedded"`
+	Score float64
+}
+
+// searchCursor holds the sort key of the last row on a page.
+type searchCursor struct {
+	Name      string    `json:"n"`
+	CreatedAt time.Time `json:"c"`
+	Score     float64   `json:"s"`
+	ID        string    `json:"i"`
+}
+
+// Search pages through live users with keyset pagination, so pages stay
+// stable while users sign up. Queries use the users_fts index created by the
+// migrations; databases without it, such as AutoMigrated test databases,
+// fall back to LIKE.
+func (r *UserRepository) Search(ctx context.Context, s UserSearch) (*UserPage, error) {
+	db := r.db.WithContext(ctx)
+	if s.Limit <= 0 {
+		s.Limit = defaultSearchLimit
+	}
+
+	var after *searchCursor
+	if s.After != "" {
+		c, err := decodeCursor(s.After)
+		if err != nil {
+			return nil, err
+		}
+		after = c
+	}
+
+	source, err := r.searchSource(db, s.Query)
+	if err != nil {
+		return nil, err
+	}
+	q := db.Table("(?) AS u", source).Where("u.deleted_at is null AND NOT u.hide_from_search")
+	if s.Country != "" {
+		// Only match countries their owners show to everyone, or the filter
+		// would reveal hidden ones.
+		q = q.Where("u.country = ? AND COALESCE(json_extract(u.visibility, ?), ?) = ?",
+			s.Country, "$."+FieldCountry, VisibilityPublic, VisibilityPublic)
+	}
+
+	sort := s.Sort
+	if sort == SortRelevance && strings.TrimSpace(s.Query) == "" {
+		sort = SortHandle
+	}
+	switch sort {
+	case SortNewest:
+		if after != nil {
+			q = q.Where("(u.created_at, u.id) < (?, ?)", after.CreatedAt, after.ID)
+		}
+		q = q.Order("u.created_at desc").Order("u.id desc")
+	case SortRelevance:
+		if after != nil {
+			q = q.Where("(u.score, u.name, u.id) > (?, ?, ?)", after.Score, after.Name, after.ID)
+		}
+		q = q.Order("u.score").Order("u.name").Order("u.id")
+	default:
+		if after != nil {
+			q = q.Where("(u.name, u.id) > (?, ?)", after.Name, after.ID)
+		}
+		q = q.Order("u.name").Order("u.id")
+	}
+
+	var rows []searchRow
+	if err := q.Limit(s.Limit + 1).Find(&rows).Error; err != nil {
+		return nil, fmt.Errorf("failed to search users: %w", err)
+	}
+
+	page := &UserPage{Users: make([]User, 0, len(rows))}
+	if len(rows) > s.Limit {
+		rows = rows[:s.Limit]
+		last := rows[len(rows)-1]
+		page.Next = encodeCursor(searchCursor{
+			Name:      last.Name,
+			CreatedAt: last.CreatedAt,
+			Score:     last.Score,
+			ID:        last.ID.String(),
+		})
+	}
+	for _, row := range rows {
+		page.Users = append(page.Users, row.User)
+	}
+	return page, nil
+}
+
+// searchSource selects the users matching query with a score column.
+func (r *UserRepository) searchSource(db *gorm.DB, query string) (*gorm.DB, error) {
+	terms := strings.Fields(query)
+	if len(terms) == 0 {
+		return db.Table("users").Select("users.*, 0 AS score"), nil
+	}
+
+	indexed, err := r.hasSearchIndex(db)
+	if err != nil {
+		return nil, err
+	}
+	if indexed && !hasShortTerm(terms) {
+		return db.Table("users_fts").
+			Select("users.*, bm25(users_fts) AS score").
+			Joins("JOIN users ON users.id = users_fts.user_id").
+			Where("users_fts MATCH ?", ftsQuery(terms)), nil
+	}
+
+	// Without the index, handles starting with the first term rank first.
+	q := db.Table("users").
+		Select("users.*, CASE WHEN users.name LIKE ? ESCAPE '\\' THEN 0 ELSE 1 END AS score", likeEscape(terms[0])+"%")
+	for _, term := range terms {
+		pattern := "%" + likeEscape(term) + "%"
+		q = q.Where("(users.name LIKE ? ESCAPE '\\' OR users.display_name LIKE ? ESCAPE '\\')", pattern, pattern)
+	}
+	return q, nil
+}
+
+// hasSearchIndex reports whether the users_fts table exists. The answer is
+// cached once a lookup succeeds.
+func (r *UserRepository) hasSearchIndex(db *gorm.DB) (bool, error) {
+	r.searchMu.Lock()
+	defer r.searchMu.Unlock()
+	if r.searchIndex == nil {
+		var count int64
+		err := db.Raw("SELECT count(*) FROM sqlite_master WHERE type = 'table' AND name = 'users_fts'").
+			Scan(&count).Error
+		if err != nil {
+			return false, fmt.Errorf("failed to look up search index: %w", err)
+		}
+		exists := count > 0
+		r.searchIndex = &exists
+	}
+	return *r.searchIndex, nil
+}
+
+func hasShortTerm(terms []string) bool {
+	for _, term := range terms {
+		if utf8.RuneCountInString(term) < minTrigramTerm {
+			return true
+		}
+	}
+	return false
+}
+
+// ftsQuery quotes each term as an FTS5 string, so every term must appear
+// somewhere in the handle or display name and user input is never parsed as
+// query syntax.
+func ftsQuery(terms []string) string {
+	quoted := make([]string, len(terms))
+	for i, term := range terms {
+		quoted[i] = `"` + strings.ReplaceAll(term, `"`, `""`) + `"`
+	}
+	return strings.Join(quoted, " ")
+}
+
+func likeEscape(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(s)
+}
+
+func encodeCursor(c searchCursor) string {
+	payload, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(payload)
+}
+
+func decodeCursor(s string) (*searchCursor, error) {
+	payload, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+	var c searchCursor
+	if err := json.Unmarshal(payload, &c); err != nil || c.ID == "" {
+		return nil, ErrInvalidCursor
+	}
+	return &c, nil
+}
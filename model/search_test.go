@@ -0,0 +1,215 @@
+package model
+
+import (
+	"context"
+	"errors"
+	"os"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"myapp/testutil"
+
+	"gorm.io/gorm"
+)
+
+// searchMigration creates the users_fts index and its triggers.
+const searchMigration = "../migrations/20261019150100_add_users_search.sql"
+
+func TestSearch(t *testing.T) {
+	t.Run("like", func(t *testing.T) {
+		testSearch(t, testutil.NewTestDB(t, &User{}))
+	})
+	t.Run("fts5", func(t *testing.T) {
+		db := testutil.NewTestDB(t, &User{})
+		sql, err := os.ReadFile(searchMigration)
+		if err != nil {
+			t.Fatalf("failed to read migration: %v", err)
+		}
+		if err := db.Exec(string(sql)).Error; err != nil {
+			if strings.Contains(err.Error(), "no such module: fts5") {
+				t.Skip("SQLite built without FTS5; run with -tags sqlite_fts5")
+			}
+			t.Fatalf("failed to apply migration: %v", err)
+		}
+		testSearch(t, db)
+	})
+}
+
+func testSearch(t *testing.T, db *gorm.DB) {
+	ctx := context.Background()
+	repo := NewUserRepository(db)
+
+	seed := []User{
+		{Name: "alice", DisplayName: "Alice Liddell", Country: "GB"},
+		{Name: "alicia", DisplayName: "Alicia Keys", Country: "US"},
+		{Name: "bob", DisplayName: "Bob Malice", Country: "US"},
+		{Name: "carol", DisplayName: "Carol 100%", Country: "DE"},
+		{Name: "dave_x", Country: "US"},
+		{Name: "gone", DisplayName: "Alice Gone", Country: "GB"},
+		{Name: "hermit", DisplayName: "Alice Hermit", Country: "GB", HideFromSearch: true},
+		{Name: "erin", Country: "US", Visibility: ProfileVisibility{FieldCountry: VisibilityFollowers}},
+	}
+	base := time.Now()
+	for i := range seed {
+		u := &seed[i]
+		u.Email = u.Name + "@example.com"
+		u.PasswordHash = "x"
+		if err := repo.Create(ctx, u); err != nil {
+			t.Fatalf("Create failed: %v", err)
+		}
+		// Spread signups out so newest-first has a defined order.
+		db.Model(u).UpdateColumn("created_at", base.Add(time.Duration(i)*time.Minute))
+	}
+	if err := repo.Delete(ctx, seed[5].ID.String()); err != nil {
+		t.Fatalf("Delete failed: %v", err)
+	}
+
+	handles := func(page *UserPage) []string {
+		names := make([]string, len(page.Users))
+		for i, u := range page.Users {
+			names[i] = u.Name
+		}
+		return names
+	}
+	search := func(s UserSearch) []string {
+		t.Helper()
+		if s.Limit == 0 {
+			s.Limit = 10
+		}
+		page, err := repo.Search(ctx, s)
+		if err != nil {
+			t.Fatalf("Search(%+v) failed: %v", s, err)
+		}
+		return handles(page)
+	}
+
+	t.Run("substring over handle and display name", func(t *testing.T) {
+		got := search(UserSearch{Query: "lic"})
+		if want := []string{"alice", "alicia", "bob"}; !slices.Equal(got, want) {
+			t.Errorf("expected %v, got %v", want, got)
+		}
+	})
+
+	t.Run("every term must match", func(t *testing.T) {
+		if got := search(UserSearch{Query: "alice liddell"}); !slices.Equal(got, []string{"alice"}) {
+			t.Errorf("expected [alice], got %v", got)
+		}
+	})
+
+	t.Run("case insensitive", func(t *testing.T) {
+		if got := search(UserSearch{Query: "CAROL"}); !slices.Equal(got, []string{"carol"}) {
+			t.Errorf("expected [carol], got %v", got)
+		}
+	})
+
+	t.Run("short prefixes", func(t *testing.T) {
+		if got := search(UserSearch{Query: "bo"}); !slices.Equal(got, []string{"bob"}) {
+			t.Errorf("expected [bob], got %v", got)
+		}
+	})
+
+	t.Run("syntax in the query is literal", func(t *testing.T) {
+		for _, q := range []string{`100%`, `"alice`, `alice OR bob`, `x_`} {
+			page, err := repo.Search(ctx, UserSearch{Query: q, Limit: 10})
+			if err != nil {
+				t.Errorf("Search(%q) failed: %v", q, err)
+				continue
+			}
+			for _, u := range page.Users {
+				if q == "100%" && u.Name != "carol" || q == "x_" && u.Name != "dave_x" {
+					t.Errorf("Search(%q) matched %s", q, u.Name)
+				}
+			}
+			if q == "alice OR bob" && len(page.Users) != 0 {
+				t.Errorf("expected OR to be a literal term, got %v", handles(page))
+			}
+		}
+	})
+
+	t.Run("country filter", func(t *testing.T) {
+		if got := search(UserSearch{Country: "US"}); !slices.Equal(got, []string{"alicia", "bob", "dave_x"}) {
+			t.Errorf("expected US users by handle, got %v", got)
+		}
+		if got := search(UserSearch{Query: "alic", Country: "GB"}); !slices.Equal(got, []string{"alice"}) {
+			t.Errorf("expected [alice], got %v", got)
+		}
+	})
+
+	t.Run("country filter skips hidden countries", func(t *testing.T) {
+		if got := search(UserSearch{Country: "US"}); slices.Contains(got, "erin") {
+			t.Errorf("expected erin's hidden country not to match, got %v", got)
+		}
+		if got := search(UserSearch{Query: "erin"}); !slices.Equal(got, []string{"erin"}) {
+			t.Errorf("expected erin without a country filter, got %v", got)
+		}
+	})
+
+	t.Run("users hiding from search are left out", func(t *testing.T) {
+		if got := search(UserSearch{Query: "hermit"}); len(got) != 0 {
+			t.Errorf("expected no results, got %v", got)
+		}
+		if got := search(UserSearch{Country: "GB"}); !slices.Equal(got, []string{"alice"}) {
+			t.Errorf("expected [alice], got %v", got)
+		}
+	})
+
+	t.Run("deleted users are hidden", func(t *testing.T) {
+		if got := search(UserSearch{Query: "gone"}); len(got) != 0 {
+			t.Errorf("expected no results, got %v", got)
+		}
+	})
+
+	t.Run("sorts", func(t *testing.T) {
+		if got := search(UserSearch{Sort: SortNewest}); !slices.Equal(got, []string{"erin", "dave_x", "carol", "bob", "alicia", "alice"}) {
+			t.Errorf("unexpected newest order %v", got)
+		}
+		got := search(UserSearch{Query: "alic", Sort: SortRelevance})
+		if len(got) != 3 || got[2] != "bob" {
+			t.Errorf("expected handle matches before bob, got %v", got)
+		}
+	})
+
+	t.Run("keyset pages cover every user once", func(t *testing.T) {
+		for _, sort := range []string{SortHandle, SortNewest, SortRelevance} {
+			for _, query := range []string{"", "lic"} {
+				var seen []string
+				s := UserSearch{Query: query, Sort: sort, Limit: 2}
+				for {
+					page, err := repo.Search(ctx, s)
+					if err != nil {
+						t.Fatalf("Search failed: %v", err)
+					}
+					seen = append(seen, handles(page)...)
+					if page.Next == "" {
+						break
+					}
+					s.After = page.Next
+				}
+				want := search(UserSearch{Query: query, Sort: sort})
+				if !slices.Equal(seen, want) {
+					t.Errorf("sort %s query %q: paged %v, want %v", sort, query, seen, want)
+				}
+			}
+		}
+	})
+
+	t.Run("unset limit uses the default", func(t *testing.T) {
+		for _, limit := range []int{0, -1} {
+			page, err := repo.Search(ctx, UserSearch{Limit: limit})
+			if err != nil {
+				t.Fatalf("Search(Limit: %d) failed: %v", limit, err)
+			}
+			if len(page.Users) == 0 || len(page.Users) > defaultSearchLimit {
+				t.Errorf("Limit %d: got %d users", limit, len(page.Users))
+			}
+		}
+	})
+
+	t.Run("invalid cursor", func(t *testing.T) {
+		if _, err := repo.Search(ctx, UserSearch{After: "not a cursor", Limit: 10}); !errors.Is(err, ErrInvalidCursor) {
+			t.Errorf("expected ErrInvalidCursor, got %v", err)
+		}
+	})
+}
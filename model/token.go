@@ -0,0 +1,112 @@
+package model
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"myapp/util"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// PersonalAccessToken lets API clients act as a user with limited scopes.
+// Only a SHA-256 hash of the secret is stored; Prefix identifies the token
+// in listings.
+type PersonalAccessToken struct {
+	util.Entity
+	UserID    uuid.UUID `gorm:"index;not null"`
+	Name      string    `gorm:"not null"`
+	TokenHash string    `gorm:"uniqueIndex;not null"`
+	Prefix    string    `gorm:"not null"`
+	// Scopes is a space-separated list, e.g. "profile:read profile:write".
+	Scopes     string `gorm:"not null"`
+	ExpiresAt  *time.Time
+	LastUsedAt *time.Time
+	RevokedAt  *time.Time
+}
+
+func (t *PersonalAccessToken) HasScope(scope string) bool {
+	for _, s := range strings.Fields(t.Scopes) {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
+
+// Active reports whether the token is neither revoked nor expired at now.
+func (t *PersonalAccessToken) Active(now time.Time) bool {
+	return t.RevokedAt == nil && (t.ExpiresAt == nil || now.Before(*t.ExpiresAt))
+}
+
+type TokenRepository struct {
+	db *gorm.DB
+}
+
+func NewTokenRepository(db *gorm.DB) *TokenRepository {
+	return &TokenRepository{db: db}
+}
+
+func (r *TokenRepository) Create(ctx context.Context, token *PersonalAccessToken) error {
+	return r.db.WithContext(ctx).Create(token).Error
+}
+
+// ListByUser returns the user's tokens, newest first, including revoked ones.
+func (r *TokenRepository) ListByUser(ctx context.Context, userID string) ([]PersonalAccessToken, error) {
+	var tokens []PersonalAccessToken
+	err := r.db.WithContext(ctx).
+		Where("user_id = ?", userID).
+		Where("deleted_at is null").
+		Order("created_at desc").
+		Find(&tokens).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to list tokens: %w", err)
+	}
+	return tokens, nil
+}
+
+func (r *TokenRepository) GetByHash(ctx context.Context, hash string) (*PersonalAccessToken, error) {
+	var token PersonalAccessToken
+	err := r.db.WithContext(ctx).
+		Where("token_hash = ?", hash).
+		Where("deleted_at is null").
+		First(&token).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrNotFound
+		}
+		return nil, fmt.Errorf("failed to get token: %w", err)
+	}
+	return &token, nil
+}
+
+// Revoke marks one of the user's active tokens as revoked.
+func (r *TokenRepository) Revoke(ctx context.Context, userID, id string, at time.Time) error {
+	result := r.db.WithContext(ctx).
+		Model(&PersonalAccessToken{}).
+		Where("id = ? AND user_id = ?", id, userID).
+		Where("revoked_at is null AND deleted_at is null").
+		Update("revoked_at", at)
+	if result.Error != nil {
+		return fmt.Errorf("failed to revoke token: %w", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func (r *TokenRepository) TouchLastUsed(ctx context.Context, id string, at time.Time) error {
+	err := r.db.WithContext(ctx).
+		Model(&PersonalAccessToken{}).
+		Where("id = ?", id).
+		UpdateColumn("last_used_at", at).Error
+	if err != nil {
+		return fmt.Errorf("failed to record token use: %w", err)
+	}
+	return nil
+}
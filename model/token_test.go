@@ -0,0 +1,96 @@
+package model
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"myapp/testutil"
+
+	"github.com/google/uuid"
+)
+
+func TestTokenRepository(t *testing.T) {
+	ctx := context.Background()
+	repo := NewTokenRepository(testutil.NewTestDB(t, &PersonalAccessToken{}))
+	owner := uuid.New()
+
+	token := &PersonalAccessToken{UserID: owner, Name: "ci", TokenHash: "hash1", Prefix: "myapp_pat_abc", Scopes: "profile:read"}
+	if err := repo.Create(ctx, token); err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+
+	t.Run("get by hash", func(t *testing.T) {
+		got, err := repo.GetByHash(ctx, "hash1")
+		if err != nil || got.ID != token.ID {
+			t.Fatalf("expected token %s, got %v, %v", token.ID, got, err)
+		}
+		if _, err := repo.GetByHash(ctx, "missing"); !errors.Is(err, ErrNotFound) {
+			t.Errorf("expected ErrNotFound, got %v", err)
+		}
+	})
+
+	t.Run("list by user", func(t *testing.T) {
+		_ = repo.Create(ctx, &PersonalAccessToken{UserID: uuid.New(), Name: "other", TokenHash: "hash2", Prefix: "p", Scopes: "profile:read"})
+		tokens, err := repo.ListByUser(ctx, owner.String())
+		if err != nil || len(tokens) != 1 || tokens[0].Name != "ci" {
+			t.Errorf("expected only the owner's token, got %v, %v", tokens, err)
+		}
+	})
+
+	t.Run("touch last used", func(t *testing.T) {
+		at := time.Now()
+		if err := repo.TouchLastUsed(ctx, token.ID.String(), at); err != nil {
+			t.Fatalf("TouchLastUsed failed: %v", err)
+		}
+		got, _ := repo.GetByHash(ctx, "hash1")
+		if got.LastUsedAt == nil || !got.LastUsedAt.Equal(at) {
+			t.Errorf("expected last used %v, got %v", at, got.LastUsedAt)
+		}
+	})
+
+	t.Run("revoke", func(t *testing.T) {
+		if err := repo.Revoke(ctx, uuid.New().String(), token.ID.String(), time.Now()); !errors.Is(err, ErrNotFound) {
+			t.Errorf("expected ErrNotFound revoking another user's token, got %v", err)
+		}
+		if err := repo.Revoke(ctx, owner.String(), token.ID.String(), time.Now()); err != nil {
+			t.Fatalf("Revoke failed: %v", err)
+		}
+		if err := repo.Revoke(ctx, owner.String(), token.ID.String(), time.Now()); !errors.Is(err, ErrNotFound) {
+			t.Errorf("expected ErrNotFound revoking twice, got %v", err)
+		}
+		got, _ := repo.GetByHash(ctx, "hash1")
+		if got.RevokedAt == nil {
+			t.Error("expected revoked_at to be set")
+		}
+	})
+}
+
+func TestPersonalAccessToken(t *testing.T) {
+	now := time.Now()
+	past, future := now.Add(-time.Minute), now.Add(time.Minute)
+
+	token := PersonalAccessToken{Scopes: "profile:read profile:write"}
+	if !token.HasScope("profile:write") || token.HasScope("profile") {
+		t.Error("HasScope must match whole scopes only")
+	}
+
+	tests := []struct {
+		name   string
+		token  PersonalAccessToken
+		active bool
+	}{
+		{"no expiry", PersonalAccessToken{}, true},
+		{"expires later", PersonalAccessToken{ExpiresAt: &future}, true},
+		{"expired", PersonalAccessToken{ExpiresAt: &past}, false},
+		{"revoked", PersonalAccessToken{RevokedAt: &past}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.token.Active(now); got != tt.active {
+				t.Errorf("Active() = %v, want %v", got, tt.active)
+			}
+		})
+	}
+}
@@ -6,41 +6,127 @@ import (
 	"fmt"
 	"myapp/util"
 	"regexp"
+	"strings"
+	"sync"
+	"time"
 
+	"github.com/google/uuid"
 	"gorm.io/gorm"
 )
 
 var HandleRegex = regexp.MustCompile(`^[a-z0-9][a-z0-9_-]{2,29}$`)
 
-type SocialLinks struct {
-	Instagram string `json:"instagram"`
-	Facebook  string `json:"facebook"`
-	Linkedin  string `json:"linkedin"`
-	X         string `json:"x"`
+// ErrDuplicateEmail and ErrDuplicateHandle are returned when a write breaks
+// the unique index on emails, or on the handles of live users.
+var (
+	ErrDuplicateEmail  = errors.New("email already in use")
+	ErrDuplicateHandle = errors.New("handle already in use")
+)
+
+// NormalizeHandle returns the canonical form of a handle as typed. Handles
+// are case-insensitive and stored in lower case.
+func NormalizeHandle(handle string) string {
+	return strings.ToLower(strings.TrimSpace(handle))
+}
+
+// Link is one entry in a profile's list of links. Type names the network,
+// or is "website" or "other"; Label, if set, is shown instead of the type.
+type Link struct {
+	Type  string `json:"type"`
+	URL   string `json:"url"`
+	Label string `json:"label,omitempty"`
+}
+
+// Visibility says who may see a profile field.
+type Visibility string
+
+const (
+	VisibilityPublic    Visibility = "public"
+	VisibilityFollowers Visibility = "followers"
+	VisibilityPrivate   Visibility = "private"
+)
+
+// Fields with their own visibility setting, as keys of ProfileVisibility.
+const (
+	FieldEmail    = "email"
+	FieldBio      = "bio"
+	FieldPronouns = "pronouns"
+	FieldLocation = "location"
+	FieldCountry  = "country"
+	FieldLinks    = "links"
+)
+
+// ProfileVisibility maps profile fields to who may see them. Fields without
+// an entry have their default: the email is private, the rest are public.
+type ProfileVisibility map[string]Visibility
+
+// Of returns the visibility of field.
+func (v ProfileVisibility) Of(field string) Visibility {
+	if vis, ok := v[field]; ok {
+		return vis
+	}
+	if field == FieldEmail {
+		return VisibilityPrivate
+	}
+	return VisibilityPublic
 }
 
+// maxEmailMatches bounds ListByEmail, so a login never checks a password
+// against more than a handful of hashes.
+const maxEmailMatches = 5
+
+// User is an account. EmailNormalized is Email as compared for uniqueness
+// and login. Every account has one; the column is only nullable because it
+// was added to an existing table.
 type User struct {
 	util.Entity
-	Email        string      `json:"email"        gorm:"uniqueIndex;not null"`
-	PasswordHash string      `json:"-"            gorm:"column:password_hash;not null"`
-	Name         string      `json:"name"`
-	DisplayName  string      `json:"display_name"`
-	Bio          string      `json:"bio"`
-	Country      string      `json:"country"`
-	SocialLinks  SocialLinks `json:"social_links" gorm:"serializer:json"`
-	AvatarURL    string      `json:"avatar_url"`
+	Email           string  `json:"email"        gorm:"uniqueIndex;not null"`
+	EmailNormalized *string `json:"-"            gorm:"uniqueIndex"`
+	PasswordHash    string  `json:"-"            gorm:"column:password_hash;not null"`
+	Name            string  `json:"name"         gorm:"uniqueIndex:idx_users_name,where:deleted_at IS NULL"`
+	DisplayName     string  `json:"display_name"`
+	Bio             string  `json:"bio"`
+	Pronouns        string  `json:"pronouns"`
+	Location        string  `json:"location"`
+	Country         string  `json:"country"      gorm:"index"`
+	Links           []Link  `json:"links"        gorm:"serializer:json"`
+	AvatarURL       string  `json:"avatar_url"`
+	// Visibility limits who sees each field. HideFromSearch keeps the
+	// profile out of the directory and asks search engines not to index it.
+	Visibility     ProfileVisibility `json:"visibility"       gorm:"serializer:json"`
+	HideFromSearch bool              `json:"hide_from_search" gorm:"not null;default:false"`
+	// Warnings counts the moderation warnings the user received. A suspended
+	// user cannot sign in.
+	Warnings    int        `json:"-" gorm:"not null;default:0"`
+	SuspendedAt *time.Time `json:"-"`
+}
+
+// Suspended reports whether an admin suspended the account.
+func (u *User) Suspended() bool {
+	return u.SuspendedAt != nil
 }
 
 type UserRepository struct {
 	db *gorm.DB
+
+	searchMu    sync.Mutex
+	searchIndex *bool // whether users_fts exists, once known
 }
 
 func NewUserRepository(db *gorm.DB) *UserRepository {
 	return &UserRepository{db: db}
 }
 
+// Transaction runs fn with a repository bound to one transaction, which is
+// committed if fn returns nil and rolled back otherwise.
+func (r *UserRepository) Transaction(ctx context.Context, fn func(repo *UserRepository) error) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		return fn(&UserRepository{db: tx})
+	})
+}
+
 func (r *UserRepository) Create(ctx context.Context, user *User) error {
-	return r.db.WithContext(ctx).Create(user).Error
+	return duplicateUser(r.db.WithContext(ctx).Create(user).Error)
 }
 
 func (r *UserRepository) GetByID(ctx context.Context, id string) (*User, error) {
@@ -52,7 +138,7 @@ func (r *UserRepository) GetByID(ctx context.Context, id string) (*User, error)
 
 	if err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
-			return nil, fmt.Errorf("user not found")
+			return nil, ErrNotFound
 		}
 		return nil, fmt.Errorf("failed to get user by id: %w", err)
 	}
@@ -68,7 +154,7 @@ func (r *UserRepository) GetByEmail(ctx context.Context, email string) (*User, e
 
 	if err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
-			return nil, fmt.Errorf("user not found")
+			return nil, ErrNotFound
 		}
 		return nil, fmt.Errorf("failed to get user by email: %w", err)
 	}
@@ -84,15 +170,56 @@ func (r *UserRepository) GetByHandle(ctx context.Context, handle string) (*User,
 
 	if err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
-			return nil, fmt.Errorf("user not found")
+			return nil, ErrNotFound
 		}
 		return nil, fmt.Errorf("failed to get user by handle: %w", err)
 	}
 	return &user, nil
 }
 
+// Update saves user. The moderation fields are left out: only AddWarning
+// and Suspend write them, so saving a user loaded before a warning or a
+// suspension cannot undo it.
 func (r *UserRepository) Update(ctx context.Context, user *User) error {
-	return r.db.WithContext(ctx).Save(user).Error
+	return duplicateUser(r.db.WithContext(ctx).Omit("warnings", "suspended_at").Save(user).Error)
+}
+
+// AddWarning counts a moderation warning against the live user with id. The
+// count is incremented in the database, so concurrent warnings all count.
+func (r *UserRepository) AddWarning(ctx context.Context, id uuid.UUID) error {
+	err := r.db.WithContext(ctx).
+		Model(&User{}).
+		Where("id = ? AND deleted_at is null", id).
+		UpdateColumn("warnings", gorm.Expr("warnings + 1")).Error
+	if err != nil {
+		return fmt.Errorf("failed to warn user: %w", err)
+	}
+	return nil
+}
+
+// Suspend suspends the live user with id as of at. Users already suspended
+// keep their original suspension time.
+func (r *UserRepository) Suspend(ctx context.Context, id uuid.UUID, at time.Time) error {
+	err := r.db.WithContext(ctx).
+		Model(&User{}).
+		Where("id = ? AND deleted_at is null AND suspended_at is null", id).
+		UpdateColumn("suspended_at", at).Error
+	if err != nil {
+		return fmt.Errorf("failed to suspend user: %w", err)
+	}
+	return nil
+}
+
+// duplicateUser maps unique index violations on users to their sentinel
+// errors and returns any other error unchanged.
+func duplicateUser(err error) error {
+	switch {
+	case isUniqueViolation(err, "users.email"), isUniqueViolation(err, "users.email_normalized"):
+		return ErrDuplicateEmail
+	case isUniqueViolation(err, "users.name"):
+		return ErrDuplicateHandle
+	}
+	return err
 }
 
 func (r *UserRepository) Delete(ctx context.Context, id string) error {
@@ -106,17 +233,18 @@ func (r *UserRepository) Delete(ctx context.Context, id string) error {
 		return fmt.Errorf("failed to soft-delete user: %w", result.Error)
 	}
 	if result.RowsAffected == 0 {
-		return fmt.Errorf("user not found")
+		return ErrNotFound
 	}
 	return nil
 }
 
-// ExistsByEmail – useful for registration checks
-func (r *UserRepository) ExistsByEmail(ctx context.Context, email string) (bool, error) {
+// ExistsByNormalizedEmail reports whether a live user has the normalized
+// email.
+func (r *UserRepository) ExistsByNormalizedEmail(ctx context.Context, normalized string) (bool, error) {
 	var count int64
 	err := r.db.WithContext(ctx).
 		Model(&User{}).
-		Where("email = ?", email).
+		Where("email_normalized = ?", normalized).
 		Where("deleted_at is null").
 		Count(&count).Error
 	if err != nil {
@@ -124,3 +252,46 @@ func (r *UserRepository) ExistsByEmail(ctx context.Context, email string) (bool,
 	}
 	return count > 0, nil
 }
+
+// ListByEmail returns the live users an email may log in as: the holder of
+// normalized, and accounts whose stored email matches email ignoring ASCII
+// case. Oldest first.
+func (r *UserRepository) ListByEmail(ctx context.Context, normalized, email string) ([]User, error) {
+	var users []User
+	err := r.db.WithContext(ctx).
+		Where("email_normalized = ? OR lower(email) = lower(?)", normalized, email).
+		Where("deleted_at is null").
+		Order("created_at").
+		Limit(maxEmailMatches).
+		Find(&users).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to list users by email: %w", err)
+	}
+	return users, nil
+}
+
+// ListEmails returns up to limit users, live or deleted, with IDs after
+// after in ID order. Only their IDs and emails are loaded.
+func (r *UserRepository) ListEmails(ctx context.Context, after uuid.UUID, limit int) ([]User, error) {
+	var users []User
+	err := r.db.WithContext(ctx).
+		Select("id", "email", "email_normalized").
+		Where("id > ?", after).
+		Order("id").
+		Limit(limit).
+		Find(&users).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to list user emails: %w", err)
+	}
+	return users, nil
+}
+
+// SetNormalizedEmail stores normalized as the normalized email of the user
+// with id, live or deleted. It is ErrDuplicateEmail if another user has it.
+func (r *UserRepository) SetNormalizedEmail(ctx context.Context, id uuid.UUID, normalized string) error {
+	err := r.db.WithContext(ctx).
+		Model(&User{}).
+		Where("id = ?", id).
+		UpdateColumn("email_normalized", normalized).Error
+	return duplicateUser(err)
+}
@@ -2,10 +2,13 @@ package model
 
 import (
 	"context"
+	"errors"
 	"testing"
+	"time"
 
 	"myapp/testutil"
 
+	"github.com/google/uuid"
 	"gorm.io/gorm"
 )
 
@@ -33,6 +36,117 @@ func TestCreate(t *testing.T) {
 	}
 }
 
+func TestUniqueHandle(t *testing.T) {
+	ctx := context.Background()
+	repo := NewUserRepository(newTestDB(t))
+	first := newTestUser()
+	_ = repo.Create(ctx, first)
+
+	t.Run("create rejects a live duplicate", func(t *testing.T) {
+		dup := &User{Email: "dup@example.com", PasswordHash: "hash", Name: first.Name}
+		if err := repo.Create(ctx, dup); !errors.Is(err, ErrDuplicateHandle) {
+			t.Errorf("expected ErrDuplicateHandle, got %v", err)
+		}
+	})
+
+	t.Run("update rejects a live duplicate", func(t *testing.T) {
+		other := &User{Email: "other@example.com", PasswordHash: "hash", Name: "otheruser"}
+		_ = repo.Create(ctx, other)
+		other.Name = first.Name
+		if err := repo.Update(ctx, other); !errors.Is(err, ErrDuplicateHandle) {
+			t.Errorf("expected ErrDuplicateHandle, got %v", err)
+		}
+	})
+
+	t.Run("deleted users free their handle", func(t *testing.T) {
+		_ = repo.Delete(ctx, first.ID.String())
+		again := &User{Email: "again@example.com", PasswordHash: "hash", Name: first.Name}
+		if err := repo.Create(ctx, again); err != nil {
+			t.Errorf("expected the handle to be free, got %v", err)
+		}
+	})
+}
+
+func TestUniqueEmail(t *testing.T) {
+	ctx := context.Background()
+	repo := NewUserRepository(newTestDB(t))
+	first := newTestUser()
+	_ = repo.Create(ctx, first)
+
+	dup := &User{Email: first.Email, PasswordHash: "hash", Name: "otheruser"}
+	if err := repo.Create(ctx, dup); !errors.Is(err, ErrDuplicateEmail) {
+		t.Errorf("expected ErrDuplicateEmail, got %v", err)
+	}
+}
+
+func TestEmailLookups(t *testing.T) {
+	ctx := context.Background()
+	repo := NewUserRepository(newTestDB(t))
+	normalized := "bob@example.com"
+	holder := &User{Email: "Bob@Example.com", EmailNormalized: &normalized, PasswordHash: "hash", Name: "holder"}
+	legacy := &User{Email: "BOB@example.com", PasswordHash: "hash", Name: "legacy"}
+	other := &User{Email: "eve@example.com", PasswordHash: "hash", Name: "other"}
+	for _, u := range []*User{holder, legacy, other} {
+		if err := repo.Create(ctx, u); err != nil {
+			t.Fatalf("Create failed: %v", err)
+		}
+	}
+
+	t.Run("exists by normalized email", func(t *testing.T) {
+		if ok, _ := repo.ExistsByNormalizedEmail(ctx, normalized); !ok {
+			t.Error("expected the normalized email to exist")
+		}
+		if ok, _ := repo.ExistsByNormalizedEmail(ctx, "eve@example.com"); ok {
+			t.Error("expected users without a normalized email not to count")
+		}
+	})
+
+	t.Run("list by email", func(t *testing.T) {
+		users, err := repo.ListByEmail(ctx, normalized, "bob@example.com")
+		if err != nil {
+			t.Fatalf("ListByEmail failed: %v", err)
+		}
+		if len(users) != 2 || users[0].ID != holder.ID || users[1].ID != legacy.ID {
+			t.Errorf("expected holder then legacy, got %+v", users)
+		}
+	})
+
+	t.Run("normalized email is unique", func(t *testing.T) {
+		dup := &User{Email: "bob@example.com", EmailNormalized: &normalized, PasswordHash: "hash", Name: "dup"}
+		if err := repo.Create(ctx, dup); !errors.Is(err, ErrDuplicateEmail) {
+			t.Errorf("expected ErrDuplicateEmail, got %v", err)
+		}
+	})
+}
+
+func TestTransaction(t *testing.T) {
+	ctx := context.Background()
+	repo := NewUserRepository(newTestDB(t))
+
+	errAbort := errors.New("abort")
+	err := repo.Transaction(ctx, func(tx *UserRepository) error {
+		if err := tx.Create(ctx, newTestUser()); err != nil {
+			return err
+		}
+		return errAbort
+	})
+	if !errors.Is(err, errAbort) {
+		t.Fatalf("expected fn's error, got %v", err)
+	}
+	if _, err := repo.GetByHandle(ctx, "testuser"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("expected the insert to be rolled back, got %v", err)
+	}
+
+	if err := repo.Transaction(ctx, func(tx *UserRepository) error {
+		return tx.Create(ctx, newTestUser())
+	}); err != nil {
+		t.Fatalf("Transaction failed: %v", err)
+	}
+	if _, err := repo.GetByHandle(ctx, "testuser"); err != nil {
+		t.Errorf("expected the insert to be committed, got %v", err)
+	}
+}
+
 func TestGetByID(t *testing.T) {
 	repo := NewUserRepository(newTestDB(t))
 	user := newTestUser()
@@ -50,8 +164,8 @@ func TestGetByID(t *testing.T) {
 
 	t.Run("not found", func(t *testing.T) {
 		_, err := repo.GetByID(context.Background(), "00000000-0000-0000-0000-000000000000")
-		if err == nil {
-			t.Error("expected error for missing ID, got nil")
+		if !errors.Is(err, ErrNotFound) {
+			t.Errorf("expected ErrNotFound for missing ID, got %v", err)
 		}
 	})
 }
@@ -73,8 +187,8 @@ func TestGetByEmail(t *testing.T) {
 
 	t.Run("not found", func(t *testing.T) {
 		_, err := repo.GetByEmail(context.Background(), "nobody@example.com")
-		if err == nil {
-			t.Error("expected error for missing email, got nil")
+		if !errors.Is(err, ErrNotFound) {
+			t.Errorf("expected ErrNotFound for missing email, got %v", err)
 		}
 	})
 }
@@ -96,12 +210,30 @@ func TestGetByHandle(t *testing.T) {
 
 	t.Run("not found", func(t *testing.T) {
 		_, err := repo.GetByHandle(context.Background(), "nobody")
-		if err == nil {
-			t.Error("expected error for missing handle, got nil")
+		if !errors.Is(err, ErrNotFound) {
+			t.Errorf("expected ErrNotFound for missing handle, got %v", err)
 		}
 	})
 }
 
+func TestProfileVisibility(t *testing.T) {
+	var unset ProfileVisibility
+	if unset.Of(FieldEmail) != VisibilityPrivate || unset.Of(FieldBio) != VisibilityPublic {
+		t.Errorf("unexpected defaults: email %q, bio %q", unset.Of(FieldEmail), unset.Of(FieldBio))
+	}
+
+	repo := NewUserRepository(newTestDB(t))
+	user := newTestUser()
+	user.Visibility = ProfileVisibility{FieldEmail: VisibilityFollowers, FieldLinks: VisibilityPrivate}
+	if err := repo.Create(context.Background(), user); err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+	got, _ := repo.GetByID(context.Background(), user.ID.String())
+	if got.Visibility.Of(FieldEmail) != VisibilityFollowers || got.Visibility.Of(FieldLinks) != VisibilityPrivate || got.Visibility.Of(FieldBio) != VisibilityPublic {
+		t.Errorf("visibility did not round-trip: %v", got.Visibility)
+	}
+}
+
 func TestUpdate(t *testing.T) {
 	repo := NewUserRepository(newTestDB(t))
 	user := newTestUser()
@@ -142,45 +274,88 @@ func TestDelete(t *testing.T) {
 
 	t.Run("not found", func(t *testing.T) {
 		err := repo.Delete(context.Background(), "00000000-0000-0000-0000-000000000000")
-		if err == nil {
-			t.Error("expected error for missing ID, got nil")
+		if !errors.Is(err, ErrNotFound) {
+			t.Errorf("expected ErrNotFound for missing ID, got %v", err)
 		}
 	})
 }
 
-func TestExistsByEmail(t *testing.T) {
+func TestNormalizedEmailBackfill(t *testing.T) {
+	ctx := context.Background()
 	repo := NewUserRepository(newTestDB(t))
-	user := newTestUser()
-	_ = repo.Create(context.Background(), user)
-
-	t.Run("exists", func(t *testing.T) {
-		ok, err := repo.ExistsByEmail(context.Background(), user.Email)
-		if err != nil {
-			t.Fatalf("ExistsByEmail failed: %v", err)
+	for _, name := range []string{"alice", "bob", "carol"} {
+		normalized := name + "@example.com"
+		u := &User{Email: normalized, EmailNormalized: &normalized, PasswordHash: "x", Name: name}
+		if err := repo.Create(ctx, u); err != nil {
+			t.Fatalf("Create failed: %v", err)
 		}
-		if !ok {
-			t.Error("expected true for existing email, got false")
+		if name == "carol" {
+			_ = repo.Delete(ctx, u.ID.String())
 		}
-	})
+	}
 
-	t.Run("not exists", func(t *testing.T) {
-		ok, err := repo.ExistsByEmail(context.Background(), "nobody@example.com")
+	var all []User
+	for after := uuid.Nil; ; {
+		page, err := repo.ListEmails(ctx, after, 2)
 		if err != nil {
-			t.Fatalf("ExistsByEmail failed: %v", err)
+			t.Fatalf("ListEmails failed: %v", err)
 		}
-		if ok {
-			t.Error("expected false for missing email, got true")
+		if len(page) == 0 {
+			break
 		}
-	})
+		all = append(all, page...)
+		after = page[len(page)-1].ID
+	}
+	if len(all) != 3 {
+		t.Fatalf("expected every user, deleted ones too, got %d", len(all))
+	}
 
-	t.Run("not exists after delete", func(t *testing.T) {
-		_ = repo.Delete(context.Background(), user.ID.String())
-		ok, err := repo.ExistsByEmail(context.Background(), user.Email)
-		if err != nil {
-			t.Fatalf("ExistsByEmail failed: %v", err)
-		}
-		if ok {
-			t.Error("expected false for soft-deleted user, got true")
+	if err := repo.SetNormalizedEmail(ctx, all[0].ID, "new@example.com"); err != nil {
+		t.Fatalf("SetNormalizedEmail failed: %v", err)
+	}
+	got, _ := repo.ListEmails(ctx, uuid.Nil, 1)
+	if *got[0].EmailNormalized != "new@example.com" {
+		t.Errorf("expected the new normalized email, got %s", *got[0].EmailNormalized)
+	}
+	if err := repo.SetNormalizedEmail(ctx, all[1].ID, "new@example.com"); !errors.Is(err, ErrDuplicateEmail) {
+		t.Errorf("expected ErrDuplicateEmail, got %v", err)
+	}
+}
+
+func TestModerationUpdates(t *testing.T) {
+	ctx := context.Background()
+	repo := NewUserRepository(newTestDB(t))
+	user := newTestUser()
+	_ = repo.Create(ctx, user)
+	stale, _ := repo.GetByID(ctx, user.ID.String())
+
+	for range 2 {
+		if err := repo.AddWarning(ctx, user.ID); err != nil {
+			t.Fatalf("AddWarning failed: %v", err)
 		}
-	})
+	}
+	suspended := time.Now().Add(-time.Hour)
+	if err := repo.Suspend(ctx, user.ID, suspended); err != nil {
+		t.Fatalf("Suspend failed: %v", err)
+	}
+	if err := repo.Suspend(ctx, user.ID, time.Now()); err != nil {
+		t.Fatalf("Suspend failed: %v", err)
+	}
+
+	// A profile save from before the moderation must not undo it.
+	stale.DisplayName = "Stale"
+	if err := repo.Update(ctx, stale); err != nil {
+		t.Fatalf("Update failed: %v", err)
+	}
+
+	got, _ := repo.GetByID(ctx, user.ID.String())
+	if got.DisplayName != "Stale" {
+		t.Errorf("expected the profile save to apply, got %q", got.DisplayName)
+	}
+	if got.Warnings != 2 {
+		t.Errorf("expected 2 warnings, got %d", got.Warnings)
+	}
+	if got.SuspendedAt == nil || !got.SuspendedAt.Equal(suspended) {
+		t.Errorf("expected the first suspension to stand, got %v", got.SuspendedAt)
+	}
 }
@@ -0,0 +1,89 @@
+package openapi
+
+import (
+	"slices"
+	"testing"
+	"time"
+)
+
+type testLinks struct {
+	Site string `json:"site"`
+}
+
+type testBase struct {
+	ID string `json:"id"`
+}
+
+type testNode struct {
+	testBase
+	Name     string            `json:"name"`
+	Nick     *string           `json:"nick"`
+	Note     string            `json:"note,omitempty"`
+	Secret   string            `json:"-"`
+	Created  time.Time         `json:"created"`
+	Tags     []string          `json:"tags"`
+	Counts   map[string]int    `json:"counts"`
+	Links    testLinks         `json:"links"`
+	Children []*testNode       `json:"children"`
+	Extra    map[string]string `json:"extra,omitempty"`
+	hidden   string
+}
+
+func TestRef(t *testing.T) {
+	doc := New("test", "1.0.0")
+	doc.Ref("Links", testLinks{})
+	ref := doc.Ref("Node", &testNode{})
+	if ref.Ref != "#/components/schemas/Node" {
+		t.Fatalf("unexpected ref %q", ref.Ref)
+	}
+
+	node := doc.Components.Schemas["Node"]
+	wantProps := []string{"id", "name", "nick", "note", "created", "tags", "counts", "links", "children", "extra"}
+	if len(node.Properties) != len(wantProps) {
+		t.Errorf("expected %d properties, got %v", len(wantProps), node.Properties)
+	}
+	for _, name := range wantProps {
+		if node.Properties[name] == nil {
+			t.Errorf("missing property %q", name)
+		}
+	}
+
+	wantRequired := []string{"id", "name", "created", "tags", "counts", "links", "children"}
+	if !slices.Equal(node.Required, wantRequired) {
+		t.Errorf("required = %v, want %v", node.Required, wantRequired)
+	}
+
+	tests := []struct {
+		prop string
+		want Schema
+	}{
+		{"created", Schema{Type: "string", Format: "date-time"}},
+		{"nick", Schema{Type: "string"}},
+		{"links", Schema{Ref: "#/components/schemas/Links"}},
+	}
+	for _, tt := range tests {
+		got := node.Properties[tt.prop]
+		if got.Type != tt.want.Type || got.Format != tt.want.Format || got.Ref != tt.want.Ref {
+			t.Errorf("%s = %+v, want %+v", tt.prop, got, tt.want)
+		}
+	}
+	if items := node.Properties["children"].Items; items == nil || items.Ref != "#/components/schemas/Node" {
+		t.Errorf("expected recursive children to reference Node, got %+v", items)
+	}
+	if ap := node.Properties["counts"].AdditionalProperties; ap == nil || ap.Type != "integer" {
+		t.Errorf("expected integer map values, got %+v", ap)
+	}
+}
+
+func TestAddOperation(t *testing.T) {
+	doc := New("test", "1.0.0")
+	op := &Operation{OperationID: "getThing"}
+	doc.AddOperation("GET", "/things/{id}", op)
+
+	if doc.Operation("get", "/things/{id}") != op {
+		t.Error("expected operation to be found case-insensitively")
+	}
+	if doc.Operation("POST", "/things/{id}") != nil || doc.Operation("GET", "/other") != nil {
+		t.Error("expected nil for undocumented operations")
+	}
+}
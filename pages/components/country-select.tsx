@@ -1,15 +1,18 @@
+import type { ComponentProps } from "react";
 import { COUNTRIES } from "../lib/countries";
 import { Select } from "../ui/select";
 
-interface CountrySelectProps {
+interface CountrySelectProps extends Omit<ComponentProps<"select">, "value" | "defaultValue"> {
   name: string;
   value?: string;
+  // emptyLabel names the "no country" option.
+  emptyLabel?: string;
 }
 
-export function CountrySelect({ name, value }: CountrySelectProps) {
+export function CountrySelect({ name, value, emptyLabel = "—", ...props }: CountrySelectProps) {
   return (
-    <Select name={name} defaultValue={value ?? ""}>
-      <option value="">—</option>
+    <Select name={name} defaultValue={value ?? ""} {...props}>
+      <option value="">{emptyLabel}</option>
       {COUNTRIES.map((c) => (
         <option key={c.code} value={c.code}>
           {c.name}
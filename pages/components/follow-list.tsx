@@ -0,0 +1,52 @@
+import { t } from "../lib/i18n";
+import { buttonClass } from "../ui/button";
+import { UserList, type ListedUser } from "./user-list";
+
+interface FollowListProps {
+  kind: "followers" | "following";
+  profile: { handle: string; displayName: string };
+  users: ListedUser[];
+  page: number;
+  hasNext: boolean;
+  t: Record<string, string>;
+}
+
+export function FollowList({ kind, profile, users, page, hasNext, t: translations }: FollowListProps) {
+  const base = `/user/${profile.handle}/${kind}`;
+
+  return (
+    <div className="container py-12 max-w-2xl mx-auto">
+      <div className="mb-8">
+        <a href={`/user/${profile.handle}`} className="text-sm text-muted-foreground underline-offset-4 hover:underline">
+          {profile.displayName || `@${profile.handle}`}
+        </a>
+        <h1 className="text-2xl font-bold">{t(translations, `follows.${kind}`)}</h1>
+      </div>
+
+      {users.length === 0 ? (
+        <p className="text-muted-foreground">
+          {t(translations, kind === "followers" ? "follows.emptyFollowers" : "follows.emptyFollowing")}
+        </p>
+      ) : (
+        <UserList users={users} />
+      )}
+
+      {(page > 1 || hasNext) && (
+        <nav className="flex justify-between mt-8">
+          {page > 1 ? (
+            <a href={page === 2 ? base : `${base}?page=${page - 1}`} className={buttonClass("outline", "sm")}>
+              {t(translations, "follows.previous")}
+            </a>
+          ) : (
+            <span />
+          )}
+          {hasNext && (
+            <a href={`${base}?page=${page + 1}`} className={buttonClass("outline", "sm")}>
+              {t(translations, "follows.next")}
+            </a>
+          )}
+        </nav>
+      )}
+    </div>
+  );
+}
@@ -0,0 +1,31 @@
+import { Facehash } from "facehash";
+
+export interface ListedUser {
+  handle: string;
+  displayName: string;
+  avatarURL: string;
+}
+
+export function UserList({ users }: { users: ListedUser[] }) {
+  return (
+    <ul className="space-y-3">
+      {users.map((u) => (
+        <li key={u.handle}>
+          <a href={`/user/${u.handle}`} className="flex items-center gap-3 rounded-lg p-2 hover:bg-muted">
+            <div className="w-10 h-10 rounded-full overflow-hidden flex-shrink-0">
+              {u.avatarURL ? (
+                <img src={u.avatarURL} alt={`@${u.handle}`} className="w-full h-full object-cover" />
+              ) : (
+                <Facehash name={u.handle} size={40} />
+              )}
+            </div>
+            <div className="min-w-0">
+              <p className="font-medium truncate">{u.displayName || `@${u.handle}`}</p>
+              {u.displayName && <p className="text-sm text-muted-foreground">@{u.handle}</p>}
+            </div>
+          </a>
+        </li>
+      ))}
+    </ul>
+  );
+}
@@ -0,0 +1,32 @@
+import Layout from "./layout";
+import { ThemeScript } from "./theme-script";
+import { FollowList } from "./components/follow-list";
+import type { ListedUser } from "./components/user-list";
+
+interface FollowersProps {
+  user?: { email: string; handle: string };
+  profile: { handle: string; displayName: string };
+  users: ListedUser[];
+  page: number;
+  hasNext: boolean;
+  locale: string;
+  t: Record<string, string>;
+}
+
+export function Head() {
+  return (
+    <>
+      <ThemeScript />
+      <title>Followers - MyApp</title>
+      <meta name="description" content="People following this user" />
+    </>
+  );
+}
+
+export default function Followers({ user, locale, t: translations, ...list }: FollowersProps) {
+  return (
+    <Layout user={user} locale={locale} t={translations}>
+      <FollowList kind="followers" t={translations} {...list} />
+    </Layout>
+  );
+}
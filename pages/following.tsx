@@ -0,0 +1,32 @@
+import Layout from "./layout";
+import { ThemeScript } from "./theme-script";
+import { FollowList } from "./components/follow-list";
+import type { ListedUser } from "./components/user-list";
+
+interface FollowingProps {
+  user?: { email: string; handle: string };
+  profile: { handle: string; displayName: string };
+  users: ListedUser[];
+  page: number;
+  hasNext: boolean;
+  locale: string;
+  t: Record<string, string>;
+}
+
+export function Head() {
+  return (
+    <>
+      <ThemeScript />
+      <title>Following - MyApp</title>
+      <meta name="description" content="People this user follows" />
+    </>
+  );
+}
+
+export default function Following({ user, locale, t: translations, ...list }: FollowingProps) {
+  return (
+    <Layout user={user} locale={locale} t={translations}>
+      <FollowList kind="following" t={translations} {...list} />
+    </Layout>
+  );
+}
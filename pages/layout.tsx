@@ -23,6 +23,9 @@ export default function Layout({ user, locale, t: translations, children, hideAu
           <ThemeToggle />
         </div>
         <div className="flex items-center gap-1">
+          <a href="/users" className={buttonClass("ghost", "sm")}>
+            {t(translations, "nav.users")}
+          </a>
           <form method="POST" action="/api/set-lang">
             <input type="hidden" name="lang" value={locale === "es" ? "en" : "es"} />
             <Button variant="ghost" size="sm" type="submit">
@@ -32,6 +35,12 @@ export default function Layout({ user, locale, t: translations, children, hideAu
           {user ? (
             <>
               <a href={`/user/${user.handle}`} className="text-sm text-muted-foreground underline-offset-4 hover:underline">@{user.handle}</a>
+              <a href="/settings/privacy" className={buttonClass("ghost", "sm")}>
+                {t(translations, "nav.privacy")}
+              </a>
+              <a href="/settings/tokens" className={buttonClass("ghost", "sm")}>
+                {t(translations, "nav.tokens")}
+              </a>
               <form method="POST" action="/api/logout">
                 <Button variant="ghost" size="sm" type="submit">
                   {t(translations, "nav.logout")}
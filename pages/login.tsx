@@ -10,6 +10,7 @@ import { Input } from "./ui/input";
 interface LoginProps {
   user?: { email: string; handle: string };
   error?: string;
+  values?: Record<string, string>;
   locale: string;
   t: Record<string, string>;
 }
@@ -24,7 +25,7 @@ export function Head() {
   );
 }
 
-export default function Login({ user, error, locale, t: translations }: LoginProps) {
+export default function Login({ user, error, values = {}, locale, t: translations }: LoginProps) {
   return (
     <Layout user={user} locale={locale} t={translations} hideAuthLinks>
       <div className="container flex justify-center py-24">
@@ -45,6 +46,7 @@ export default function Login({ user, error, locale, t: translations }: LoginPro
                 id="email"
                 type="email"
                 name="email"
+                defaultValue={values.email}
                 placeholder="you@example.com"
                 required
               />
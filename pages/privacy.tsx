@@ -0,0 +1,113 @@
+import Layout from "./layout";
+import { ThemeScript } from "./theme-script";
+import { t } from "./lib/i18n";
+import { Alert } from "./ui/alert";
+import { SubmitButton } from "./ui/submit-button";
+import { FormField } from "./ui/form-field";
+import { Select } from "./ui/select";
+
+interface PrivacyProps {
+  user: { email: string; handle: string };
+  // fields lists the profile fields with a visibility setting, in order.
+  fields: string[];
+  visibilities: string[];
+  visibility: Record<string, string>;
+  hideFromSearch: boolean;
+  error?: string;
+  // values and fieldErrors are set when a submission was rejected, keyed by
+  // field name, e.g. "visibility.email".
+  values?: Record<string, string>;
+  fieldErrors?: Record<string, string>;
+  success?: boolean;
+  locale: string;
+  t: Record<string, string>;
+}
+
+export function Head() {
+  return (
+    <>
+      <ThemeScript />
+      <title>Privacy - MyApp</title>
+      <meta name="description" content="Choose who sees your profile" />
+    </>
+  );
+}
+
+export default function Privacy({
+  user,
+  fields,
+  visibilities,
+  visibility,
+  hideFromSearch,
+  error,
+  values,
+  fieldErrors = {},
+  success,
+  locale,
+  t: translations,
+}: PrivacyProps) {
+  const hidden = values ? values.hide_from_search === "on" : hideFromSearch;
+  return (
+    <Layout user={user} locale={locale} t={translations}>
+      <div className="container flex justify-center py-12">
+        <div className="w-full max-w-lg">
+          <h1 className="text-2xl font-bold mb-2">{t(translations, "privacy.title")}</h1>
+          <p className="text-sm text-muted-foreground mb-6">{t(translations, "privacy.intro")}</p>
+
+          {error && (
+            <div className="mb-4">
+              <Alert variant="error">{error}</Alert>
+            </div>
+          )}
+
+          {success && (
+            <div className="mb-4">
+              <Alert variant="success">{t(translations, "privacy.saved")}</Alert>
+            </div>
+          )}
+
+          <form method="POST" action="/api/user/privacy" className="space-y-4">
+            {fields.map((field) => {
+              const name = `visibility.${field}`;
+              return (
+                <FormField
+                  key={field}
+                  label={t(translations, `privacy.${field}`)}
+                  htmlFor={name}
+                  error={fieldErrors[name]}
+                >
+                  <Select
+                    id={name}
+                    name={name}
+                    defaultValue={values?.[name] ?? visibility[field]}
+                    {...(fieldErrors[name]
+                      ? { "aria-invalid": true, "aria-describedby": `${name}-error` }
+                      : {})}
+                  >
+                    {visibilities.map((v) => (
+                      <option key={v} value={v}>
+                        {t(translations, `visibility.${v}`)}
+                      </option>
+                    ))}
+                  </Select>
+                </FormField>
+              );
+            })}
+
+            <label className="flex items-start gap-2 text-sm">
+              <input type="checkbox" name="hide_from_search" defaultChecked={hidden} className="mt-0.5" />
+              <span>
+                <span className="font-medium">{t(translations, "privacy.hideFromSearch")}</span>
+                <span className="block text-muted-foreground">
+                  {t(translations, "privacy.hideFromSearchHint")}
+                </span>
+              </span>
+            </label>
+
+            <SubmitButton fullWidth>{t(translations, "privacy.submit")}</SubmitButton>
+          </form>
+        </div>
+      </div>
+    </Layout>
+  );
+}
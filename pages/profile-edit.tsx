@@ -7,6 +7,7 @@ import { Alert } from "./ui/alert";
 import { SubmitButton } from "./ui/submit-button";
 import { FormField } from "./ui/form-field";
 import { Input } from "./ui/input";
+import { Select } from "./ui/select";
 import { Textarea } from "./ui/textarea";
 
 interface EditProfileProps {
@@ -15,17 +16,36 @@ interface EditProfileProps {
     handle: string;
     displayName: string;
     bio: string;
+    pronouns: string;
+    location: string;
     country: string;
-    email: string;
+    email?: string;
     avatarURL: string;
-    socialLinks: { instagram: string; facebook: string; linkedin: string; x: string };
+    links: Link[];
   };
+  // linkTypes lists the accepted link types in menu order.
+  linkTypes: string[];
+  maxLinks: number;
   error?: string;
+  // values and fieldErrors are set when a submission was rejected, keyed by
+  // field name, e.g. "display_name" or "links.0.url".
+  values?: Record<string, string>;
+  fieldErrors?: Record<string, string>;
   success?: boolean;
   locale: string;
   t: Record<string, string>;
 }
 
+interface Link {
+  type: string;
+  url: string;
+  label: string;
+}
+
+// blankLinkRows is how many empty rows follow the saved links, so a few
+// links can be added per save.
+const blankLinkRows = 2;
+
 export function Head() {
   return (
     <>
@@ -39,11 +59,38 @@ export function Head() {
 export default function EditProfile({
   user,
   profile,
+  linkTypes,
+  maxLinks,
   error,
+  values,
+  fieldErrors = {},
   success,
   locale,
   t: translations,
 }: EditProfileProps) {
+  const value = (field: string, saved: string) => values?.[field] ?? saved;
+  const invalid = (field: string) =>
+    fieldErrors[field]
+      ? { "aria-invalid": true, "aria-describedby": `${field}-error` }
+      : {};
+
+  // After a rejected save, show the submitted rows rather than the saved ones.
+  let links = profile.links;
+  if (values) {
+    links = [];
+    for (let i = 0; values[`links.${i}.type`] !== undefined; i++) {
+      links.push({
+        type: values[`links.${i}.type`],
+        url: values[`links.${i}.url`] ?? "",
+        label: values[`links.${i}.label`] ?? "",
+      });
+    }
+  }
+  const rows: Link[] = [...links];
+  while (rows.length < Math.min(links.length + blankLinkRows, maxLinks)) {
+    rows.push({ type: linkTypes[0], url: "", label: "" });
+  }
+
   return (
     <Layout user={user} locale={locale} t={translations}>
       <div className="container flex justify-center py-12">
@@ -79,7 +126,7 @@ export default function EditProfile({
                       className="w-full h-full object-cover"
                     />
                   ) : (
-                    <Facehash name={profile.email} size={64} />
+                    <Facehash name={profile.handle} size={64} />
                   )}
                 </div>
                 <input
@@ -91,65 +138,127 @@ export default function EditProfile({
               </div>
             </div>
 
-            <FormField label={t(translations, "edit.handle")} htmlFor="handle">
+            <FormField label={t(translations, "edit.handle")} htmlFor="handle" error={fieldErrors.handle}>
               <Input
                 id="handle"
                 type="text"
                 name="handle"
-                defaultValue={profile.handle}
+                defaultValue={value("handle", profile.handle)}
+                {...invalid("handle")}
                 required
                 pattern="[a-z0-9][a-z0-9_\-]{2,29}"
               />
             </FormField>
 
-            <FormField label={t(translations, "edit.displayName")} htmlFor="display_name">
+            <FormField
+              label={t(translations, "edit.displayName")}
+              htmlFor="display_name"
+              error={fieldErrors.display_name}
+            >
               <Input
                 id="display_name"
                 type="text"
                 name="display_name"
-                defaultValue={profile.displayName}
+                defaultValue={value("display_name", profile.displayName)}
+                maxLength={50}
+                {...invalid("display_name")}
               />
             </FormField>
 
-            <FormField label={t(translations, "edit.bio")} htmlFor="bio">
+            <FormField label={t(translations, "edit.bio")} htmlFor="bio" error={fieldErrors.bio}>
               <Textarea
                 id="bio"
                 name="bio"
-                defaultValue={profile.bio}
+                defaultValue={value("bio", profile.bio)}
                 rows={3}
+                maxLength={300}
+                {...invalid("bio")}
               />
+              <p className="text-sm text-muted-foreground">{t(translations, "edit.bioHint")}</p>
             </FormField>
 
-            <FormField label={t(translations, "edit.country")} htmlFor="country">
-              <CountrySelect name="country" value={profile.country} />
-            </FormField>
-
-            <div className="space-y-2">
-              <h3 className="text-sm font-medium">{t(translations, "edit.socials")}</h3>
-              <Input
-                type="text"
-                name="instagram"
-                defaultValue={profile.socialLinks.instagram}
-                placeholder={t(translations, "edit.instagram")}
-              />
+            <FormField label={t(translations, "edit.pronouns")} htmlFor="pronouns" error={fieldErrors.pronouns}>
               <Input
+                id="pronouns"
                 type="text"
-                name="facebook"
-                defaultValue={profile.socialLinks.facebook}
-                placeholder={t(translations, "edit.facebook")}
-              />
-              <Input
-                type="text"
-                name="linkedin"
-                defaultValue={profile.socialLinks.linkedin}
-                placeholder={t(translations, "edit.linkedin")}
+                name="pronouns"
+                defaultValue={value("pronouns", profile.pronouns)}
+                maxLength={30}
+                {...invalid("pronouns")}
               />
+            </FormField>
+
+            <FormField label={t(translations, "edit.location")} htmlFor="location" error={fieldErrors.location}>
               <Input
+                id="location"
                 type="text"
-                name="x"
-                defaultValue={profile.socialLinks.x}
-                placeholder={t(translations, "edit.x")}
+                name="location"
+                defaultValue={value("location", profile.location)}
+                maxLength={60}
+                {...invalid("location")}
               />
+            </FormField>
+
+            <FormField label={t(translations, "edit.country")} htmlFor="country" error={fieldErrors.country}>
+              <CountrySelect name="country" value={value("country", profile.country)} />
+            </FormField>
+
+            <div className="space-y-2">
+              <h3 className="text-sm font-medium">{t(translations, "edit.links")}</h3>
+              <p className="text-sm text-muted-foreground">{t(translations, "edit.linksHint")}</p>
+              {fieldErrors.links && (
+                <p id="links-error" className="text-sm text-destructive">
+                  {fieldErrors.links}
+                </p>
+              )}
+              {rows.map((link, i) => {
+                const field = (name: string) => `links.${i}.${name}`;
+                const errors = ["type", "url", "label"].filter((name) => fieldErrors[field(name)]);
+                return (
+                  <div key={i} className="space-y-1.5">
+                    <div className="flex gap-2">
+                      <Select
+                        name="link_type"
+                        defaultValue={link.type}
+                        aria-label={t(translations, "edit.linkType")}
+                        className="w-36 flex-shrink-0"
+                        {...invalid(field("type"))}
+                      >
+                        {linkTypes.map((type) => (
+                          <option key={type} value={type}>
+                            {t(translations, `links.${type}`)}
+                          </option>
+                        ))}
+                      </Select>
+                      <Input
+                        id={field("url")}
+                        type="url"
+                        name="link_url"
+                        defaultValue={link.url}
+                        placeholder="https://"
+                        aria-label={t(translations, "edit.linkURL")}
+                        {...invalid(field("url"))}
+                      />
+                      <Input
+                        id={field("label")}
+                        type="text"
+                        name="link_label"
+                        defaultValue={link.label}
+                        placeholder={t(translations, "edit.linkLabel")}
+                        aria-label={t(translations, "edit.linkLabel")}
+                        maxLength={40}
+                        className="w-40 flex-shrink-0"
+                        {...invalid(field("label"))}
+                      />
+                    </div>
+                    {errors.map((name) => (
+                      <p key={name} id={`${field(name)}-error`} className="text-sm text-destructive">
+                        {fieldErrors[field(name)]}
+                      </p>
+                    ))}
+                  </div>
+                );
+              })}
             </div>
 
             <SubmitButton fullWidth>
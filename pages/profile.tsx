@@ -3,7 +3,11 @@ import Layout from "./layout";
 import { ThemeScript } from "./theme-script";
 import { t } from "./lib/i18n";
 import { countryName } from "./lib/countries";
-import { buttonClass } from "./ui/button";
+import { Alert } from "./ui/alert";
+import { Button, buttonClass } from "./ui/button";
+import { FormField } from "./ui/form-field";
+import { Select } from "./ui/select";
+import { Textarea } from "./ui/textarea";
 
 interface ProfileProps {
   user?: { email: string; handle: string };
@@ -11,38 +15,80 @@ interface ProfileProps {
     handle: string;
     displayName: string;
     bio: string;
+    // bioHTML is the bio rendered from Markdown and sanitized by the server.
+    bioHTML: string;
+    pronouns: string;
+    location: string;
     country: string;
-    email: string;
+    // email is only sent when the viewer may see it.
+    email?: string;
     avatarURL: string;
-    socialLinks: { instagram: string; facebook: string; linkedin: string; x: string };
+    links: { type: string; url: string; label: string }[];
   };
   isOwner: boolean;
+  followers: number;
+  following: number;
+  isFollowing: boolean;
+  // isBlocking is set when the viewer blocked this profile.
+  isBlocking: boolean;
+  reportReasons: string[];
+  // reported is set after the viewer's report was sent.
+  reported?: boolean;
+  // noindex asks search engines to leave the profile out.
+  noindex: boolean;
+  error?: string;
+  // values and fieldErrors are set when a report was rejected.
+  values?: Record<string, string>;
+  fieldErrors?: Record<string, string>;
   locale: string;
   t: Record<string, string>;
 }
 
-export function Head() {
+export function Head({ noindex }: Pick<ProfileProps, "noindex">) {
   return (
     <>
       <ThemeScript />
       <title>Profile - MyApp</title>
       <meta name="description" content="User profile" />
+      {noindex && <meta name="robots" content="noindex" />}
     </>
   );
 }
 
-export default function Profile({ user, profile, isOwner, locale, t: translations }: ProfileProps) {
+export default function Profile({
+  user,
+  profile,
+  isOwner,
+  followers,
+  following,
+  isFollowing,
+  isBlocking,
+  reportReasons,
+  reported,
+  error,
+  values,
+  fieldErrors = {},
+  locale,
+  t: translations,
+}: ProfileProps) {
   const hasInfo =
-    profile.bio ||
-    profile.country ||
-    profile.socialLinks.instagram ||
-    profile.socialLinks.facebook ||
-    profile.socialLinks.linkedin ||
-    profile.socialLinks.x;
+    profile.email || profile.bio || profile.location || profile.country || profile.links.length > 0;
 
   return (
     <Layout user={user} locale={locale} t={translations}>
       <div className="container py-12 max-w-2xl mx-auto">
+        {error && (
+          <div className="mb-6">
+            <Alert variant="error">{error}</Alert>
+          </div>
+        )}
+
+        {reported && (
+          <div className="mb-6">
+            <Alert variant="success">{t(translations, "report.sent")}</Alert>
+          </div>
+        )}
+
         <div className="flex items-start justify-between mb-8">
           <div className="flex items-center gap-4">
             <div className="w-20 h-20 rounded-full overflow-hidden flex-shrink-0">
@@ -53,28 +99,63 @@ export default function Profile({ user, profile, isOwner, locale, t: translation
                   className="w-full h-full object-cover"
                 />
               ) : (
-                <Facehash name={profile.email} size={80} />
+                <Facehash name={profile.handle} size={80} />
               )}
             </div>
             <div>
               <h1 className="text-2xl font-bold">
                 {profile.displayName || `@${profile.handle}`}
               </h1>
-              {profile.displayName && (
-                <p className="text-muted-foreground">@{profile.handle}</p>
+              {(profile.displayName || profile.pronouns) && (
+                <p className="text-muted-foreground">
+                  {profile.displayName && `@${profile.handle}`}
+                  {profile.displayName && profile.pronouns && " · "}
+                  {profile.pronouns}
+                </p>
               )}
+              <div className="flex gap-4 mt-1 text-sm">
+                <a href={`/user/${profile.handle}/followers`} className="underline-offset-4 hover:underline">
+                  <span className="font-medium">{followers}</span>{" "}
+                  <span className="text-muted-foreground">{t(translations, "follows.followers")}</span>
+                </a>
+                <a href={`/user/${profile.handle}/following`} className="underline-offset-4 hover:underline">
+                  <span className="font-medium">{following}</span>{" "}
+                  <span className="text-muted-foreground">{t(translations, "follows.following")}</span>
+                </a>
+              </div>
             </div>
           </div>
-          {isOwner && (
+          {isOwner ? (
             <a
               href={`/user/${profile.handle}/edit`}
               className={buttonClass("outline")}
             >
               {t(translations, "profile.editButton")}
             </a>
+          ) : (
+            user && (
+              <div className="flex gap-2">
+                {!isBlocking && (
+                  <form method="POST" action={`/api/user/${profile.handle}/${isFollowing ? "unfollow" : "follow"}`}>
+                    <Button variant={isFollowing ? "outline" : "primary"} type="submit">
+                      {t(translations, isFollowing ? "follows.unfollow" : "follows.follow")}
+                    </Button>
+                  </form>
+                )}
+                <form method="POST" action={`/api/user/${profile.handle}/${isBlocking ? "unblock" : "block"}`}>
+                  <Button variant="outline" type="submit">
+                    {t(translations, isBlocking ? "block.unblock" : "block.block")}
+                  </Button>
+                </form>
+              </div>
+            )
           )}
         </div>
 
+        {isBlocking && (
+          <p className="mb-6 text-sm text-muted-foreground">{t(translations, "block.blocking")}</p>
+        )}
+
         {!hasInfo && (
           <p className="text-muted-foreground">{t(translations, "profile.noInfo")}</p>
         )}
@@ -84,7 +165,30 @@ export default function Profile({ user, profile, isOwner, locale, t: translation
             <h2 className="text-sm font-medium text-muted-foreground mb-1">
               {t(translations, "profile.bio")}
             </h2>
-            <p>{profile.bio}</p>
+            <div
+              className="space-y-2 break-words [&_a]:underline [&_a]:underline-offset-4 [&_ol]:list-decimal [&_ol]:pl-5 [&_ul]:list-disc [&_ul]:pl-5"
+              dangerouslySetInnerHTML={{ __html: profile.bioHTML }}
+            />
+          </div>
+        )}
+
+        {profile.email && (
+          <div className="mb-6">
+            <h2 className="text-sm font-medium text-muted-foreground mb-1">
+              {t(translations, "profile.email")}
+            </h2>
+            <a href={`mailto:${profile.email}`} className="underline underline-offset-4">
+              {profile.email}
+            </a>
+          </div>
+        )}
+
+        {profile.location && (
+          <div className="mb-6">
+            <h2 className="text-sm font-medium text-muted-foreground mb-1">
+              {t(translations, "profile.location")}
+            </h2>
+            <p>{profile.location}</p>
           </div>
         )}
 
@@ -97,58 +201,62 @@ export default function Profile({ user, profile, isOwner, locale, t: translation
           </div>
         )}
 
-        {(profile.socialLinks.instagram ||
-          profile.socialLinks.facebook ||
-          profile.socialLinks.linkedin ||
-          profile.socialLinks.x) && (
+        {profile.links.length > 0 && (
           <div className="mb-6">
             <h2 className="text-sm font-medium text-muted-foreground mb-2">
-              {t(translations, "profile.socials")}
+              {t(translations, "profile.links")}
             </h2>
             <div className="flex flex-wrap gap-3">
-              {profile.socialLinks.instagram && (
+              {profile.links.map((link, i) => (
                 <a
-                  href={profile.socialLinks.instagram}
+                  key={i}
+                  href={link.url}
                   className="text-sm underline underline-offset-4"
                   target="_blank"
                   rel="noopener noreferrer"
                 >
-                  Instagram
+                  {link.label || t(translations, `links.${link.type}`)}
                 </a>
-              )}
-              {profile.socialLinks.facebook && (
-                <a
-                  href={profile.socialLinks.facebook}
-                  className="text-sm underline underline-offset-4"
-                  target="_blank"
-                  rel="noopener noreferrer"
-                >
-                  Facebook
-                </a>
-              )}
-              {profile.socialLinks.linkedin && (
-                <a
-                  href={profile.socialLinks.linkedin}
-                  className="text-sm underline underline-offset-4"
-                  target="_blank"
-                  rel="noopener noreferrer"
-                >
-                  LinkedIn
-                </a>
-              )}
-              {profile.socialLinks.x && (
-                <a
-                  href={profile.socialLinks.x}
-                  className="text-sm underline underline-offset-4"
-                  target="_blank"
-                  rel="noopener noreferrer"
-                >
-                  X
-                </a>
-              )}
+              ))}
             </div>
           </div>
         )}
+
+        {user && !isOwner && (
+          <details className="mt-10 text-sm" open={!!values}>
+            <summary className="cursor-pointer text-muted-foreground">{t(translations, "report.title")}</summary>
+            <form method="POST" action={`/api/user/${profile.handle}/report`} className="mt-4 space-y-4">
+              <p className="text-muted-foreground">{t(translations, "report.intro")}</p>
+              <FormField label={t(translations, "report.reason")} htmlFor="reason" error={fieldErrors.reason}>
+                <Select
+                  id="reason"
+                  name="reason"
+                  defaultValue={values?.reason}
+                  {...(fieldErrors.reason ? { "aria-invalid": true, "aria-describedby": "reason-error" } : {})}
+                >
+                  {reportReasons.map((reason) => (
+                    <option key={reason} value={reason}>
+                      {t(translations, `report.reason.${reason}`)}
+                    </option>
+                  ))}
+                </Select>
+              </FormField>
+              <FormField label={t(translations, "report.evidence")} htmlFor="evidence" error={fieldErrors.evidence}>
+                <Textarea
+                  id="evidence"
+                  name="evidence"
+                  rows={4}
+                  maxLength={1000}
+                  defaultValue={values?.evidence}
+                  {...(fieldErrors.evidence ? { "aria-invalid": true, "aria-describedby": "evidence-error" } : {})}
+                />
+              </FormField>
+              <Button variant="outline" type="submit">
+                {t(translations, "report.submit")}
+              </Button>
+            </form>
+          </details>
+        )}
       </div>
     </Layout>
   );
@@ -0,0 +1,116 @@
+import Layout from "./layout";
+import { ThemeScript } from "./theme-script";
+import { t } from "./lib/i18n";
+import { Alert } from "./ui/alert";
+import { Button } from "./ui/button";
+import { Card } from "./ui/card";
+
+interface Report {
+  id: string;
+  reason: string;
+  evidence: string;
+  createdAt: string;
+  reporter: { handle: string; displayName: string };
+  reported: { handle: string; displayName: string; warnings: number; suspended: boolean };
+}
+
+interface ReportsProps {
+  user: { email: string; handle: string };
+  // reports lists the open reports, oldest first.
+  reports: Report[];
+  actions: string[];
+  error?: string;
+  resolved?: boolean;
+  locale: string;
+  t: Record<string, string>;
+}
+
+export function Head() {
+  return (
+    <>
+      <ThemeScript />
+      <title>Reports - MyApp</title>
+      <meta name="robots" content="noindex" />
+    </>
+  );
+}
+
+export default function Reports({
+  user,
+  reports,
+  actions,
+  error,
+  resolved,
+  locale,
+  t: translations,
+}: ReportsProps) {
+  return (
+    <Layout user={user} locale={locale} t={translations}>
+      <div className="container flex justify-center py-12">
+        <div className="w-full max-w-2xl space-y-6">
+          <div>
+            <h1 className="text-2xl font-bold mb-2">{t(translations, "reports.title")}</h1>
+            <p className="text-sm text-muted-foreground">{t(translations, "reports.intro")}</p>
+          </div>
+
+          {error && <Alert variant="error">{error}</Alert>}
+
+          {resolved && <Alert variant="success">{t(translations, "reports.resolved")}</Alert>}
+
+          {reports.length === 0 ? (
+            <p className="text-sm text-muted-foreground">{t(translations, "reports.empty")}</p>
+          ) : (
+            <div className="space-y-3">
+              {reports.map((report) => (
+                <Card key={report.id}>
+                  <div className="space-y-3">
+                    <div className="flex items-start justify-between gap-4">
+                      <div className="min-w-0">
+                        <a href={`/user/${report.reported.handle}`} className="font-medium underline-offset-4 hover:underline">
+                          {report.reported.displayName || `@${report.reported.handle}`}
+                        </a>
+                        <p className="text-xs text-muted-foreground">
+                          {t(translations, "reports.warnings", { count: String(report.reported.warnings) })}
+                          {report.reported.suspended && (
+                            <>
+                              {" · "}
+                              {t(translations, "reports.suspended")}
+                            </>
+                          )}
+                        </p>
+                      </div>
+                      <span className="text-sm font-medium">
+                        {t(translations, `report.reason.${report.reason}`)}
+                      </span>
+                    </div>
+                    <p className="text-sm whitespace-pre-wrap break-words">
+                      {report.evidence || (
+                        <span className="text-muted-foreground">{t(translations, "reports.noEvidence")}</span>
+                      )}
+                    </p>
+                    <p className="text-xs text-muted-foreground">
+                      {t(translations, "reports.reportedBy", {
+                        handle: report.reporter.handle,
+                        date: report.createdAt,
+                      })}
+                    </p>
+                    <div className="flex gap-2">
+                      {actions.map((action) => (
+                        <form key={action} method="POST" action={`/api/admin/reports/${report.id}/resolve`}>
+                          <input type="hidden" name="action" value={action} />
+                          <Button variant="outline" size="sm" type="submit">
+                            {t(translations, `reports.action.${action}`)}
+                          </Button>
+                        </form>
+                      ))}
+                    </div>
+                  </div>
+                </Card>
+              ))}
+            </div>
+          )}
+        </div>
+      </div>
+    </Layout>
+  );
+}
@@ -10,6 +10,8 @@ import { Input } from "./ui/input";
 interface SignupProps {
   user?: { email: string; handle: string };
   error?: string;
+  values?: Record<string, string>;
+  fieldErrors?: Record<string, string>;
   locale: string;
   t: Record<string, string>;
 }
@@ -24,7 +26,14 @@ export function Head() {
   );
 }
 
-export default function Signup({ user, error, locale, t: translations }: SignupProps) {
+export default function Signup({
+  user,
+  error,
+  values = {},
+  fieldErrors = {},
+  locale,
+  t: translations,
+}: SignupProps) {
   return (
     <Layout user={user} locale={locale} t={translations} hideAuthLinks>
       <div className="container flex justify-center py-24">
@@ -40,28 +49,30 @@ export default function Signup({ user, error, locale, t: translations }: SignupP
           )}
 
           <form method="POST" action="/api/signup" className="mt-6 space-y-4">
-            <FormField label={t(translations, "signup.handle")} htmlFor="handle">
+            <FormField label={t(translations, "signup.handle")} htmlFor="handle" error={fieldErrors.handle}>
               <Input
                 id="handle"
                 type="text"
                 name="handle"
+                defaultValue={values.handle}
                 placeholder="yourhandle"
                 required
                 pattern="[a-z0-9][a-z0-9_\-]{2,29}"
               />
             </FormField>
 
-            <FormField label={t(translations, "signup.email")} htmlFor="email">
+            <FormField label={t(translations, "signup.email")} htmlFor="email" error={fieldErrors.email}>
               <Input
                 id="email"
                 type="email"
                 name="email"
+                defaultValue={values.email}
                 placeholder="you@example.com"
                 required
               />
             </FormField>
 
-            <FormField label={t(translations, "signup.password")} htmlFor="password">
+            <FormField label={t(translations, "signup.password")} htmlFor="password" error={fieldErrors.password}>
               <Input
                 id="password"
                 type="password"
@@ -72,7 +83,7 @@ export default function Signup({ user, error, locale, t: translations }: SignupP
               />
             </FormField>
 
-            <FormField label={t(translations, "signup.confirmPassword")} htmlFor="confirm_password">
+            <FormField label={t(translations, "signup.confirmPassword")} htmlFor="confirm_password" error={fieldErrors.confirm_password}>
               <Input
                 id="confirm_password"
                 type="password"
@@ -0,0 +1,157 @@
+import Layout from "./layout";
+import { ThemeScript } from "./theme-script";
+import { t } from "./lib/i18n";
+import { Alert } from "./ui/alert";
+import { Button } from "./ui/button";
+import { Card } from "./ui/card";
+import { SubmitButton } from "./ui/submit-button";
+import { FormField } from "./ui/form-field";
+import { Input } from "./ui/input";
+import { Select } from "./ui/select";
+
+interface Token {
+  id: string;
+  name: string;
+  prefix: string;
+  scopes: string[];
+  createdAt: string;
+  expiresAt: string;
+  lastUsedAt: string;
+  revoked: boolean;
+  active: boolean;
+}
+
+interface TokensProps {
+  user: { email: string; handle: string };
+  tokens: Token[];
+  scopes: string[];
+  newToken?: string;
+  error?: string;
+  values?: Record<string, string>;
+  fieldErrors?: Record<string, string>;
+  revoked?: boolean;
+  locale: string;
+  t: Record<string, string>;
+}
+
+const expiryDays = ["30", "90", "365"];
+
+export function Head() {
+  return (
+    <>
+      <ThemeScript />
+      <title>Access Tokens - MyApp</title>
+      <meta name="description" content="Manage your personal access tokens" />
+    </>
+  );
+}
+
+export default function Tokens({
+  user,
+  tokens,
+  scopes,
+  newToken,
+  error,
+  values = {},
+  fieldErrors = {},
+  revoked,
+  locale,
+  t: translations,
+}: TokensProps) {
+  return (
+    <Layout user={user} locale={locale} t={translations}>
+      <div className="container flex justify-center py-12">
+        <div className="w-full max-w-lg space-y-6">
+          <div>
+            <h1 className="text-2xl font-bold mb-2">{t(translations, "tokens.title")}</h1>
+            <p className="text-sm text-muted-foreground">{t(translations, "tokens.intro")}</p>
+          </div>
+
+          {error && <Alert variant="error">{error}</Alert>}
+
+          {revoked && <Alert variant="success">{t(translations, "tokens.revoked")}</Alert>}
+
+          {newToken && (
+            <Alert variant="success">
+              <p className="mb-2">{t(translations, "tokens.created")}</p>
+              <code className="block break-all font-mono text-sm select-all">{newToken}</code>
+            </Alert>
+          )}
+
+          <form method="POST" action="/api/tokens" className="space-y-4">
+            <FormField label={t(translations, "tokens.name")} htmlFor="name" error={fieldErrors.name}>
+              <Input id="name" type="text" name="name" required maxLength={100} defaultValue={values.name} />
+            </FormField>
+
+            <fieldset className="space-y-2">
+              <legend className="text-sm font-medium">{t(translations, "tokens.scopes")}</legend>
+              {scopes.map((scope) => (
+                <label key={scope} className="flex items-center gap-2 text-sm">
+                  <input type="checkbox" name="scope" value={scope} defaultChecked={scope === "profile:read"} />
+                  <span>{t(translations, `tokens.scope.${scope}`)}</span>
+                  <code className="text-muted-foreground">{scope}</code>
+                </label>
+              ))}
+              {fieldErrors.scope && <p className="text-sm text-destructive">{fieldErrors.scope}</p>}
+            </fieldset>
+
+            <FormField label={t(translations, "tokens.expiresIn")} htmlFor="expires_in">
+              <Select id="expires_in" name="expires_in" defaultValue={values.expires_in ?? "90"}>
+                {expiryDays.map((days) => (
+                  <option key={days} value={days}>
+                    {t(translations, "tokens.days", { days })}
+                  </option>
+                ))}
+                <option value="">{t(translations, "tokens.never")}</option>
+              </Select>
+            </FormField>
+
+            <SubmitButton fullWidth>{t(translations, "tokens.create")}</SubmitButton>
+          </form>
+
+          {tokens.length === 0 ? (
+            <p className="text-sm text-muted-foreground">{t(translations, "tokens.empty")}</p>
+          ) : (
+            <div className="space-y-3">
+              {tokens.map((token) => (
+                <Card key={token.id} className={token.active ? "" : "opacity-60"}>
+                  <div className="flex items-start justify-between gap-4">
+                    <div className="space-y-1 min-w-0">
+                      <p className="font-medium">{token.name}</p>
+                      <p className="text-sm text-muted-foreground font-mono">{token.prefix}…</p>
+                      <p className="text-sm text-muted-foreground">{token.scopes.join(", ")}</p>
+                      <p className="text-xs text-muted-foreground">
+                        {t(translations, "tokens.createdAt", { date: token.createdAt })}
+                        {" · "}
+                        {token.lastUsedAt
+                          ? t(translations, "tokens.lastUsed", { date: token.lastUsedAt })
+                          : t(translations, "tokens.neverUsed")}
+                        {token.expiresAt && (
+                          <>
+                            {" · "}
+                            {t(translations, "tokens.expiresAt", { date: token.expiresAt })}
+                          </>
+                        )}
+                      </p>
+                    </div>
+                    {token.active ? (
+                      <form method="POST" action={`/api/tokens/${token.id}/revoke`}>
+                        <Button variant="outline" size="sm" type="submit">
+                          {t(translations, "tokens.revoke")}
+                        </Button>
+                      </form>
+                    ) : (
+                      <span className="text-sm text-muted-foreground">
+                        {t(translations, token.revoked ? "tokens.statusRevoked" : "tokens.statusExpired")}
+                      </span>
+                    )}
+                  </div>
+                </Card>
+              ))}
+            </div>
+          )}
+        </div>
+      </div>
+    </Layout>
+  );
+}
@@ -3,16 +3,22 @@ import type { ReactNode } from "react";
 interface FormFieldProps {
   label: string;
   htmlFor: string;
+  error?: string;
   children: ReactNode;
 }
 
-export function FormField({ label, htmlFor, children }: FormFieldProps) {
+export function FormField({ label, htmlFor, error, children }: FormFieldProps) {
   return (
     <div className="space-y-1.5">
       <label htmlFor={htmlFor} className="text-sm font-medium">
         {label}
       </label>
       {children}
+      {error && (
+        <p id={`${htmlFor}-error`} className="text-sm text-destructive">
+          {error}
+        </p>
+      )}
     </div>
   );
 }
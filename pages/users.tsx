@@ -0,0 +1,85 @@
+import Layout from "./layout";
+import { ThemeScript } from "./theme-script";
+import { t } from "./lib/i18n";
+import { CountrySelect } from "./components/country-select";
+import { UserList, type ListedUser } from "./components/user-list";
+import { Button, buttonClass } from "./ui/button";
+import { Input } from "./ui/input";
+import { Select } from "./ui/select";
+
+interface UsersProps {
+  user?: { email: string; handle: string };
+  users: ListedUser[];
+  next: string;
+  search: { q: string; country: string; sort: string };
+  locale: string;
+  t: Record<string, string>;
+}
+
+const sorts = ["", "handle", "newest"];
+
+export function Head() {
+  return (
+    <>
+      <ThemeScript />
+      <title>People - MyApp</title>
+      <meta name="description" content="Find people on MyApp" />
+    </>
+  );
+}
+
+export default function Users({ user, users, next, search, locale, t: translations }: UsersProps) {
+  const nextParams = new URLSearchParams();
+  for (const [key, value] of Object.entries(search)) {
+    if (value) nextParams.set(key, value);
+  }
+  nextParams.set("after", next);
+
+  return (
+    <Layout user={user} locale={locale} t={translations}>
+      <div className="container py-12 max-w-2xl mx-auto">
+        <h1 className="text-2xl font-bold mb-6">{t(translations, "users.title")}</h1>
+
+        <form method="GET" action="/users" role="search" className="flex flex-col gap-2 mb-8 sm:flex-row">
+          <Input
+            type="search"
+            name="q"
+            defaultValue={search.q}
+            maxLength={100}
+            placeholder={t(translations, "users.searchPlaceholder")}
+            aria-label={t(translations, "users.search")}
+          />
+          <CountrySelect
+            name="country"
+            value={search.country}
+            emptyLabel={t(translations, "users.anyCountry")}
+            className="sm:w-48"
+            aria-label={t(translations, "users.country")}
+          />
+          <Select name="sort" defaultValue={search.sort} className="sm:w-40" aria-label={t(translations, "users.sort")}>
+            {sorts.map((sort) => (
+              <option key={sort} value={sort}>
+                {t(translations, `users.sort.${sort || "best"}`)}
+              </option>
+            ))}
+          </Select>
+          <Button type="submit">{t(translations, "users.search")}</Button>
+        </form>
+
+        {users.length === 0 ? (
+          <p className="text-muted-foreground">{t(translations, "users.empty")}</p>
+        ) : (
+          <UserList users={users} />
+        )}
+
+        {next && (
+          <nav className="flex justify-end mt-8">
+            <a href={`/users?${nextParams}`} className={buttonClass("outline", "sm")}>
+              {t(translations, "users.more")}
+            </a>
+          </nav>
+        )}
+      </div>
+    </Layout>
+  );
+}
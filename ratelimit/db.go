@@ -0,0 +1,72 @@
+package ratelimit
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"myapp/model"
+)
+
+// maxAttempts bounds compare-and-swap retries under contention.
+const maxAttempts = 5
+
+var errContention = errors.New("rate limit bucket contended, giving up")
+
+// DBStore keeps buckets in the database so limits hold across replicas.
+// Updates use optimistic concurrency on the bucket version.
+type DBStore struct {
+	repo *model.RateLimitRepository
+	now  func() time.Time
+}
+
+func NewDBStore(repo *model.RateLimitRepository) *DBStore {
+	return &DBStore{repo: repo, now: time.Now}
+}
+
+func (s *DBStore) Allow(ctx context.Context, key string, p Policy) (Result, error) {
+	k := p.bucketKey(key)
+	for range maxAttempts {
+		now := s.now()
+		bucket, err := s.repo.Get(ctx, k)
+		if err != nil {
+			return Result{}, err
+		}
+
+		if bucket == nil {
+			tokens, res := p.take(float64(p.Limit), now, now)
+			ok, err := s.repo.Insert(ctx, &model.RateLimitBucket{
+				ID:         k,
+				Tokens:     tokens,
+				RefilledAt: now.UnixNano(),
+				ExpiresAt:  p.fullAt(tokens, now).UnixNano(),
+			})
+			if err != nil {
+				return Result{}, err
+			}
+			if ok {
+				return res, nil
+			}
+			continue
+		}
+
+		tokens, res := p.take(bucket.Tokens, time.Unix(0, bucket.RefilledAt), now)
+		bucket.Tokens = tokens
+		bucket.RefilledAt = now.UnixNano()
+		bucket.ExpiresAt = p.fullAt(tokens, now).UnixNano()
+		ok, err := s.repo.CompareAndSwap(ctx, bucket)
+		if err != nil {
+			return Result{}, err
+		}
+		if ok {
+			return res, nil
+		}
+	}
+	return Result{}, errContention
+}
+
+// Prune deletes buckets that have refilled completely. Run it periodically;
+// the memory store does the equivalent on its own.
+func (s *DBStore) Prune(ctx context.Context) (int64, error) {
+	return s.repo.DeleteExpired(ctx, s.now().UnixNano())
+}
@@ -0,0 +1,63 @@
+package ratelimit
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// sweepInterval bounds how often full buckets are dropped from memory.
+const sweepInterval = time.Minute
+
+type memoryBucket struct {
+	tokens float64
+	last   time.Time
+	fullAt time.Time
+}
+
+// MemoryStore keeps buckets in process memory. Limits are per replica, so
+// use DBStore when running more than one.
+type MemoryStore struct {
+	mu        sync.Mutex
+	buckets   map[string]*memoryBucket
+	lastSweep time.Time
+	now       func() time.Time
+}
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{buckets: make(map[string]*memoryBucket), now: time.Now}
+}
+
+func (s *MemoryStore) Allow(_ context.Context, key string, p Policy) (Result, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.now()
+	s.sweep(now)
+
+	k := p.bucketKey(key)
+	b, ok := s.buckets[k]
+	if !ok {
+		b = &memoryBucket{tokens: float64(p.Limit), last: now}
+		s.buckets[k] = b
+	}
+	var res Result
+	b.tokens, res = p.take(b.tokens, b.last, now)
+	b.last = now
+	b.fullAt = p.fullAt(b.tokens, now)
+	return res, nil
+}
+
+// sweep drops buckets that have refilled completely; a fresh bucket is
+// indistinguishable from them. Callers must hold s.mu.
+func (s *MemoryStore) sweep(now time.Time) {
+	if now.Sub(s.lastSweep) < sweepInterval {
+		return
+	}
+	s.lastSweep = now
+	for k, b := range s.buckets {
+		if now.After(b.fullAt) {
+			delete(s.buckets, k)
+		}
+	}
+}
@@ -0,0 +1,71 @@
+// Package ratelimit implements token-bucket rate limiting with pluggable
+// backends: an in-process store for a single replica and a database store
+// shared by every replica.
+package ratelimit
+
+import (
+	"context"
+	"time"
+)
+
+// Policy describes a token bucket. Up to Limit requests may be made in a
+// burst, and the bucket refills completely over Period.
+type Policy struct {
+	// Name namespaces bucket keys and appears in logs, e.g. "login-ip".
+	Name   string
+	Limit  int
+	Period time.Duration
+}
+
+// Result is the outcome of a single Allow call.
+type Result struct {
+	Allowed   bool
+	Remaining int
+	// RetryAfter is how long until the next token, set when not allowed.
+	RetryAfter time.Duration
+}
+
+// Store takes one token from the bucket identified by key under policy p.
+type Store interface {
+	Allow(ctx context.Context, key string, p Policy) (Result, error)
+}
+
+// Unlimited returns a Store that allows every request.
+func Unlimited() Store {
+	return unlimited{}
+}
+
+type unlimited struct{}
+
+func (unlimited) Allow(context.Context, string, Policy) (Result, error) {
+	return Result{Allowed: true}, nil
+}
+
+func (p Policy) bucketKey(key string) string {
+	return p.Name + ":" + key
+}
+
+// perSecond is the refill rate in tokens per second.
+func (p Policy) perSecond() float64 {
+	return float64(p.Limit) / p.Period.Seconds()
+}
+
+// take refills a bucket holding tokens as of last up to now, then tries to
+// take one token. It returns the new token count and the outcome.
+func (p Policy) take(tokens float64, last, now time.Time) (float64, Result) {
+	if elapsed := now.Sub(last); elapsed > 0 {
+		tokens = min(float64(p.Limit), tokens+elapsed.Seconds()*p.perSecond())
+	}
+	if tokens >= 1 {
+		tokens--
+		return tokens, Result{Allowed: true, Remaining: int(tokens)}
+	}
+	wait := time.Duration((1 - tokens) / p.perSecond() * float64(time.Second))
+	return tokens, Result{RetryAfter: wait}
+}
+
+// fullAt is when a bucket holding tokens at now will be full again.
+func (p Policy) fullAt(tokens float64, now time.Time) time.Time {
+	missing := float64(p.Limit) - tokens
+	return now.Add(time.Duration(missing / p.perSecond() * float64(time.Second)))
+}
@@ -0,0 +1,179 @@
+package ratelimit
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"myapp/model"
+	"myapp/testutil"
+)
+
+var testPolicy = Policy{Name: "test", Limit: 3, Period: time.Minute}
+
+type fakeClock struct {
+	mu  sync.Mutex
+	now time.Time
+}
+
+func (c *fakeClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+func (c *fakeClock) Advance(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.now = c.now.Add(d)
+}
+
+func newTestMemoryStore(clock *fakeClock) Store {
+	s := NewMemoryStore()
+	s.now = clock.Now
+	return s
+}
+
+func newTestDBStore(t *testing.T, clock *fakeClock) *DBStore {
+	s := NewDBStore(model.NewRateLimitRepository(testutil.NewTestDB(t, &model.RateLimitBucket{})))
+	s.now = clock.Now
+	return s
+}
+
+func TestConformance(t *testing.T) {
+	backends := map[string]func(t *testing.T, clock *fakeClock) Store{
+		"memory": func(t *testing.T, clock *fakeClock) Store { return newTestMemoryStore(clock) },
+		"db":     func(t *testing.T, clock *fakeClock) Store { return newTestDBStore(t, clock) },
+	}
+	for name, newStore := range backends {
+		t.Run(name, func(t *testing.T) {
+			testStore(t, newStore)
+		})
+	}
+}
+
+func testStore(t *testing.T, newStore func(t *testing.T, clock *fakeClock) Store) {
+	ctx := context.Background()
+
+	allow := func(t *testing.T, s Store, key string, p Policy) Result {
+		t.Helper()
+		res, err := s.Allow(ctx, key, p)
+		if err != nil {
+			t.Fatalf("Allow failed: %v", err)
+		}
+		return res
+	}
+
+	t.Run("allows a burst up to the limit", func(t *testing.T) {
+		s := newStore(t, &fakeClock{now: time.Unix(1000, 0)})
+		for i := range testPolicy.Limit {
+			res := allow(t, s, "1.2.3.4", testPolicy)
+			if !res.Allowed {
+				t.Fatalf("request %d rejected", i+1)
+			}
+			if want := testPolicy.Limit - i - 1; res.Remaining != want {
+				t.Errorf("request %d: got remaining %d, want %d", i+1, res.Remaining, want)
+			}
+		}
+		res := allow(t, s, "1.2.3.4", testPolicy)
+		if res.Allowed {
+			t.Fatal("expected request over the limit to be rejected")
+		}
+		if res.RetryAfter != 20*time.Second {
+			t.Errorf("got retry after %v, want 20s", res.RetryAfter)
+		}
+	})
+
+	t.Run("refills over time", func(t *testing.T) {
+		clock := &fakeClock{now: time.Unix(1000, 0)}
+		s := newStore(t, clock)
+		for range testPolicy.Limit {
+			allow(t, s, "1.2.3.4", testPolicy)
+		}
+		clock.Advance(20 * time.Second)
+		if !allow(t, s, "1.2.3.4", testPolicy).Allowed {
+			t.Error("expected a token after one refill interval")
+		}
+		if allow(t, s, "1.2.3.4", testPolicy).Allowed {
+			t.Error("expected only one token to have refilled")
+		}
+	})
+
+	t.Run("keys and policies are independent", func(t *testing.T) {
+		s := newStore(t, &fakeClock{now: time.Unix(1000, 0)})
+		for range testPolicy.Limit {
+			allow(t, s, "1.2.3.4", testPolicy)
+		}
+		if !allow(t, s, "5.6.7.8", testPolicy).Allowed {
+			t.Error("expected other key to be allowed")
+		}
+		other := Policy{Name: "other", Limit: 1, Period: time.Minute}
+		if !allow(t, s, "1.2.3.4", other).Allowed {
+			t.Error("expected same key under another policy to be allowed")
+		}
+	})
+}
+
+func TestMemoryStoreConcurrent(t *testing.T) {
+	s := newTestMemoryStore(&fakeClock{now: time.Unix(1000, 0)})
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	allowed := 0
+	for range 50 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			res, _ := s.Allow(context.Background(), "1.2.3.4", testPolicy)
+			if res.Allowed {
+				mu.Lock()
+				allowed++
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+	if allowed != testPolicy.Limit {
+		t.Errorf("got %d allowed, want %d", allowed, testPolicy.Limit)
+	}
+}
+
+func TestMemoryStoreSweep(t *testing.T) {
+	clock := &fakeClock{now: time.Unix(1000, 0)}
+	s := NewMemoryStore()
+	s.now = clock.Now
+	s.Allow(context.Background(), "1.2.3.4", testPolicy)
+	clock.Advance(2 * time.Minute)
+	s.Allow(context.Background(), "5.6.7.8", testPolicy)
+
+	if _, ok := s.buckets[testPolicy.bucketKey("1.2.3.4")]; ok {
+		t.Error("expected refilled bucket to be swept")
+	}
+	if len(s.buckets) != 1 {
+		t.Errorf("got %d buckets, want 1", len(s.buckets))
+	}
+}
+
+func TestDBStorePrune(t *testing.T) {
+	clock := &fakeClock{now: time.Unix(1000, 0)}
+	s := newTestDBStore(t, clock)
+	ctx := context.Background()
+	s.Allow(ctx, "1.2.3.4", testPolicy)
+	clock.Advance(time.Minute)
+	s.Allow(ctx, "5.6.7.8", testPolicy)
+
+	n, err := s.Prune(ctx)
+	if err != nil {
+		t.Fatalf("Prune failed: %v", err)
+	}
+	if n != 1 {
+		t.Errorf("got %d pruned, want 1", n)
+	}
+}
+
+func TestUnlimited(t *testing.T) {
+	res, err := Unlimited().Allow(context.Background(), "1.2.3.4", testPolicy)
+	if err != nil || !res.Allowed {
+		t.Errorf("got %+v, %v; want allowed", res, err)
+	}
+}
@@ -4,77 +4,187 @@ import (
 	"context"
 	"errors"
 	"net/http"
+	"strings"
+	"time"
 
-	"myapp/config"
+	"myapp/metrics"
 	"myapp/model"
 	"myapp/util"
 
 	"github.com/google/uuid"
 	"golang.org/x/crypto/bcrypt"
+	"golang.org/x/text/unicode/norm"
 )
 
 var (
-	ErrEmailTaken         = errors.New("email already taken")
-	ErrHandleTaken        = errors.New("handle already taken")
-	ErrHandleInvalid      = errors.New("handle invalid")
-	ErrInvalidCredentials = errors.New("invalid credentials")
+	ErrEmailTaken         = util.Conflict("error.emailTaken").ForField("email")
+	ErrHandleTaken        = util.Conflict("error.handleTaken").ForField("handle")
+	ErrHandleInvalid      = util.Invalid("handle", "error.handleInvalid")
+	ErrInvalidCredentials = util.Unauthorized("error.invalidCredentials")
+	ErrAccountSuspended   = util.Forbidden("error.accountSuspended")
 )
 
 type AuthService struct {
-	repo *model.UserRepository
+	repo      *model.UserRepository
+	jwtSecret string
+	handles   *HandlePolicy
+	emails    EmailPolicy
+	tokens    *TokenService
+	metrics   *metrics.Metrics
 }
 
-func NewAuthService(repo *model.UserRepository) *AuthService {
-	return &AuthService{repo: repo}
+// NewAuthService creates the auth service. handles may be nil to reserve
+// only the built-in handles, tokens may be nil to disable personal access
+// tokens and m may be nil to disable metrics.
+func NewAuthService(repo *model.UserRepository, jwtSecret string, handles *HandlePolicy, emails EmailPolicy, tokens *TokenService, m *metrics.Metrics) *AuthService {
+	return &AuthService{repo: repo, jwtSecret: jwtSecret, handles: handles, emails: emails, tokens: tokens, metrics: m}
 }
 
-func (s *AuthService) Signup(ctx context.Context, email, password, handle string) (string, error) {
-	if !model.HandleRegex.MatchString(handle) {
-		return "", ErrHandleInvalid
+// uniqueConflict turns a unique index rejecting a user, because another
+// request claimed the email or handle after the checks passed, into
+// ErrEmailTaken or ErrHandleTaken.
+func uniqueConflict(err error) error {
+	switch {
+	case errors.Is(err, model.ErrDuplicateEmail):
+		return ErrEmailTaken
+	case errors.Is(err, model.ErrDuplicateHandle):
+		return ErrHandleTaken
 	}
+	return err
+}
 
-	if exists, _ := s.repo.ExistsByEmail(ctx, email); exists {
-		return "", ErrEmailTaken
-	}
+// Identity is the authenticated caller of an API request.
+type Identity struct {
+	User *model.User
+	// Token is the personal access token used, or nil for a session.
+	Token *model.PersonalAccessToken
+}
+
+// Can reports whether the caller holds scope. Sessions hold every scope.
+func (i *Identity) Can(scope string) bool {
+	return i.Token == nil || i.Token.HasScope(scope)
+}
 
-	if _, err := s.repo.GetByHandle(ctx, handle); err == nil {
-		return "", ErrHandleTaken
+func (s *AuthService) Signup(ctx context.Context, email, password, handle string) (token string, err error) {
+	defer func() { s.metrics.ObserveSignup(err) }()
+
+	email = norm.NFC.String(strings.TrimSpace(email))
+	normalized, err := s.emails.Normalize(email)
+	if err != nil {
+		return "", err
+	}
+	handle = model.NormalizeHandle(handle)
+	if err := s.handles.Check(handle); err != nil {
+		return "", err
 	}
 
+	// Hash before the transaction so it does not hold the write lock.
 	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
 	if err != nil {
 		return "", err
 	}
+	user := &model.User{Email: email, EmailNormalized: &normalized, PasswordHash: string(hash), Name: handle}
 
-	user := &model.User{Email: email, PasswordHash: string(hash), Name: handle}
-	if err := s.repo.Create(ctx, user); err != nil {
-		return "", err
+	// The checks give friendly errors; the unique indexes catch the signups
+	// that race past them.
+	err = s.repo.Transaction(ctx, func(repo *model.UserRepository) error {
+		exists, err := repo.ExistsByNormalizedEmail(ctx, normalized)
+		if err != nil {
+			return err
+		}
+		if exists {
+			return ErrEmailTaken
+		}
+		if err := checkHandleAvailable(ctx, repo, handle, uuid.Nil, time.Now()); err != nil {
+			return err
+		}
+		return repo.Create(ctx, user)
+	})
+	if err != nil {
+		return "", uniqueConflict(err)
 	}
+	util.Logger(ctx).Info("user signed up", "user_id", user.ID.String())
 
-	return signToken(user.ID)
+	return s.signToken(user.ID)
 }
 
-func (s *AuthService) Login(ctx context.Context, email, password string) (string, error) {
-	user, err := s.repo.GetByEmail(ctx, email)
+func (s *AuthService) Login(ctx context.Context, email, password string) (token string, err error) {
+	defer func() { s.metrics.ObserveLogin(err) }()
+
+	normalized, err := s.emails.Normalize(email)
 	if err != nil {
+		util.Logger(ctx).Info("login failed", "reason", "invalid email")
+		return "", ErrInvalidCredentials
+	}
+	candidates, err := s.repo.ListByEmail(ctx, normalized, strings.TrimSpace(email))
+	if err != nil {
+		return "", err
+	}
+	if len(candidates) == 0 {
+		util.Logger(ctx).Info("login failed", "reason", "unknown email")
 		return "", ErrInvalidCredentials
 	}
 
-	if err := bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte(password)); err != nil {
+	// Usually there is one candidate. Accounts that differ only in the case
+	// of their email predate normalization, and the password tells them apart.
+	var user *model.User
+	for i := range candidates {
+		if bcrypt.CompareHashAndPassword([]byte(candidates[i].PasswordHash), []byte(password)) == nil {
+			user = &candidates[i]
+			break
+		}
+	}
+	if user == nil {
+		util.Logger(ctx).Info("login failed", "reason", "wrong password", "user_id", candidates[0].ID.String())
 		return "", ErrInvalidCredentials
 	}
+	// Checked after the password, so the error tells nobody but the owner
+	// that the account exists.
+	if user.Suspended() {
+		util.Logger(ctx).Info("login failed", "reason", "suspended", "user_id", user.ID.String())
+		return "", ErrAccountSuspended
+	}
 
-	return signToken(user.ID)
+	return s.signToken(user.ID)
 }
 
+// Authenticate resolves the caller of an API request. Besides everything
+// GetUserFromRequest accepts, it takes personal access tokens as bearer
+// tokens; callers must then check scopes with Identity.Can.
+func (s *AuthService) Authenticate(r *http.Request) *Identity {
+	if token := bearerToken(r); s.tokens != nil && strings.HasPrefix(token, TokenPrefix) {
+		user, pat, err := s.tokens.Authenticate(r.Context(), token)
+		if err != nil || user.Suspended() {
+			return nil
+		}
+		util.SetRequestUserID(r.Context(), user.ID.String())
+		return &Identity{User: user, Token: pat}
+	}
+	if user := s.GetUserFromRequest(r); user != nil {
+		return &Identity{User: user}
+	}
+	return nil
+}
+
+// GetUserFromRequest resolves the signed-in user from the session cookie or
+// a session JWT sent as "Authorization: Bearer". Personal access tokens are
+// not accepted here, since form and page routes do not check scopes.
+// Sessions of suspended users are rejected, signing them out.
 func (s *AuthService) GetUserFromRequest(r *http.Request) *model.User {
-	cookie, err := r.Cookie("session")
-	if err != nil {
+	token := bearerToken(r)
+	if strings.HasPrefix(token, TokenPrefix) {
 		return nil
 	}
+	if token == "" {
+		cookie, err := r.Cookie("session")
+		if err != nil {
+			return nil
+		}
+		token = cookie.Value
+	}
 
-	claims, appErr := util.ParseJwt(config.Env.JWT_SECRET, cookie.Value)
-	if appErr != nil {
+	claims, err := util.ParseJwt(s.jwtSecret, token)
+	if err != nil {
 		return nil
 	}
 
@@ -89,18 +199,68 @@ func (s *AuthService) GetUserFromRequest(r *http.Request) *model.User {
 	}
 
 	user, err := s.repo.GetByID(r.Context(), userID.String())
-	if err != nil {
+	if err != nil || user.Suspended() {
 		return nil
 	}
+	util.SetRequestUserID(r.Context(), user.ID.String())
 	return user
 }
 
-func signToken(userID uuid.UUID) (string, error) {
-	token, appErr := util.SignJwt(config.Env.JWT_SECRET, map[string]any{
+func bearerToken(r *http.Request) string {
+	scheme, token, ok := strings.Cut(r.Header.Get("Authorization"), " ")
+	if !ok || !strings.EqualFold(scheme, "Bearer") {
+		return ""
+	}
+	return strings.TrimSpace(token)
+}
+
+func (s *AuthService) signToken(userID uuid.UUID) (string, error) {
+	return util.SignJwt(s.jwtSecret, map[string]any{
 		"sub": userID.String(),
 	})
-	if appErr != nil {
-		return "", appErr.Error
+}
+
+// backfillBatch is how many users NormalizeStoredEmails loads at a time.
+const backfillBatch = 500
+
+// NormalizeStoredEmails brings every user's normalized email, deleted users
+// included, in line with the email policy. The migration that added the
+// column could only fold ASCII case in SQL, and the policy may have changed
+// since. Users whose email would collide with another account's keep their
+// current value and are logged for an operator to resolve, as are emails the
+// policy rejects; updated and conflicts count the users changed and kept.
+func (s *AuthService) NormalizeStoredEmails(ctx context.Context) (updated, conflicts int, err error) {
+	logger := util.Logger(ctx)
+	for after := uuid.Nil; ; {
+		users, err := s.repo.ListEmails(ctx, after, backfillBatch)
+		if err != nil {
+			return updated, conflicts, err
+		}
+		if len(users) == 0 {
+			return updated, conflicts, nil
+		}
+		after = users[len(users)-1].ID
+
+		for _, u := range users {
+			normalized, err := s.emails.Normalize(u.Email)
+			if err != nil {
+				logger.Warn("stored email cannot be normalized", "user_id", u.ID)
+				conflicts++
+				continue
+			}
+			if u.EmailNormalized != nil && *u.EmailNormalized == normalized {
+				continue
+			}
+			err = s.repo.SetNormalizedEmail(ctx, u.ID, normalized)
+			if errors.Is(err, model.ErrDuplicateEmail) {
+				logger.Error("normalized email belongs to another account; resolve by hand", "user_id", u.ID)
+				conflicts++
+				continue
+			}
+			if err != nil {
+				return updated, conflicts, err
+			}
+			updated++
+		}
 	}
-	return *token, nil
 }
@@ -3,17 +3,27 @@ package services
 import (
 	"context"
 	"errors"
+	"fmt"
 	"net/http"
 	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"sync"
 	"testing"
+	"time"
 
+	"myapp/config"
+	"myapp/metrics"
 	"myapp/model"
 	"myapp/testutil"
+	"myapp/util"
+
+	"golang.org/x/crypto/bcrypt"
 )
 
 func newTestService(t *testing.T) *AuthService {
 	t.Helper()
-	return NewAuthService(model.NewUserRepository(testutil.NewTestDB(t, &model.User{})))
+	return NewAuthService(model.NewUserRepository(testutil.NewTestDB(t, &model.User{}, &model.HandleHistory{})), "test-secret", nil, EmailPolicy{}, nil, nil)
 }
 
 func TestSignup(t *testing.T) {
@@ -55,6 +65,141 @@ func TestSignup(t *testing.T) {
 			t.Errorf("expected ErrHandleInvalid, got %v", err)
 		}
 	})
+
+	t.Run("handles are case-insensitive", func(t *testing.T) {
+		svc := newTestService(t)
+		if _, err := svc.Signup(ctx, "user1@example.com", "password123", " TestUser "); err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+		if _, err := svc.repo.GetByHandle(ctx, "testuser"); err != nil {
+			t.Errorf("expected the handle stored in lower case, got %v", err)
+		}
+		_, err := svc.Signup(ctx, "user2@example.com", "password123", "TESTUSER")
+		if !errors.Is(err, ErrHandleTaken) {
+			t.Errorf("expected ErrHandleTaken, got %v", err)
+		}
+	})
+
+	t.Run("emails are compared normalized", func(t *testing.T) {
+		svc := newTestService(t)
+		if _, err := svc.Signup(ctx, " Bob@Example.com ", "password123", "user1hnd"); err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+		_, err := svc.Signup(ctx, "bob@example.COM", "password123", "user2hnd")
+		if !errors.Is(err, ErrEmailTaken) {
+			t.Errorf("expected ErrEmailTaken, got %v", err)
+		}
+		user, _ := svc.repo.GetByHandle(ctx, "user1hnd")
+		if user.Email != "Bob@Example.com" {
+			t.Errorf("expected the email kept as typed, got %q", user.Email)
+		}
+	})
+
+	t.Run("plus tags are one account when stripped", func(t *testing.T) {
+		svc := newTestService(t)
+		svc.emails = EmailPolicy{StripPlusTags: true}
+		_, _ = svc.Signup(ctx, "bob@example.com", "password123", "user1hnd")
+		_, err := svc.Signup(ctx, "bob+alt@example.com", "password123", "user2hnd")
+		if !errors.Is(err, ErrEmailTaken) {
+			t.Errorf("expected ErrEmailTaken, got %v", err)
+		}
+	})
+
+	t.Run("invalid email", func(t *testing.T) {
+		svc := newTestService(t)
+		_, err := svc.Signup(ctx, "Bob <bob@example.com>", "password123", "testuser")
+		if !errors.Is(err, ErrEmailInvalid) {
+			t.Errorf("expected ErrEmailInvalid, got %v", err)
+		}
+	})
+
+	t.Run("reserved handle", func(t *testing.T) {
+		svc := newTestService(t)
+		_, err := svc.Signup(ctx, "user@example.com", "password123", "Admin")
+		if !errors.Is(err, ErrHandleReserved) {
+			t.Errorf("expected ErrHandleReserved, got %v", err)
+		}
+	})
+}
+
+func TestSignupRace(t *testing.T) {
+	ctx := context.Background()
+	// In-memory databases are private to each pooled connection, so racing
+	// signups need a file.
+	db, err := util.NewDatabase(ctx, config.DBConfig{
+		DSN:             "file:" + filepath.Join(t.TempDir(), "race.db"),
+		MaxOpenConns:    8,
+		MaxIdleConns:    8,
+		ConnMaxLifetime: time.Minute,
+		BusyTimeout:     5 * time.Second,
+	})
+	if err != nil {
+		t.Fatalf("NewDatabase failed: %v", err)
+	}
+	t.Cleanup(func() { _ = util.CloseDatabase(db) })
+	if err := db.AutoMigrate(&model.User{}, &model.HandleHistory{}); err != nil {
+		t.Fatalf("AutoMigrate failed: %v", err)
+	}
+	svc := NewAuthService(model.NewUserRepository(db), "test-secret", nil, EmailPolicy{}, nil, nil)
+
+	race := func(t *testing.T, signup func(i int) error, lost error) {
+		t.Helper()
+		const n = 8
+		errs := make([]error, n)
+		var wg sync.WaitGroup
+		for i := range n {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				errs[i] = signup(i)
+			}()
+		}
+		wg.Wait()
+
+		won := 0
+		for _, err := range errs {
+			switch {
+			case err == nil:
+				won++
+			case !errors.Is(err, lost):
+				t.Errorf("expected %v, got %v", lost, err)
+			}
+		}
+		if won != 1 {
+			t.Errorf("expected exactly one signup to win, got %d", won)
+		}
+	}
+
+	t.Run("same email", func(t *testing.T) {
+		race(t, func(i int) error {
+			email := "same@example.com"
+			if i%2 == 1 {
+				email = "Same@Example.com"
+			}
+			_, err := svc.Signup(ctx, email, "password123", fmt.Sprintf("email%d", i))
+			return err
+		}, ErrEmailTaken)
+	})
+
+	t.Run("same handle", func(t *testing.T) {
+		race(t, func(i int) error {
+			_, err := svc.Signup(ctx, fmt.Sprintf("handle%d@example.com", i), "password123", "samehandle")
+			return err
+		}, ErrHandleTaken)
+	})
+}
+
+func TestUniqueConflict(t *testing.T) {
+	cases := map[error]error{
+		fmt.Errorf("create: %w", model.ErrDuplicateEmail):  ErrEmailTaken,
+		fmt.Errorf("create: %w", model.ErrDuplicateHandle): ErrHandleTaken,
+		model.ErrNotFound: model.ErrNotFound,
+	}
+	for in, want := range cases {
+		if got := uniqueConflict(in); !errors.Is(got, want) {
+			t.Errorf("uniqueConflict(%v) = %v, want %v", in, got, want)
+		}
+	}
 }
 
 func TestLogin(t *testing.T) {
@@ -90,6 +235,66 @@ func TestLogin(t *testing.T) {
 			t.Errorf("expected ErrInvalidCredentials, got %v", err)
 		}
 	})
+
+	t.Run("email case does not matter", func(t *testing.T) {
+		svc := newTestService(t)
+		_, _ = svc.Signup(ctx, "User@Example.com", "password123", "testuser")
+		if _, err := svc.Login(ctx, " user@EXAMPLE.com", "password123"); err != nil {
+			t.Errorf("expected no error, got %v", err)
+		}
+	})
+
+	t.Run("accounts that predate normalization are told apart by password", func(t *testing.T) {
+		svc := newTestService(t)
+		// As left by the migration: the newer account has no normalized email.
+		older := &model.User{Email: "bob@example.com", Name: "older"}
+		newer := &model.User{Email: "Bob@example.com", Name: "newer"}
+		for user, password := range map[*model.User]string{older: "older-password", newer: "newer-password"} {
+			hash, _ := bcrypt.GenerateFromPassword([]byte(password), bcrypt.MinCost)
+			user.PasswordHash = string(hash)
+		}
+		normalized := "bob@example.com"
+		older.EmailNormalized = &normalized
+		_ = svc.repo.Create(ctx, older)
+		_ = svc.repo.Create(ctx, newer)
+
+		for password, want := range map[string]*model.User{"older-password": older, "newer-password": newer} {
+			token, err := svc.Login(ctx, "BOB@example.com", password)
+			if err != nil {
+				t.Fatalf("Login with %s failed: %v", password, err)
+			}
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			req.AddCookie(&http.Cookie{Name: "session", Value: token})
+			if got := svc.GetUserFromRequest(req); got == nil || got.ID != want.ID {
+				t.Errorf("%s: expected user %s, got %v", password, want.Name, got)
+			}
+		}
+	})
+
+	t.Run("suspended account", func(t *testing.T) {
+		svc := newTestService(t)
+		_, _ = svc.Signup(ctx, "user@example.com", "password123", "testuser")
+		suspend(t, svc, "user@example.com")
+
+		if _, err := svc.Login(ctx, "user@example.com", "password123"); !errors.Is(err, ErrAccountSuspended) {
+			t.Errorf("expected ErrAccountSuspended, got %v", err)
+		}
+		if _, err := svc.Login(ctx, "user@example.com", "wrongpassword"); !errors.Is(err, ErrInvalidCredentials) {
+			t.Errorf("expected a wrong password to say nothing of the suspension, got %v", err)
+		}
+	})
+}
+
+// suspend suspends the user with email, as an admin would.
+func suspend(t *testing.T, svc *AuthService, email string) {
+	t.Helper()
+	user, err := svc.repo.GetByEmail(context.Background(), email)
+	if err != nil {
+		t.Fatalf("GetByEmail failed: %v", err)
+	}
+	if err := svc.repo.Suspend(context.Background(), user.ID, time.Now()); err != nil {
+		t.Fatalf("Suspend failed: %v", err)
+	}
 }
 
 func TestGetUserFromRequest(t *testing.T) {
@@ -110,6 +315,15 @@ func TestGetUserFromRequest(t *testing.T) {
 		}
 	})
 
+	t.Run("bearer token returns user", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+
+		if user := svc.GetUserFromRequest(req); user == nil || user.Email != "user@example.com" {
+			t.Errorf("expected user from bearer token, got %v", user)
+		}
+	})
+
 	t.Run("invalid token returns nil", func(t *testing.T) {
 		req := httptest.NewRequest(http.MethodGet, "/", nil)
 		req.AddCookie(&http.Cookie{Name: "session", Value: "not.a.valid.token"})
@@ -125,4 +339,147 @@ func TestGetUserFromRequest(t *testing.T) {
 			t.Error("expected nil for missing cookie")
 		}
 	})
+
+	t.Run("suspended user returns nil", func(t *testing.T) {
+		suspend(t, svc, "user@example.com")
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.AddCookie(&http.Cookie{Name: "session", Value: token})
+
+		if svc.GetUserFromRequest(req) != nil {
+			t.Error("expected the session of a suspended user to be rejected")
+		}
+	})
+}
+
+func TestAuthenticate(t *testing.T) {
+	ctx := context.Background()
+	tokens, svc := newTestTokenService(t)
+	session, _ := svc.Signup(ctx, "user@example.com", "password123", "testuser")
+	user, _ := svc.repo.GetByEmail(ctx, "user@example.com")
+	pat, _, _ := tokens.Create(ctx, user.ID.String(), "ci", []string{ScopeProfileRead}, 0)
+
+	bearer := func(token string) *http.Request {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		return req
+	}
+
+	t.Run("session holds every scope", func(t *testing.T) {
+		id := svc.Authenticate(bearer(session))
+		if id == nil || id.Token != nil || !id.Can(ScopeProfileWrite) {
+			t.Errorf("expected session identity with full access, got %+v", id)
+		}
+	})
+
+	t.Run("access token is limited to its scopes", func(t *testing.T) {
+		id := svc.Authenticate(bearer(pat))
+		if id == nil || id.User.ID != user.ID {
+			t.Fatalf("expected identity for token owner, got %+v", id)
+		}
+		if !id.Can(ScopeProfileRead) || id.Can(ScopeProfileWrite) {
+			t.Error("expected token to hold profile:read only")
+		}
+	})
+
+	t.Run("access token rejected outside the API", func(t *testing.T) {
+		if svc.GetUserFromRequest(bearer(pat)) != nil {
+			t.Error("expected GetUserFromRequest to reject access tokens")
+		}
+	})
+
+	t.Run("invalid access token", func(t *testing.T) {
+		if svc.Authenticate(bearer(TokenPrefix+"nope")) != nil {
+			t.Error("expected nil identity for unknown token")
+		}
+	})
+
+	t.Run("suspended user's access token", func(t *testing.T) {
+		suspend(t, svc, "user@example.com")
+		if svc.Authenticate(bearer(pat)) != nil || svc.Authenticate(bearer(session)) != nil {
+			t.Error("expected a suspended user to be rejected")
+		}
+	})
+}
+
+func TestAuthMetrics(t *testing.T) {
+	ctx := context.Background()
+	m := metrics.New()
+	svc := NewAuthService(model.NewUserRepository(testutil.NewTestDB(t, &model.User{}, &model.HandleHistory{})), "test-secret", nil, EmailPolicy{}, nil, m)
+
+	_, _ = svc.Signup(ctx, "user@example.com", "password123", "testuser")
+	_, _ = svc.Signup(ctx, "user@example.com", "password123", "otheruser")
+	_, _ = svc.Login(ctx, "user@example.com", "password123")
+	_, _ = svc.Login(ctx, "user@example.com", "wrongpassword")
+
+	w := httptest.NewRecorder()
+	m.Handler().ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	body := w.Body.String()
+	for _, want := range []string{
+		`myapp_auth_signups_total{result="success"} 1`,
+		`myapp_auth_signups_total{result="failure"} 1`,
+		`myapp_auth_logins_total{result="success"} 1`,
+		`myapp_auth_logins_total{result="failure"} 1`,
+	} {
+		if !strings.Contains(body, want) {
+			t.Errorf("metrics output missing %q", want)
+		}
+	}
+}
+
+func TestNormalizeStoredEmails(t *testing.T) {
+	ctx := context.Background()
+	repo := model.NewUserRepository(testutil.NewTestDB(t, &model.User{}))
+	svc := NewAuthService(repo, "test-secret", nil, EmailPolicy{StripPlusTags: true}, nil, nil)
+
+	// Seed what the SQL backfill left: lower(trim(email)), ASCII only.
+	seed := func(email, backfilled, handle string) *model.User {
+		u := &model.User{Email: email, EmailNormalized: &backfilled, PasswordHash: "x", Name: handle}
+		if err := repo.Create(ctx, u); err != nil {
+			t.Fatalf("Create failed: %v", err)
+		}
+		return u
+	}
+	accented := seed("ÉMILE@Example.com", "Émile@example.com", "emile")
+	decomposed := seed("Zoé@example.com", "zoé@example.com", "zoe")
+	tagged := seed("bob+news@example.com", "bob+news@example.com", "bob")
+	seed("plain@example.com", "plain@example.com", "plain")
+	seed("émile@example.com", "émile@example.com", "emile2")
+
+	updated, conflicts, err := svc.NormalizeStoredEmails(ctx)
+	if err != nil {
+		t.Fatalf("NormalizeStoredEmails failed: %v", err)
+	}
+	// ÉMILE collides with the émile account and keeps its value; Zoé is
+	// recomposed and bob loses the plus tag.
+	if updated != 2 || conflicts != 1 {
+		t.Errorf("expected 2 updated and 1 conflict, got %d and %d", updated, conflicts)
+	}
+
+	want := map[string]string{
+		accented.ID.String():   "Émile@example.com",
+		decomposed.ID.String(): "zoé@example.com",
+		tagged.ID.String():     "bob@example.com",
+	}
+	for id, normalized := range want {
+		u, err := repo.GetByID(ctx, id)
+		if err != nil {
+			t.Fatalf("GetByID failed: %v", err)
+		}
+		if *u.EmailNormalized != normalized {
+			t.Errorf("%s: expected %q, got %q", u.Name, normalized, *u.EmailNormalized)
+		}
+	}
+
+	t.Run("new signups find the backfilled accounts", func(t *testing.T) {
+		if _, err := svc.Signup(ctx, "ZOÉ@example.com", "password123", "zoe2"); !errors.Is(err, ErrEmailTaken) {
+			t.Errorf("expected ErrEmailTaken, got %v", err)
+		}
+	})
+
+	t.Run("a second run changes nothing", func(t *testing.T) {
+		updated, conflicts, err := svc.NormalizeStoredEmails(ctx)
+		if err != nil || updated != 0 || conflicts != 1 {
+			t.Errorf("expected only the standing conflict, got %d, %d, %v", updated, conflicts, err)
+		}
+	})
 }
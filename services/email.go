@@ -0,0 +1,39 @@
+package services
+
+import (
+	"net/mail"
+	"strings"
+
+	"myapp/util"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+var ErrEmailInvalid = util.Invalid("email", "error.emailInvalid")
+
+// EmailPolicy decides when two email addresses belong to the same account.
+type EmailPolicy struct {
+	// StripPlusTags treats bob+news@example.com as bob@example.com.
+	StripPlusTags bool
+}
+
+// Normalize returns the form of email that is compared for uniqueness and
+// login: NFC, lower case, and without a plus tag if the policy strips them.
+// email must be a bare address that net/mail accepts, without a display name
+// or angle brackets; anything else is ErrEmailInvalid.
+func (p EmailPolicy) Normalize(email string) (string, error) {
+	email = norm.NFC.String(strings.TrimSpace(email))
+	addr, err := mail.ParseAddress(email)
+	if err != nil || addr.Address != email {
+		return "", ErrEmailInvalid
+	}
+
+	at := strings.LastIndex(email, "@")
+	local, domain := strings.ToLower(email[:at]), strings.ToLower(email[at+1:])
+	if p.StripPlusTags {
+		if tagless, _, _ := strings.Cut(local, "+"); tagless != "" {
+			local = tagless
+		}
+	}
+	return local + "@" + domain, nil
+}
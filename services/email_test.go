@@ -0,0 +1,35 @@
+package services
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestEmailPolicyNormalize(t *testing.T) {
+	keep := EmailPolicy{}
+	strip := EmailPolicy{StripPlusTags: true}
+	cases := []struct {
+		policy EmailPolicy
+		email  string
+		want   string
+	}{
+		{keep, "  Bob@Example.COM ", "bob@example.com"},
+		{keep, "bob+news@example.com", "bob+news@example.com"},
+		{strip, "Bob+News@example.com", "bob@example.com"},
+		{strip, "+only@example.com", "+only@example.com"},
+		// "é" typed as e + combining acute accent composes to U+00E9.
+		{keep, "Jose\u0301@example.com", "jos\u00e9@example.com"},
+	}
+	for _, tc := range cases {
+		got, err := tc.policy.Normalize(tc.email)
+		if err != nil || got != tc.want {
+			t.Errorf("Normalize(%q) = %q, %v, want %q", tc.email, got, err, tc.want)
+		}
+	}
+
+	for _, email := range []string{"", "bob", "bob@", "@example.com", "Bob <bob@example.com>", "<bob@example.com>", "bob@example.com, eve@example.com"} {
+		if _, err := keep.Normalize(email); !errors.Is(err, ErrEmailInvalid) {
+			t.Errorf("Normalize(%q): expected ErrEmailInvalid, got %v", email, err)
+		}
+	}
+}
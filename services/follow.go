@@ -0,0 +1,184 @@
+package services
+
+import (
+	"context"
+
+	"myapp/model"
+	"myapp/util"
+
+	"github.com/google/uuid"
+)
+
+// FollowsPerPage is the page size of the follower and following lists.
+const FollowsPerPage = 20
+
+var (
+	ErrFollowSelf = util.BadRequest("error.followSelf")
+	ErrBlockSelf  = util.BadRequest("error.blockSelf")
+	// ErrBlocked is returned when a block between two users stands in the
+	// way of a follow.
+	ErrBlocked = util.Forbidden("error.blocked")
+)
+
+// FollowStats counts a user's followers and the users they follow.
+type FollowStats struct {
+	Followers int64
+	Following int64
+}
+
+// FollowPage is one page of a follower or following list. Page starts at 1.
+type FollowPage struct {
+	Users   []model.User
+	Page    int
+	HasNext bool
+}
+
+type FollowService struct {
+	follows *model.FollowRepository
+	blocks  *model.BlockRepository
+	users   *model.UserRepository
+}
+
+func NewFollowService(follows *model.FollowRepository, blocks *model.BlockRepository, users *model.UserRepository) *FollowService {
+	return &FollowService{follows: follows, blocks: blocks, users: users}
+}
+
+// Follow makes follower follow the user with handle. Following someone
+// already followed is not an error. Deleted users cannot be followed, and
+// neither can the follower themselves or anyone on either side of a block.
+func (s *FollowService) Follow(ctx context.Context, follower *model.User, handle string) error {
+	followee, err := s.users.GetByHandle(ctx, handle)
+	if err != nil {
+		return err
+	}
+	if followee.ID == follower.ID {
+		return ErrFollowSelf
+	}
+	for _, pair := range [][2]*model.User{{followee, follower}, {follower, followee}} {
+		blocked, err := s.HasBlocked(ctx, pair[0], pair[1])
+		if err != nil {
+			return err
+		}
+		if blocked {
+			return ErrBlocked
+		}
+	}
+	if err := s.follows.Create(ctx, follower.ID, followee.ID); err != nil {
+		return err
+	}
+	util.Logger(ctx).Info("user followed", "user_id", follower.ID, "followee_id", followee.ID)
+	return nil
+}
+
+// Unfollow stops follower following the user with handle, if they did.
+func (s *FollowService) Unfollow(ctx context.Context, follower *model.User, handle string) error {
+	followee, err := s.users.GetByHandle(ctx, handle)
+	if err != nil {
+		return err
+	}
+	if err := s.follows.Delete(ctx, follower.ID, followee.ID); err != nil {
+		return err
+	}
+	util.Logger(ctx).Info("user unfollowed", "user_id", follower.ID, "followee_id", followee.ID)
+	return nil
+}
+
+// IsFollowing reports whether follower follows user. A nil follower, the
+// signed-out visitor, follows nobody.
+func (s *FollowService) IsFollowing(ctx context.Context, follower, user *model.User) (bool, error) {
+	if follower == nil || follower.ID == user.ID {
+		return false, nil
+	}
+	return s.follows.Exists(ctx, follower.ID, user.ID)
+}
+
+// Block makes blocker block the user with handle and ends any follow
+// between the two, in either direction. Blocking someone already blocked is
+// not an error.
+func (s *FollowService) Block(ctx context.Context, blocker *model.User, handle string) error {
+	blocked, err := s.users.GetByHandle(ctx, handle)
+	if err != nil {
+		return err
+	}
+	if blocked.ID == blocker.ID {
+		return ErrBlockSelf
+	}
+	// Block and unfollow together, so a failure cannot leave a block with
+	// the follows it should have ended.
+	err = s.blocks.Transaction(ctx, func(blocks *model.BlockRepository, follows *model.FollowRepository) error {
+		if err := blocks.Create(ctx, blocker.ID, blocked.ID); err != nil {
+			return err
+		}
+		if err := follows.Delete(ctx, blocker.ID, blocked.ID); err != nil {
+			return err
+		}
+		return follows.Delete(ctx, blocked.ID, blocker.ID)
+	})
+	if err != nil {
+		return err
+	}
+	util.Logger(ctx).Info("user blocked", "user_id", blocker.ID, "blocked_id", blocked.ID)
+	return nil
+}
+
+// Unblock lifts blocker's block on the user with handle, if there was one.
+// Follows ended by the block are not restored.
+func (s *FollowService) Unblock(ctx context.Context, blocker *model.User, handle string) error {
+	blocked, err := s.users.GetByHandle(ctx, handle)
+	if err != nil {
+		return err
+	}
+	if err := s.blocks.Delete(ctx, blocker.ID, blocked.ID); err != nil {
+		return err
+	}
+	util.Logger(ctx).Info("user unblocked", "user_id", blocker.ID, "blocked_id", blocked.ID)
+	return nil
+}
+
+// HasBlocked reports whether blocker blocked user. A nil user, the
+// signed-out visitor, cannot be blocked.
+func (s *FollowService) HasBlocked(ctx context.Context, blocker, user *model.User) (bool, error) {
+	if user == nil || blocker.ID == user.ID {
+		return false, nil
+	}
+	return s.blocks.Exists(ctx, blocker.ID, user.ID)
+}
+
+func (s *FollowService) Stats(ctx context.Context, user *model.User) (FollowStats, error) {
+	followers, err := s.follows.CountFollowers(ctx, user.ID)
+	if err != nil {
+		return FollowStats{}, err
+	}
+	following, err := s.follows.CountFollowing(ctx, user.ID)
+	if err != nil {
+		return FollowStats{}, err
+	}
+	return FollowStats{Followers: followers, Following: following}, nil
+}
+
+// Followers returns the given page of users following user, newest first.
+func (s *FollowService) Followers(ctx context.Context, user *model.User, page int) (FollowPage, error) {
+	return s.page(ctx, s.follows.ListFollowers, user, page)
+}
+
+// Following returns the given page of users user follows, newest first.
+func (s *FollowService) Following(ctx context.Context, user *model.User, page int) (FollowPage, error) {
+	return s.page(ctx, s.follows.ListFollowing, user, page)
+}
+
+type listFollows func(ctx context.Context, userID uuid.UUID, offset, limit int) ([]model.User, error)
+
+// page fetches one row past the page to learn whether another page follows.
+// Pages below 1 are treated as the first.
+func (s *FollowService) page(ctx context.Context, list listFollows, user *model.User, page int) (FollowPage, error) {
+	page = max(page, 1)
+	users, err := list(ctx, user.ID, (page-1)*FollowsPerPage, FollowsPerPage+1)
+	if err != nil {
+		return FollowPage{}, err
+	}
+	hasNext := len(users) > FollowsPerPage
+	if hasNext {
+		users = users[:FollowsPerPage]
+	}
+	return FollowPage{Users: users, Page: page, HasNext: hasNext}, nil
+}
@@ -0,0 +1,160 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"myapp/model"
+	"myapp/testutil"
+)
+
+func TestFollowService(t *testing.T) {
+	ctx := context.Background()
+	db := testutil.NewTestDB(t, &model.User{}, &model.HandleHistory{}, &model.Follow{}, &model.Block{})
+	users := model.NewUserRepository(db)
+	svc := NewFollowService(model.NewFollowRepository(db), model.NewBlockRepository(db), users)
+
+	newUser := func(handle string) *model.User {
+		u := &model.User{Email: handle + "@example.com", PasswordHash: "x", Name: handle}
+		if err := users.Create(ctx, u); err != nil {
+			t.Fatalf("Create user failed: %v", err)
+		}
+		return u
+	}
+	alice, bob := newUser("alice"), newUser("bob")
+
+	t.Run("follow and unfollow", func(t *testing.T) {
+		if err := svc.Follow(ctx, bob, "alice"); err != nil {
+			t.Fatalf("Follow failed: %v", err)
+		}
+		if ok, _ := svc.IsFollowing(ctx, bob, alice); !ok {
+			t.Error("expected bob to follow alice")
+		}
+		stats, err := svc.Stats(ctx, alice)
+		if err != nil || stats.Followers != 1 || stats.Following != 0 {
+			t.Errorf("unexpected stats %+v, %v", stats, err)
+		}
+		if err := svc.Unfollow(ctx, bob, "alice"); err != nil {
+			t.Fatalf("Unfollow failed: %v", err)
+		}
+		if ok, _ := svc.IsFollowing(ctx, bob, alice); ok {
+			t.Error("expected bob to no longer follow alice")
+		}
+	})
+
+	t.Run("cannot follow yourself", func(t *testing.T) {
+		if err := svc.Follow(ctx, alice, "alice"); !errors.Is(err, ErrFollowSelf) {
+			t.Errorf("expected ErrFollowSelf, got %v", err)
+		}
+	})
+
+	t.Run("cannot follow missing or deleted users", func(t *testing.T) {
+		if err := svc.Follow(ctx, alice, "nobody"); !errors.Is(err, model.ErrNotFound) {
+			t.Errorf("expected ErrNotFound, got %v", err)
+		}
+		gone := newUser("gone")
+		if err := users.Delete(ctx, gone.ID.String()); err != nil {
+			t.Fatalf("Delete failed: %v", err)
+		}
+		if err := svc.Follow(ctx, alice, "gone"); !errors.Is(err, model.ErrNotFound) {
+			t.Errorf("expected ErrNotFound for a deleted user, got %v", err)
+		}
+	})
+
+	t.Run("signed-out visitors follow nobody", func(t *testing.T) {
+		if ok, err := svc.IsFollowing(ctx, nil, alice); ok || err != nil {
+			t.Errorf("expected false, got %v, %v", ok, err)
+		}
+	})
+
+	t.Run("pages", func(t *testing.T) {
+		star := newUser("star")
+		for i := range FollowsPerPage + 1 {
+			if err := svc.Follow(ctx, newUser(fmt.Sprintf("fan%02d", i)), "star"); err != nil {
+				t.Fatalf("Follow failed: %v", err)
+			}
+		}
+		first, err := svc.Followers(ctx, star, 0)
+		if err != nil || first.Page != 1 || len(first.Users) != FollowsPerPage || !first.HasNext {
+			t.Fatalf("unexpected first page %d users, page %d, next %v, %v", len(first.Users), first.Page, first.HasNext, err)
+		}
+		second, _ := svc.Followers(ctx, star, 2)
+		if len(second.Users) != 1 || second.HasNext {
+			t.Errorf("expected 1 user on the last page, got %d, next %v", len(second.Users), second.HasNext)
+		}
+		following, _ := svc.Following(ctx, &first.Users[0], 1)
+		if len(following.Users) != 1 || following.Users[0].Name != "star" {
+			t.Errorf("expected the fan to follow star, got %v", following.Users)
+		}
+	})
+
+	t.Run("blocking", func(t *testing.T) {
+		carol := newUser("carol")
+		_ = svc.Follow(ctx, carol, "alice")
+		_ = svc.Follow(ctx, alice, "carol")
+		if err := svc.Block(ctx, alice, "carol"); err != nil {
+			t.Fatalf("Block failed: %v", err)
+		}
+		if err := svc.Block(ctx, alice, "carol"); err != nil {
+			t.Errorf("expected blocking twice to be a no-op, got %v", err)
+		}
+		if ok, _ := svc.HasBlocked(ctx, alice, carol); !ok {
+			t.Error("expected alice to block carol")
+		}
+		if ok, _ := svc.HasBlocked(ctx, carol, alice); ok {
+			t.Error("expected blocks to be one-way")
+		}
+		for _, pair := range [][2]*model.User{{carol, alice}, {alice, carol}} {
+			if ok, _ := svc.IsFollowing(ctx, pair[0], pair[1]); ok {
+				t.Errorf("expected %s to no longer follow %s", pair[0].Name, pair[1].Name)
+			}
+		}
+		if err := svc.Follow(ctx, carol, "alice"); !errors.Is(err, ErrBlocked) {
+			t.Errorf("expected ErrBlocked for the blocked user, got %v", err)
+		}
+		if err := svc.Follow(ctx, alice, "carol"); !errors.Is(err, ErrBlocked) {
+			t.Errorf("expected ErrBlocked for the blocker, got %v", err)
+		}
+
+		if err := svc.Unblock(ctx, alice, "carol"); err != nil {
+			t.Fatalf("Unblock failed: %v", err)
+		}
+		if err := svc.Follow(ctx, carol, "alice"); err != nil {
+			t.Errorf("expected to follow after the unblock, got %v", err)
+		}
+	})
+
+	t.Run("cannot block yourself", func(t *testing.T) {
+		if err := svc.Block(ctx, alice, "alice"); !errors.Is(err, ErrBlockSelf) {
+			t.Errorf("expected ErrBlockSelf, got %v", err)
+		}
+	})
+
+	t.Run("a failed block leaves nothing behind", func(t *testing.T) {
+		// Without a follows table, ending the follows fails after the block
+		// is written.
+		db := testutil.NewTestDB(t, &model.User{}, &model.HandleHistory{}, &model.Block{})
+		users := model.NewUserRepository(db)
+		blocks := model.NewBlockRepository(db)
+		svc := NewFollowService(model.NewFollowRepository(db), blocks, users)
+		dave := &model.User{Email: "dave@example.com", PasswordHash: "x", Name: "dave"}
+		erin := &model.User{Email: "erin@example.com", PasswordHash: "x", Name: "erin"}
+		_ = users.Create(ctx, dave)
+		_ = users.Create(ctx, erin)
+
+		if err := svc.Block(ctx, dave, "erin"); err == nil {
+			t.Fatal("expected Block to fail")
+		}
+		if ok, err := blocks.Exists(ctx, dave.ID, erin.ID); ok || err != nil {
+			t.Errorf("expected the block to be rolled back, got %v, %v", ok, err)
+		}
+	})
+
+	t.Run("signed-out visitors are never blocked", func(t *testing.T) {
+		if ok, err := svc.HasBlocked(ctx, alice, nil); ok || err != nil {
+			t.Errorf("expected false, got %v, %v", ok, err)
+		}
+	})
+}
@@ -0,0 +1,94 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"myapp/model"
+	"myapp/util"
+
+	"github.com/google/uuid"
+)
+
+const (
+	// HandleReuseCooldown is how long a released handle stays reserved for
+	// the user who gave it up, so old links keep pointing at them.
+	HandleReuseCooldown = 30 * 24 * time.Hour
+	// MaxRenames handle changes are allowed per RenameWindow.
+	MaxRenames   = 3
+	RenameWindow = 30 * 24 * time.Hour
+)
+
+// reservedHandles can never be registered: they collide with routes, or
+// could pass for the site or its staff.
+var reservedHandles = []string{
+	"about", "account", "admin", "administrator", "anonymous", "api", "app",
+	"assets", "auth", "blog", "dashboard", "docs", "edit", "everyone",
+	"followers", "following", "healthz", "help", "login", "logout", "metrics",
+	"moderator", "mod", "new", "null", "official", "openapi",
+	"readyz", "root", "security", "settings", "signup", "staff", "static",
+	"support", "system", "team", "tokens", "undefined", "uploads", "user",
+	"users", "version", "www",
+}
+
+var (
+	ErrHandleReserved    = util.Invalid("handle", "error.handleReserved")
+	ErrHandleCoolingDown = util.Conflict("error.handleCoolingDown").ForField("handle")
+	ErrRenameLimit       = util.NewError(util.KindRateLimited, "error.renameLimit").ForField("handle")
+)
+
+// HandlePolicy decides which well-formed handles may be registered.
+type HandlePolicy struct {
+	reserved map[string]bool
+}
+
+var defaultHandlePolicy = NewHandlePolicy()
+
+// NewHandlePolicy reserves the built-in handles plus extra, which are
+// normalized like user input.
+func NewHandlePolicy(extra ...string) *HandlePolicy {
+	p := &HandlePolicy{reserved: make(map[string]bool, len(reservedHandles)+len(extra))}
+	for _, handle := range append(reservedHandles, extra...) {
+		p.reserved[model.NormalizeHandle(handle)] = true
+	}
+	return p
+}
+
+// Check reports whether handle, already normalized, is well formed and not
+// reserved. A nil policy reserves only the built-in handles.
+func (p *HandlePolicy) Check(handle string) error {
+	if p == nil {
+		p = defaultHandlePolicy
+	}
+	if !model.HandleRegex.MatchString(handle) {
+		return ErrHandleInvalid
+	}
+	if p.reserved[handle] {
+		return ErrHandleReserved
+	}
+	return nil
+}
+
+// checkHandleAvailable reports whether claimant may take handle: nobody may
+// hold it, and nobody else may have released it within HandleReuseCooldown.
+// claimant is uuid.Nil for a new user.
+func checkHandleAvailable(ctx context.Context, repo *model.UserRepository, handle string, claimant uuid.UUID, now time.Time) error {
+	if _, err := repo.GetByHandle(ctx, handle); err == nil {
+		return ErrHandleTaken
+	} else if !errors.Is(err, model.ErrNotFound) {
+		return err
+	}
+
+	release, err := repo.LatestRelease(ctx, handle)
+	if errors.Is(err, model.ErrNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if release.UserID != claimant && now.Sub(release.CreatedAt) < HandleReuseCooldown {
+		return ErrHandleCoolingDown
+	}
+	return nil
+}
@@ -0,0 +1,112 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestHandlePolicy(t *testing.T) {
+	policy := NewHandlePolicy("Billing ", "press")
+	cases := []struct {
+		policy *HandlePolicy
+		handle string
+		want   error
+	}{
+		{policy, "alice", nil},
+		{policy, "ab", ErrHandleInvalid},
+		{policy, "admin", ErrHandleReserved},
+		{policy, "uploads", ErrHandleReserved},
+		{policy, "billing", ErrHandleReserved},
+		{policy, "press", ErrHandleReserved},
+		{nil, "admin", ErrHandleReserved},
+		{nil, "billing", nil},
+	}
+	for _, tc := range cases {
+		if err := tc.policy.Check(tc.handle); !errors.Is(err, tc.want) {
+			t.Errorf("Check(%q) = %v, want %v", tc.handle, err, tc.want)
+		}
+	}
+}
+
+func TestHandleChanges(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("old handles stay with their owner during the cooldown", func(t *testing.T) {
+		userSvc, authSvc := newTestUserService(t)
+		_, _ = authSvc.Signup(ctx, "alice@example.com", "password123", "alice")
+		_, _ = authSvc.Signup(ctx, "bob@example.com", "password123", "bob")
+		alice, _ := userSvc.GetByHandle(ctx, "alice")
+		bob, _ := userSvc.GetByHandle(ctx, "bob")
+
+		if err := userSvc.UpdateProfile(ctx, alice.ID.String(), UpdateProfileInput{Handle: "alice2"}); err != nil {
+			t.Fatalf("UpdateProfile failed: %v", err)
+		}
+		if moved, err := userSvc.GetByPastHandle(ctx, "alice"); err != nil || moved.Name != "alice2" {
+			t.Errorf("expected alice to point at alice2, got %v, %v", moved, err)
+		}
+
+		if _, err := authSvc.Signup(ctx, "eve@example.com", "password123", "alice"); !errors.Is(err, ErrHandleCoolingDown) {
+			t.Errorf("expected ErrHandleCoolingDown on signup, got %v", err)
+		}
+		if err := userSvc.UpdateProfile(ctx, bob.ID.String(), UpdateProfileInput{Handle: "alice"}); !errors.Is(err, ErrHandleCoolingDown) {
+			t.Errorf("expected ErrHandleCoolingDown on rename, got %v", err)
+		}
+		if err := userSvc.UpdateProfile(ctx, alice.ID.String(), UpdateProfileInput{Handle: "alice"}); err != nil {
+			t.Errorf("expected alice to reclaim her old handle, got %v", err)
+		}
+	})
+
+	t.Run("released handles are free after the cooldown", func(t *testing.T) {
+		userSvc, authSvc := newTestUserService(t)
+		_, _ = authSvc.Signup(ctx, "alice@example.com", "password123", "alice")
+		_, _ = authSvc.Signup(ctx, "bob@example.com", "password123", "bob")
+		alice, _ := userSvc.GetByHandle(ctx, "alice")
+		bob, _ := userSvc.GetByHandle(ctx, "bob")
+		_ = userSvc.UpdateProfile(ctx, alice.ID.String(), UpdateProfileInput{Handle: "alice2"})
+
+		userSvc.now = func() time.Time { return time.Now().Add(HandleReuseCooldown + time.Hour) }
+		if err := userSvc.UpdateProfile(ctx, bob.ID.String(), UpdateProfileInput{Handle: "alice"}); err != nil {
+			t.Fatalf("expected bob to claim alice, got %v", err)
+		}
+		if got, _ := userSvc.GetByHandle(ctx, "alice"); got.ID != bob.ID {
+			t.Error("expected alice to be bob's handle now")
+		}
+	})
+
+	t.Run("reserved handles cannot be taken by renaming", func(t *testing.T) {
+		userSvc, authSvc := newTestUserService(t)
+		_, _ = authSvc.Signup(ctx, "user@example.com", "password123", "someone")
+		user, _ := userSvc.GetByHandle(ctx, "someone")
+		err := userSvc.UpdateProfile(ctx, user.ID.String(), UpdateProfileInput{Handle: "Settings"})
+		if !errors.Is(err, ErrHandleReserved) {
+			t.Errorf("expected ErrHandleReserved, got %v", err)
+		}
+	})
+
+	t.Run("renames are limited", func(t *testing.T) {
+		userSvc, authSvc := newTestUserService(t)
+		_, _ = authSvc.Signup(ctx, "user@example.com", "password123", "name0")
+		user, _ := userSvc.GetByHandle(ctx, "name0")
+
+		for i := 1; i <= MaxRenames; i++ {
+			if err := userSvc.UpdateProfile(ctx, user.ID.String(), UpdateProfileInput{Handle: fmt.Sprintf("name%d", i)}); err != nil {
+				t.Fatalf("rename %d failed: %v", i, err)
+			}
+		}
+		err := userSvc.UpdateProfile(ctx, user.ID.String(), UpdateProfileInput{Handle: "onemore"})
+		if !errors.Is(err, ErrRenameLimit) {
+			t.Errorf("expected ErrRenameLimit, got %v", err)
+		}
+		if err := userSvc.UpdateProfile(ctx, user.ID.String(), UpdateProfileInput{Handle: fmt.Sprintf("name%d", MaxRenames), Bio: "still editable"}); err != nil {
+			t.Errorf("expected other fields to stay editable, got %v", err)
+		}
+
+		userSvc.now = func() time.Time { return time.Now().Add(RenameWindow + time.Hour) }
+		if err := userSvc.UpdateProfile(ctx, user.ID.String(), UpdateProfileInput{Handle: "onemore"}); err != nil {
+			t.Errorf("expected a rename once the window has passed, got %v", err)
+		}
+	})
+}
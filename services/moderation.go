@@ -0,0 +1,176 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"time"
+	"unicode/utf8"
+
+	"myapp/model"
+	"myapp/util"
+)
+
+const (
+	MaxEvidenceLength = 1000
+	// QueueSize bounds the moderation queue page; older reports come first,
+	// so nothing waits forever.
+	QueueSize = 50
+)
+
+// ReportReasons lists the accepted report reasons in the order the report
+// form offers them.
+var ReportReasons = []string{"spam", "harassment", "impersonation", "inappropriate", "other"}
+
+// Moderation actions. Each resolves a report with the matching status.
+const (
+	ActionDismiss = "dismiss"
+	ActionWarn    = "warn"
+	ActionSuspend = "suspend"
+)
+
+// ModerationActions lists the actions an admin can take on a report.
+var ModerationActions = []string{ActionDismiss, ActionWarn, ActionSuspend}
+
+var actionStatus = map[string]string{
+	ActionDismiss: model.ReportDismissed,
+	ActionWarn:    model.ReportWarned,
+	ActionSuspend: model.ReportSuspended,
+}
+
+var (
+	ErrReportSelf     = util.BadRequest("error.reportSelf")
+	ErrNotAdmin       = util.Forbidden("error.notAdmin")
+	ErrActionInvalid  = util.Invalid("action", "error.actionInvalid")
+	ErrReportNotFound = util.NotFound("error.reportNotFound")
+	ErrReportResolved = util.Conflict("error.reportResolved")
+)
+
+// ModerationService takes user reports and lets admins act on them. Admins
+// are the users whose email is among the configured admin emails.
+type ModerationService struct {
+	reports *model.ReportRepository
+	users   *model.UserRepository
+	admins  map[string]bool // normalized emails
+}
+
+// NewModerationService makes the users with adminEmails admins. Emails
+// are compared as emails normalizes them; invalid ones match nobody.
+func NewModerationService(reports *model.ReportRepository, users *model.UserRepository, emails EmailPolicy, adminEmails ...string) *ModerationService {
+	admins := make(map[string]bool, len(adminEmails))
+	for _, email := range adminEmails {
+		if normalized, err := emails.Normalize(email); err == nil {
+			admins[normalized] = true
+		}
+	}
+	return &ModerationService{reports: reports, users: users, admins: admins}
+}
+
+// IsAdmin reports whether user may work the moderation queue. A nil
+// user, the signed-out visitor, may not.
+func (s *ModerationService) IsAdmin(user *model.User) bool {
+	return user != nil && user.EmailNormalized != nil && s.admins[*user.EmailNormalized]
+}
+
+// ReportInput is what a user tells admins about another user.
+type ReportInput struct {
+	Reason   string
+	Evidence string
+}
+
+// Report files reporter's report on the user with handle into the
+// moderation queue. Deleted users cannot be reported, and neither can the
+// reporter themselves.
+func (s *ModerationService) Report(ctx context.Context, reporter *model.User, handle string, input ReportInput) error {
+	fe := fieldErrors{}
+	if !slices.Contains(ReportReasons, input.Reason) {
+		fe.add("reason", "error.reportReasonInvalid")
+	}
+	if utf8.RuneCountInString(input.Evidence) > MaxEvidenceLength {
+		fe.add("evidence", "error.evidenceTooLong")
+	}
+	if err := fe.err(); err != nil {
+		return err
+	}
+
+	reported, err := s.users.GetByHandle(ctx, handle)
+	if err != nil {
+		return err
+	}
+	if reported.ID == reporter.ID {
+		return ErrReportSelf
+	}
+	report := &model.Report{
+		ReporterID: reporter.ID,
+		ReportedID: reported.ID,
+		Reason:     input.Reason,
+		Evidence:   input.Evidence,
+		Status:     model.ReportOpen,
+	}
+	if err := s.reports.Create(ctx, report); err != nil {
+		return err
+	}
+	util.Logger(ctx).Info("user reported", "user_id", reporter.ID, "reported_id", reported.ID, "report_id", report.ID, "reason", input.Reason)
+	return nil
+}
+
+// Queue returns the oldest open reports, up to QueueSize.
+func (s *ModerationService) Queue(ctx context.Context, admin *model.User) ([]model.Report, error) {
+	if !s.IsAdmin(admin) {
+		return nil, ErrNotAdmin
+	}
+	return s.reports.ListOpen(ctx, QueueSize)
+}
+
+// Resolve closes the open report with reportID by taking action on it: a
+// warning counts against the reported user, and a suspension stops them
+// signing in. Each report is resolved once; acting on it again is
+// ErrReportResolved.
+func (s *ModerationService) Resolve(ctx context.Context, admin *model.User, reportID, action string) error {
+	if !s.IsAdmin(admin) {
+		return ErrNotAdmin
+	}
+	status, ok := actionStatus[action]
+	if !ok {
+		return ErrActionInvalid
+	}
+	report, err := s.reports.GetByID(ctx, reportID)
+	if errors.Is(err, model.ErrNotFound) {
+		return ErrReportNotFound
+	}
+	if err != nil {
+		return err
+	}
+	if report.Status != model.ReportOpen {
+		return ErrReportResolved
+	}
+
+	// Claim the report first, so that two admins acting at once do not both
+	// warn the user.
+	if err := s.reports.Resolve(ctx, report, status, admin.ID); err != nil {
+		if errors.Is(err, model.ErrNotFound) {
+			return ErrReportResolved
+		}
+		return err
+	}
+	if action != ActionDismiss {
+		if err := s.penalize(ctx, report, action); err != nil {
+			return err
+		}
+	}
+	util.Logger(ctx).Info("report resolved", "user_id", admin.ID, "report_id", report.ID, "reported_id", report.ReportedID, "action", action)
+	return nil
+}
+
+// penalize applies action to the reported user. The changes are made in
+// place in the database, so they cannot be lost to a concurrent profile save
+// or warning. Users deleted since the report are left alone.
+func (s *ModerationService) penalize(ctx context.Context, report *model.Report, action string) error {
+	switch action {
+	case ActionWarn:
+		return s.users.AddWarning(ctx, report.ReportedID)
+	case ActionSuspend:
+		return s.users.Suspend(ctx, report.ReportedID, time.Now())
+	}
+	return nil
+}
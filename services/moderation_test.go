@@ -0,0 +1,122 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"myapp/model"
+	"myapp/testutil"
+	"myapp/util"
+)
+
+func TestModerationService(t *testing.T) {
+	ctx := context.Background()
+	db := testutil.NewTestDB(t, &model.User{}, &model.HandleHistory{}, &model.Report{})
+	users := model.NewUserRepository(db)
+	reports := model.NewReportRepository(db)
+	svc := NewModerationService(reports, users, EmailPolicy{}, " Admin@Example.com", "not an email")
+
+	newUser := func(handle string) *model.User {
+		email := handle + "@example.com"
+		u := &model.User{Email: email, EmailNormalized: &email, PasswordHash: "x", Name: handle}
+		if err := users.Create(ctx, u); err != nil {
+			t.Fatalf("Create user failed: %v", err)
+		}
+		return u
+	}
+	admin, alice, bob := newUser("admin"), newUser("alice"), newUser("bob")
+
+	t.Run("admins come from the configured emails", func(t *testing.T) {
+		if !svc.IsAdmin(admin) {
+			t.Error("expected admin@example.com to be an admin")
+		}
+		if svc.IsAdmin(alice) || svc.IsAdmin(nil) {
+			t.Error("expected only the configured emails to be admins")
+		}
+	})
+
+	// report files a report by alice on bob and returns it from the queue.
+	report := func(t *testing.T, reason string) *model.Report {
+		t.Helper()
+		if err := svc.Report(ctx, alice, "bob", ReportInput{Reason: reason, Evidence: "https://example.com/post/1"}); err != nil {
+			t.Fatalf("Report failed: %v", err)
+		}
+		queue, err := svc.Queue(ctx, admin)
+		if err != nil || len(queue) == 0 {
+			t.Fatalf("expected a report in the queue, got %v, %v", queue, err)
+		}
+		return &queue[len(queue)-1]
+	}
+
+	t.Run("invalid reports", func(t *testing.T) {
+		err := svc.Report(ctx, alice, "bob", ReportInput{Reason: "boring", Evidence: strings.Repeat("x", MaxEvidenceLength+1)})
+		var appErr *util.AppError
+		if !errors.As(err, &appErr) || appErr.Fields["reason"] != "error.reportReasonInvalid" || appErr.Fields["evidence"] != "error.evidenceTooLong" {
+			t.Errorf("expected reason and evidence errors, got %v", err)
+		}
+		if err := svc.Report(ctx, alice, "alice", ReportInput{Reason: "spam"}); !errors.Is(err, ErrReportSelf) {
+			t.Errorf("expected ErrReportSelf, got %v", err)
+		}
+		if err := svc.Report(ctx, alice, "nobody", ReportInput{Reason: "spam"}); !errors.Is(err, model.ErrNotFound) {
+			t.Errorf("expected ErrNotFound, got %v", err)
+		}
+	})
+
+	t.Run("only admins see the queue and act on it", func(t *testing.T) {
+		r := report(t, "spam")
+		if _, err := svc.Queue(ctx, alice); !errors.Is(err, ErrNotAdmin) {
+			t.Errorf("expected ErrNotAdmin from Queue, got %v", err)
+		}
+		if err := svc.Resolve(ctx, bob, r.ID.String(), ActionDismiss); !errors.Is(err, ErrNotAdmin) {
+			t.Errorf("expected ErrNotAdmin from Resolve, got %v", err)
+		}
+		if err := svc.Resolve(ctx, admin, r.ID.String(), "ban"); !errors.Is(err, ErrActionInvalid) {
+			t.Errorf("expected ErrActionInvalid, got %v", err)
+		}
+		if err := svc.Resolve(ctx, admin, "00000000-0000-0000-0000-000000000000", ActionDismiss); !errors.Is(err, ErrReportNotFound) {
+			t.Errorf("expected ErrReportNotFound, got %v", err)
+		}
+	})
+
+	t.Run("dismiss", func(t *testing.T) {
+		queue, _ := svc.Queue(ctx, admin)
+		if err := svc.Resolve(ctx, admin, queue[0].ID.String(), ActionDismiss); err != nil {
+			t.Fatalf("Resolve failed: %v", err)
+		}
+		got, _ := reports.GetByID(ctx, queue[0].ID.String())
+		if got.Status != model.ReportDismissed || got.ResolvedByID == nil || *got.ResolvedByID != admin.ID {
+			t.Errorf("expected a dismissed report, got %+v", got)
+		}
+		if err := svc.Resolve(ctx, admin, queue[0].ID.String(), ActionWarn); !errors.Is(err, ErrReportResolved) {
+			t.Errorf("expected ErrReportResolved, got %v", err)
+		}
+		if u, _ := users.GetByID(ctx, bob.ID.String()); u.Warnings != 0 || u.Suspended() {
+			t.Errorf("expected bob untouched, got %d warnings, suspended %v", u.Warnings, u.Suspended())
+		}
+	})
+
+	t.Run("warn", func(t *testing.T) {
+		r := report(t, "harassment")
+		if err := svc.Resolve(ctx, admin, r.ID.String(), ActionWarn); err != nil {
+			t.Fatalf("Resolve failed: %v", err)
+		}
+		if u, _ := users.GetByID(ctx, bob.ID.String()); u.Warnings != 1 || u.Suspended() {
+			t.Errorf("expected 1 warning, got %d, suspended %v", u.Warnings, u.Suspended())
+		}
+	})
+
+	t.Run("suspend", func(t *testing.T) {
+		r := report(t, "impersonation")
+		if err := svc.Resolve(ctx, admin, r.ID.String(), ActionSuspend); err != nil {
+			t.Fatalf("Resolve failed: %v", err)
+		}
+		if u, _ := users.GetByID(ctx, bob.ID.String()); !u.Suspended() || u.Warnings != 1 {
+			t.Errorf("expected bob to be suspended, got %+v", u)
+		}
+		if queue, _ := svc.Queue(ctx, admin); len(queue) != 0 {
+			t.Errorf("expected an empty queue, got %d reports", len(queue))
+		}
+	})
+}
@@ -0,0 +1,119 @@
+package services
+
+import (
+	"context"
+	"slices"
+
+	"myapp/model"
+	"myapp/util"
+)
+
+// PrivateFields lists the profile fields with a visibility setting, in the
+// order the privacy settings page shows them.
+var PrivateFields = []string{
+	model.FieldEmail, model.FieldBio, model.FieldPronouns,
+	model.FieldLocation, model.FieldCountry, model.FieldLinks,
+}
+
+// Visibilities lists the accepted visibilities, widest first.
+var Visibilities = []model.Visibility{
+	model.VisibilityPublic, model.VisibilityFollowers, model.VisibilityPrivate,
+}
+
+type PrivacyService struct {
+	users   *model.UserRepository
+	follows *FollowService
+}
+
+func NewPrivacyService(users *model.UserRepository, follows *FollowService) *PrivacyService {
+	return &PrivacyService{users: users, follows: follows}
+}
+
+// UpdatePrivacyInput replaces a user's privacy settings. Fields missing from
+// Visibility go back to their default.
+type UpdatePrivacyInput struct {
+	Visibility     model.ProfileVisibility
+	HideFromSearch bool
+}
+
+func (s *PrivacyService) UpdatePrivacy(ctx context.Context, userID string, input UpdatePrivacyInput) error {
+	fe := fieldErrors{}
+	for field, vis := range input.Visibility {
+		if !slices.Contains(PrivateFields, field) || !slices.Contains(Visibilities, vis) {
+			fe.add("visibility."+field, "error.visibilityInvalid")
+		}
+	}
+	if err := fe.err(); err != nil {
+		return err
+	}
+
+	user, err := s.users.GetByID(ctx, userID)
+	if err != nil {
+		return err
+	}
+	user.Visibility = input.Visibility
+	user.HideFromSearch = input.HideFromSearch
+	if err := s.users.Update(ctx, user); err != nil {
+		return err
+	}
+	util.Logger(ctx).Info("privacy updated", "user_id", userID, "hide_from_search", input.HideFromSearch)
+	return nil
+}
+
+// View returns profile as viewer may see it: a copy with the fields hidden
+// from viewer cleared. viewer is nil for signed-out visitors. Owners see
+// their whole profile; followers-only fields need viewer to follow profile.
+// Users blocked by profile's owner get model.ErrNotFound, as if the profile
+// did not exist.
+func (s *PrivacyService) View(ctx context.Context, viewer, profile *model.User) (*model.User, error) {
+	if viewer != nil && viewer.ID == profile.ID {
+		return profile, nil
+	}
+	blocked, err := s.follows.HasBlocked(ctx, profile, viewer)
+	if err != nil {
+		return nil, err
+	}
+	if blocked {
+		return nil, model.ErrNotFound
+	}
+
+	var follows *bool // looked up on the first followers-only field
+	hidden := func(field string) (bool, error) {
+		switch profile.Visibility.Of(field) {
+		case model.VisibilityPublic:
+			return false, nil
+		case model.VisibilityFollowers:
+			if follows == nil {
+				ok, err := s.follows.IsFollowing(ctx, viewer, profile)
+				if err != nil {
+					return false, err
+				}
+				follows = &ok
+			}
+			return !*follows, nil
+		default:
+			return true, nil
+		}
+	}
+
+	view := *profile
+	view.EmailNormalized = nil
+	strip := map[string]func(){
+		model.FieldEmail:    func() { view.Email = "" },
+		model.FieldBio:      func() { view.Bio = "" },
+		model.FieldPronouns: func() { view.Pronouns = "" },
+		model.FieldLocation: func() { view.Location = "" },
+		model.FieldCountry:  func() { view.Country = "" },
+		model.FieldLinks:    func() { view.Links = nil },
+	}
+	for _, field := range PrivateFields {
+		hide, err := hidden(field)
+		if err != nil {
+			return nil, err
+		}
+		if hide {
+			strip[field]()
+		}
+	}
+	return &view, nil
+}
@@ -0,0 +1,139 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+
+	"myapp/model"
+	"myapp/testutil"
+	"myapp/util"
+)
+
+func TestPrivacyService(t *testing.T) {
+	ctx := context.Background()
+	db := testutil.NewTestDB(t, &model.User{}, &model.HandleHistory{}, &model.Follow{}, &model.Block{})
+	users := model.NewUserRepository(db)
+	follows := NewFollowService(model.NewFollowRepository(db), model.NewBlockRepository(db), users)
+	svc := NewPrivacyService(users, follows)
+
+	newUser := func(handle string) *model.User {
+		u := &model.User{
+			Email: handle + "@example.com", PasswordHash: "x", Name: handle,
+			Bio: "Hi", Pronouns: "they/them", Location: "Lisbon", Country: "PT",
+			Links: []model.Link{{Type: LinkWebsite, URL: "https://example.com"}},
+		}
+		if err := users.Create(ctx, u); err != nil {
+			t.Fatalf("Create user failed: %v", err)
+		}
+		return u
+	}
+	owner, follower, stranger := newUser("owner"), newUser("follower"), newUser("stranger")
+	if err := follows.Follow(ctx, follower, "owner"); err != nil {
+		t.Fatalf("Follow failed: %v", err)
+	}
+
+	t.Run("defaults hide only the email", func(t *testing.T) {
+		view, err := svc.View(ctx, nil, owner)
+		if err != nil {
+			t.Fatalf("View failed: %v", err)
+		}
+		if view.Email != "" || view.EmailNormalized != nil {
+			t.Errorf("expected email to be hidden, got %q", view.Email)
+		}
+		if view.Bio != "Hi" || view.Country != "PT" || len(view.Links) != 1 {
+			t.Errorf("expected public fields, got %+v", view)
+		}
+	})
+
+	err := svc.UpdatePrivacy(ctx, owner.ID.String(), UpdatePrivacyInput{
+		Visibility: model.ProfileVisibility{
+			model.FieldEmail:    model.VisibilityFollowers,
+			model.FieldLocation: model.VisibilityFollowers,
+			model.FieldLinks:    model.VisibilityPrivate,
+		},
+		HideFromSearch: true,
+	})
+	if err != nil {
+		t.Fatalf("UpdatePrivacy failed: %v", err)
+	}
+	owner, _ = users.GetByID(ctx, owner.ID.String())
+	if !owner.HideFromSearch {
+		t.Error("expected HideFromSearch to be saved")
+	}
+
+	// Each case lists the fields the viewer sees; bio and country are
+	// public, email and location followers-only and links private.
+	cases := []struct {
+		name    string
+		viewer  *model.User
+		visible []string
+	}{
+		{"signed out", nil, []string{"bio", "country"}},
+		{"stranger", stranger, []string{"bio", "country"}},
+		{"follower", follower, []string{"bio", "country", "email", "location"}},
+		{"owner", owner, []string{"bio", "country", "email", "location", "links"}},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			view, err := svc.View(ctx, tc.viewer, owner)
+			if err != nil {
+				t.Fatalf("View failed: %v", err)
+			}
+			shown := map[string]bool{
+				"email":    view.Email != "",
+				"bio":      view.Bio != "",
+				"location": view.Location != "",
+				"country":  view.Country != "",
+				"links":    len(view.Links) > 0,
+			}
+			for field, got := range shown {
+				if want := slices.Contains(tc.visible, field); got != want {
+					t.Errorf("%s visible = %v, want %v", field, got, want)
+				}
+			}
+		})
+	}
+
+	t.Run("view leaves the profile untouched", func(t *testing.T) {
+		if _, err := svc.View(ctx, nil, owner); err != nil {
+			t.Fatal(err)
+		}
+		if owner.Email == "" || len(owner.Links) == 0 {
+			t.Error("expected View to work on a copy")
+		}
+	})
+
+	t.Run("blocked viewers get not found", func(t *testing.T) {
+		if err := follows.Block(ctx, owner, "stranger"); err != nil {
+			t.Fatalf("Block failed: %v", err)
+		}
+		if _, err := svc.View(ctx, stranger, owner); !errors.Is(err, model.ErrNotFound) {
+			t.Errorf("expected ErrNotFound, got %v", err)
+		}
+		if _, err := svc.View(ctx, owner, stranger); err != nil {
+			t.Errorf("expected the blocker to still see the blocked profile, got %v", err)
+		}
+	})
+
+	t.Run("invalid settings", func(t *testing.T) {
+		err := svc.UpdatePrivacy(ctx, owner.ID.String(), UpdatePrivacyInput{
+			Visibility: model.ProfileVisibility{
+				model.FieldEmail: "friends",
+				"password_hash":  model.VisibilityPublic,
+			},
+		})
+		var appErr *util.AppError
+		if !errors.As(err, &appErr) || appErr.Kind != util.KindValidation {
+			t.Fatalf("expected validation error, got %v", err)
+		}
+		if appErr.Fields["visibility.email"] != "error.visibilityInvalid" || appErr.Fields["visibility.password_hash"] != "error.visibilityInvalid" {
+			t.Errorf("unexpected fields %v", appErr.Fields)
+		}
+		unchanged, _ := users.GetByID(ctx, owner.ID.String())
+		if unchanged.Visibility.Of(model.FieldEmail) != model.VisibilityFollowers {
+			t.Error("expected nothing to be saved")
+		}
+	})
+}
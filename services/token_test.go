@@ -0,0 +1,110 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"myapp/model"
+	"myapp/testutil"
+)
+
+func newTestTokenService(t *testing.T) (*TokenService, *AuthService) {
+	t.Helper()
+	db := testutil.NewTestDB(t, &model.User{}, &model.HandleHistory{}, &model.PersonalAccessToken{})
+	users := model.NewUserRepository(db)
+	tokens := NewTokenService(model.NewTokenRepository(db), users)
+	return tokens, NewAuthService(users, "test-secret", nil, EmailPolicy{}, tokens, nil)
+}
+
+func TestTokenCreate(t *testing.T) {
+	ctx := context.Background()
+	svc, authSvc := newTestTokenService(t)
+	_, _ = authSvc.Signup(ctx, "user@example.com", "password123", "testuser")
+	user, _ := authSvc.repo.GetByEmail(ctx, "user@example.com")
+	userID := user.ID.String()
+
+	t.Run("stores only the hash", func(t *testing.T) {
+		plaintext, token, err := svc.Create(ctx, userID, "ci", []string{ScopeProfileRead}, 0)
+		if err != nil {
+			t.Fatalf("Create failed: %v", err)
+		}
+		if !strings.HasPrefix(plaintext, TokenPrefix) || !strings.HasPrefix(plaintext, token.Prefix) {
+			t.Errorf("unexpected plaintext %q for prefix %q", plaintext, token.Prefix)
+		}
+		if token.TokenHash == plaintext || token.TokenHash != hashToken(plaintext) {
+			t.Error("expected the token hash, not the plaintext, to be stored")
+		}
+		if token.ExpiresAt != nil {
+			t.Error("expected no expiry for zero ttl")
+		}
+	})
+
+	t.Run("ttl sets expiry", func(t *testing.T) {
+		_, token, err := svc.Create(ctx, userID, "deploy", []string{ScopeProfileRead}, 24*time.Hour)
+		if err != nil || token.ExpiresAt == nil {
+			t.Fatalf("expected expiry, got %v, %v", token, err)
+		}
+	})
+
+	t.Run("validation", func(t *testing.T) {
+		if _, _, err := svc.Create(ctx, userID, "  ", []string{ScopeProfileRead}, 0); !errors.Is(err, ErrTokenNameRequired) {
+			t.Errorf("expected ErrTokenNameRequired, got %v", err)
+		}
+		if _, _, err := svc.Create(ctx, userID, "ci", nil, 0); !errors.Is(err, ErrTokenScopeInvalid) {
+			t.Errorf("expected ErrTokenScopeInvalid for no scopes, got %v", err)
+		}
+		if _, _, err := svc.Create(ctx, userID, "ci", []string{"admin"}, 0); !errors.Is(err, ErrTokenScopeInvalid) {
+			t.Errorf("expected ErrTokenScopeInvalid for unknown scope, got %v", err)
+		}
+	})
+}
+
+func TestTokenAuthenticate(t *testing.T) {
+	ctx := context.Background()
+	svc, authSvc := newTestTokenService(t)
+	_, _ = authSvc.Signup(ctx, "user@example.com", "password123", "testuser")
+	user, _ := authSvc.repo.GetByEmail(ctx, "user@example.com")
+	userID := user.ID.String()
+
+	now := time.Now()
+	svc.now = func() time.Time { return now }
+
+	plaintext, token, _ := svc.Create(ctx, userID, "ci", []string{ScopeProfileRead}, time.Hour)
+
+	t.Run("valid token records use", func(t *testing.T) {
+		got, pat, err := svc.Authenticate(ctx, plaintext)
+		if err != nil || got.ID != user.ID || pat.ID != token.ID {
+			t.Fatalf("expected owner and token, got %v, %v, %v", got, pat, err)
+		}
+		tokens, _ := svc.List(ctx, userID)
+		if tokens[0].LastUsedAt == nil {
+			t.Error("expected last used to be recorded")
+		}
+	})
+
+	t.Run("unknown token", func(t *testing.T) {
+		if _, _, err := svc.Authenticate(ctx, TokenPrefix+"nope"); !errors.Is(err, ErrTokenInvalid) {
+			t.Errorf("expected ErrTokenInvalid, got %v", err)
+		}
+	})
+
+	t.Run("expired token", func(t *testing.T) {
+		svc.now = func() time.Time { return now.Add(2 * time.Hour) }
+		defer func() { svc.now = func() time.Time { return now } }()
+		if _, _, err := svc.Authenticate(ctx, plaintext); !errors.Is(err, ErrTokenInvalid) {
+			t.Errorf("expected ErrTokenInvalid, got %v", err)
+		}
+	})
+
+	t.Run("revoked token", func(t *testing.T) {
+		if err := svc.Revoke(ctx, userID, token.ID.String()); err != nil {
+			t.Fatalf("Revoke failed: %v", err)
+		}
+		if _, _, err := svc.Authenticate(ctx, plaintext); !errors.Is(err, ErrTokenInvalid) {
+			t.Errorf("expected ErrTokenInvalid, got %v", err)
+		}
+	})
+}
@@ -2,30 +2,40 @@ package services
 
 import (
 	"context"
+	"strings"
+	"time"
 
 	"myapp/model"
+	"myapp/util"
 )
 
 type UserService struct {
-	repo *model.UserRepository
+	repo    *model.UserRepository
+	handles *HandlePolicy
+	now     func() time.Time
 }
 
-func NewUserService(repo *model.UserRepository) *UserService {
-	return &UserService{repo: repo}
+// NewUserService creates the user service. handles may be nil to reserve
+// only the built-in handles.
+func NewUserService(repo *model.UserRepository, handles *HandlePolicy) *UserService {
+	return &UserService{repo: repo, handles: handles, now: time.Now}
 }
 
 type UpdateProfileInput struct {
 	Handle      string
 	DisplayName string
 	Bio         string
+	Pronouns    string
+	Location    string
 	Country     string
-	SocialLinks model.SocialLinks
+	Links       []model.Link
 	AvatarURL   string // empty means keep existing avatar
 }
 
 func (s *UserService) UpdateProfile(ctx context.Context, userID string, input UpdateProfileInput) error {
-	if !model.HandleRegex.MatchString(input.Handle) {
-		return ErrHandleInvalid
+	input.Handle = model.NormalizeHandle(input.Handle)
+	if err := validateProfile(input); err != nil {
+		return err
 	}
 
 	user, err := s.repo.GetByID(ctx, userID)
@@ -33,25 +43,124 @@ func (s *UserService) UpdateProfile(ctx context.Context, userID string, input Up
 		return err
 	}
 
-	if input.Handle != user.Name {
-		existing, err := s.repo.GetByHandle(ctx, input.Handle)
-		if err == nil && existing != nil {
-			return ErrHandleTaken
+	oldHandle := user.Name
+	renamed := input.Handle != oldHandle
+	if renamed {
+		if err := s.checkRename(ctx, user, input.Handle); err != nil {
+			return err
 		}
 	}
 
 	user.Name = input.Handle
 	user.DisplayName = input.DisplayName
 	user.Bio = input.Bio
+	user.Pronouns = input.Pronouns
+	user.Location = input.Location
 	user.Country = input.Country
-	user.SocialLinks = input.SocialLinks
+	user.Links = input.Links
 	if input.AvatarURL != "" {
 		user.AvatarURL = input.AvatarURL
 	}
 
-	return s.repo.Update(ctx, user)
+	if renamed {
+		if err := s.repo.Rename(ctx, user, oldHandle); err != nil {
+			return uniqueConflict(err)
+		}
+		util.Logger(ctx).Info("handle changed", "user_id", userID, "old_handle", oldHandle, "handle", user.Name)
+	} else if err := s.repo.Update(ctx, user); err != nil {
+		return err
+	}
+	util.Logger(ctx).Info("profile updated", "user_id", userID)
+	return nil
+}
+
+// checkRename reports whether user may change their handle to handle now.
+// Reserved handles are only checked here, so users who hold a handle that
+// was reserved later keep it.
+func (s *UserService) checkRename(ctx context.Context, user *model.User, handle string) error {
+	if err := s.handles.Check(handle); err != nil {
+		return err
+	}
+	now := s.now()
+	if err := checkHandleAvailable(ctx, s.repo, handle, user.ID, now); err != nil {
+		return err
+	}
+	renames, err := s.repo.CountRenamesSince(ctx, user.ID, now.Add(-RenameWindow))
+	if err != nil {
+		return err
+	}
+	if renames >= MaxRenames {
+		return ErrRenameLimit
+	}
+	return nil
+}
+
+// SetAvatar replaces the user's avatar URL, leaving the rest of the profile
+// untouched.
+func (s *UserService) SetAvatar(ctx context.Context, userID, avatarURL string) error {
+	user, err := s.repo.GetByID(ctx, userID)
+	if err != nil {
+		return err
+	}
+	user.AvatarURL = avatarURL
+	if err := s.repo.Update(ctx, user); err != nil {
+		return err
+	}
+	util.Logger(ctx).Info("avatar updated", "user_id", userID)
+	return nil
+}
+
+// DirectoryPageSize is the number of users per page of the directory.
+const DirectoryPageSize = 24
+
+// maxSearchQuery caps the length of a directory search, in characters.
+const maxSearchQuery = 100
+
+// SearchInput is a directory request as submitted by the search form. An
+// empty Sort ranks by relevance when there is a query and by handle
+// otherwise; After is the cursor of the previous page.
+type SearchInput struct {
+	Query   string
+	Country string
+	Sort    string
+	After   string
+}
+
+// Search returns a page of the user directory. Unknown countries and sort
+// orders are ignored rather than rejected, since they only arrive through
+// hand-edited URLs.
+func (s *UserService) Search(ctx context.Context, input SearchInput) (*model.UserPage, error) {
+	query := strings.TrimSpace(input.Query)
+	if runes := []rune(query); len(runes) > maxSearchQuery {
+		query = string(runes[:maxSearchQuery])
+	}
+	country := strings.ToUpper(strings.TrimSpace(input.Country))
+	if !countryCodes[country] {
+		country = ""
+	}
+	sort := input.Sort
+	if sort != model.SortHandle && sort != model.SortNewest {
+		sort = model.SortRelevance
+	}
+	return s.repo.Search(ctx, model.UserSearch{
+		Query:   query,
+		Country: country,
+		Sort:    sort,
+		After:   input.After,
+		Limit:   DirectoryPageSize,
+	})
+}
+
+func (s *UserService) GetByID(ctx context.Context, id string) (*model.User, error) {
+	return s.repo.GetByID(ctx, id)
 }
 
 func (s *UserService) GetByHandle(ctx context.Context, handle string) (*model.User, error) {
 	return s.repo.GetByHandle(ctx, handle)
 }
+
+// GetByPastHandle returns the user who most recently gave up handle, so
+// links to it can be redirected.
+func (s *UserService) GetByPastHandle(ctx context.Context, handle string) (*model.User, error) {
+	return s.repo.GetByPastHandle(ctx, handle)
+}
@@ -3,16 +3,18 @@ package services
 import (
 	"context"
 	"errors"
+	"strings"
 	"testing"
 
 	"myapp/model"
 	"myapp/testutil"
+	"myapp/util"
 )
 
 func newTestUserService(t *testing.T) (*UserService, *AuthService) {
 	t.Helper()
-	repo := model.NewUserRepository(testutil.NewTestDB(t, &model.User{}))
-	return NewUserService(repo), NewAuthService(repo)
+	repo := model.NewUserRepository(testutil.NewTestDB(t, &model.User{}, &model.HandleHistory{}))
+	return NewUserService(repo, nil), NewAuthService(repo, "test-secret", nil, EmailPolicy{}, nil, nil)
 }
 
 func TestUpdateProfile(t *testing.T) {
@@ -31,8 +33,13 @@ func TestUpdateProfile(t *testing.T) {
 			Handle:      "newhandle",
 			DisplayName: "Test User",
 			Bio:         "My bio",
+			Pronouns:    "they/them",
+			Location:    "Lisbon",
 			Country:     "US",
-			SocialLinks: model.SocialLinks{Instagram: "https://instagram.com/test"},
+			Links: []model.Link{
+				{Type: "instagram", URL: "https://instagram.com/test"},
+				{Type: LinkOther, URL: "https://example.com/shop", Label: "Shop"},
+			},
 		}
 		if err := userSvc.UpdateProfile(ctx, user.ID.String(), input); err != nil {
 			t.Fatalf("UpdateProfile failed: %v", err)
@@ -45,8 +52,11 @@ func TestUpdateProfile(t *testing.T) {
 		if updated.DisplayName != "Test User" {
 			t.Errorf("got display_name %q, want %q", updated.DisplayName, "Test User")
 		}
-		if updated.SocialLinks.Instagram != "https://instagram.com/test" {
-			t.Errorf("got instagram %q, want %q", updated.SocialLinks.Instagram, "https://instagram.com/test")
+		if updated.Pronouns != "they/them" || updated.Location != "Lisbon" {
+			t.Errorf("got pronouns %q and location %q", updated.Pronouns, updated.Location)
+		}
+		if len(updated.Links) != 2 || updated.Links[0].URL != "https://instagram.com/test" || updated.Links[1].Label != "Shop" {
+			t.Errorf("got links %+v, want them saved in order", updated.Links)
 		}
 	})
 
@@ -61,6 +71,68 @@ func TestUpdateProfile(t *testing.T) {
 		}
 	})
 
+	t.Run("reports every invalid field", func(t *testing.T) {
+		userSvc, authSvc := newTestUserService(t)
+		_, _ = authSvc.Signup(ctx, "user@example.com", "password123", "testuser")
+		user, _ := userSvc.GetByHandle(ctx, "testuser")
+
+		err := userSvc.UpdateProfile(ctx, user.ID.String(), UpdateProfileInput{
+			Handle:      "testuser",
+			DisplayName: strings.Repeat("n", MaxDisplayNameLength+1),
+			Bio:         strings.Repeat("b", MaxBioLength+1),
+			Country:     "XX",
+			Pronouns:    strings.Repeat("p", MaxPronounsLength+1),
+			Links: []model.Link{
+				{Type: "instagram", URL: "https://facebook.com/test"},
+				{Type: "x", URL: "javascript:alert(1)"},
+				{Type: "linkedin", URL: "https://www.linkedin.com/in/test"},
+				{Type: "myspace", URL: "https://myspace.com/test"},
+				{Type: LinkOther, URL: "https://example.com"},
+			},
+		})
+		var appErr *util.AppError
+		if !errors.As(err, &appErr) || appErr.Kind != util.KindValidation {
+			t.Fatalf("expected validation error, got %v", err)
+		}
+		want := map[string]string{
+			"display_name":  "error.displayNameTooLong",
+			"bio":           "error.bioTooLong",
+			"country":       "error.countryInvalid",
+			"pronouns":      "error.pronounsTooLong",
+			"links.0.url":   "error.instagramInvalid",
+			"links.1.url":   "error.xInvalid",
+			"links.3.type":  "error.linkTypeInvalid",
+			"links.4.label": "error.linkLabelRequired",
+		}
+		if len(appErr.Fields) != len(want) {
+			t.Errorf("got fields %v, want %v", appErr.Fields, want)
+		}
+		for field, key := range want {
+			if appErr.Fields[field] != key {
+				t.Errorf("field %s: got %q, want %q", field, appErr.Fields[field], key)
+			}
+		}
+
+		unchanged, _ := userSvc.GetByID(ctx, user.ID.String())
+		if unchanged.Country != "" || unchanged.Bio != "" {
+			t.Error("expected nothing to be saved")
+		}
+	})
+
+	t.Run("lengths count characters, not bytes", func(t *testing.T) {
+		userSvc, authSvc := newTestUserService(t)
+		_, _ = authSvc.Signup(ctx, "user@example.com", "password123", "testuser")
+		user, _ := userSvc.GetByHandle(ctx, "testuser")
+
+		err := userSvc.UpdateProfile(ctx, user.ID.String(), UpdateProfileInput{
+			Handle:      "testuser",
+			DisplayName: strings.Repeat("ñ", MaxDisplayNameLength),
+		})
+		if err != nil {
+			t.Errorf("expected %d two-byte characters to fit, got %v", MaxDisplayNameLength, err)
+		}
+	})
+
 	t.Run("handle taken", func(t *testing.T) {
 		userSvc, authSvc := newTestUserService(t)
 		_, _ = authSvc.Signup(ctx, "user1@example.com", "password123", "user1hnd")
@@ -96,3 +168,45 @@ func TestUserServiceGetByHandle(t *testing.T) {
 		}
 	})
 }
+
+func TestSearch(t *testing.T) {
+	ctx := context.Background()
+	userSvc, authSvc := newTestUserService(t)
+	for _, handle := range []string{"alice", "alicia", "bob"} {
+		if _, err := authSvc.Signup(ctx, handle+"@example.com", "password123", handle); err != nil {
+			t.Fatalf("Signup failed: %v", err)
+		}
+	}
+	alicia, _ := userSvc.GetByHandle(ctx, "alicia")
+	if err := userSvc.UpdateProfile(ctx, alicia.ID.String(), UpdateProfileInput{Handle: "alicia", Country: "US"}); err != nil {
+		t.Fatalf("UpdateProfile failed: %v", err)
+	}
+
+	names := func(page *model.UserPage) []string {
+		var handles []string
+		for _, u := range page.Users {
+			handles = append(handles, u.Name)
+		}
+		return handles
+	}
+
+	t.Run("normalizes the form", func(t *testing.T) {
+		page, err := userSvc.Search(ctx, SearchInput{Query: "  ali  ", Country: "us"})
+		if err != nil || len(page.Users) != 1 || page.Users[0].Name != "alicia" {
+			t.Errorf("expected [alicia], got %v, %v", names(page), err)
+		}
+	})
+
+	t.Run("unknown filters are ignored", func(t *testing.T) {
+		page, err := userSvc.Search(ctx, SearchInput{Country: "ZZ", Sort: "bogus"})
+		if err != nil || len(page.Users) != 3 || page.Users[0].Name != "alice" {
+			t.Errorf("expected every user by handle, got %v, %v", names(page), err)
+		}
+	})
+
+	t.Run("bad cursors are rejected", func(t *testing.T) {
+		if _, err := userSvc.Search(ctx, SearchInput{After: "!"}); !errors.Is(err, model.ErrInvalidCursor) {
+			t.Errorf("expected ErrInvalidCursor, got %v", err)
+		}
+	})
+}
@@ -0,0 +1,146 @@
+package services
+
+import (
+	"fmt"
+	"net/url"
+	"slices"
+	"strings"
+	"unicode/utf8"
+
+	"myapp/model"
+	"myapp/util"
+)
+
+const (
+	MaxDisplayNameLength = 50
+	MaxBioLength         = 300
+	MaxPronounsLength    = 30
+	MaxLocationLength    = 60
+	MaxLinks             = 10
+	MaxLinkLabelLength   = 40
+)
+
+// Link types without a fixed site.
+const (
+	LinkWebsite  = "website"
+	LinkMastodon = "mastodon"
+	LinkOther    = "other"
+)
+
+// LinkTypes lists the accepted link types in the order the edit form offers
+// them. Networks not listed are added as LinkOther with a label.
+var LinkTypes = []string{
+	LinkWebsite, "github", "instagram", "facebook", "linkedin", "x",
+	"bluesky", "youtube", "tiktok", LinkMastodon, LinkOther,
+}
+
+// linkDomains lists the sites a link of each network type must point at.
+// Subdomains such as www. or m. are accepted. Types without an entry accept
+// any http(s) URL.
+var linkDomains = map[string][]string{
+	"github":    {"github.com"},
+	"instagram": {"instagram.com"},
+	"facebook":  {"facebook.com", "fb.com"},
+	"linkedin":  {"linkedin.com"},
+	"x":         {"x.com", "twitter.com"},
+	"bluesky":   {"bsky.app"},
+	"youtube":   {"youtube.com", "youtu.be"},
+	"tiktok":    {"tiktok.com"},
+}
+
+// fieldErrors collects validation problems as field name → i18n key. Only
+// the first problem reported for a field is kept.
+type fieldErrors map[string]string
+
+func (fe fieldErrors) add(field, key string) {
+	if _, ok := fe[field]; !ok {
+		fe[field] = key
+	}
+}
+
+// err returns nil when nothing was reported. A single problem is returned as
+// its own error, so sentinels like ErrHandleInvalid still match.
+func (fe fieldErrors) err() error {
+	switch len(fe) {
+	case 0:
+		return nil
+	case 1:
+		for field, key := range fe {
+			return util.Invalid(field, key)
+		}
+	}
+	return util.Validation("error.invalidFields", fe)
+}
+
+// validateProfile checks every field of input and reports all problems at
+// once. Field names follow the JSON API, with links addressed by position,
+// e.g. "links.0.url".
+func validateProfile(input UpdateProfileInput) error {
+	fe := fieldErrors{}
+	if !model.HandleRegex.MatchString(input.Handle) {
+		fe.add("handle", "error.handleInvalid")
+	}
+	if utf8.RuneCountInString(input.DisplayName) > MaxDisplayNameLength {
+		fe.add("display_name", "error.displayNameTooLong")
+	}
+	if utf8.RuneCountInString(input.Bio) > MaxBioLength {
+		fe.add("bio", "error.bioTooLong")
+	}
+	if utf8.RuneCountInString(input.Pronouns) > MaxPronounsLength {
+		fe.add("pronouns", "error.pronounsTooLong")
+	}
+	if utf8.RuneCountInString(input.Location) > MaxLocationLength {
+		fe.add("location", "error.locationTooLong")
+	}
+	if input.Country != "" && !countryCodes[input.Country] {
+		fe.add("country", "error.countryInvalid")
+	}
+	if len(input.Links) > MaxLinks {
+		fe.add("links", "error.tooManyLinks")
+	}
+	for i, link := range input.Links {
+		validateLink(fe, fmt.Sprintf("links.%d.", i), link)
+	}
+	return fe.err()
+}
+
+// validateLink reports the problems with link under fields starting with
+// prefix.
+func validateLink(fe fieldErrors, prefix string, link model.Link) {
+	if !slices.Contains(LinkTypes, link.Type) {
+		fe.add(prefix+"type", "error.linkTypeInvalid")
+		return
+	}
+	if domains, ok := linkDomains[link.Type]; ok {
+		if !validLink(link.URL, domains) {
+			fe.add(prefix+"url", "error."+link.Type+"Invalid")
+		}
+	} else if !validLink(link.URL, nil) {
+		fe.add(prefix+"url", "error.linkInvalid")
+	}
+	switch {
+	case utf8.RuneCountInString(link.Label) > MaxLinkLabelLength:
+		fe.add(prefix+"label", "error.linkLabelTooLong")
+	case link.Type == LinkOther && link.Label == "":
+		fe.add(prefix+"label", "error.linkLabelRequired")
+	}
+}
+
+// validLink reports whether link is an http(s) URL on one of domains,
+// or on any host when domains is nil.
+func validLink(link string, domains []string) bool {
+	u, err := url.Parse(link)
+	if err != nil || (u.Scheme != "https" && u.Scheme != "http") || u.User != nil || u.Host == "" {
+		return false
+	}
+	if domains == nil {
+		return true
+	}
+	host := strings.ToLower(u.Hostname())
+	for _, d := range domains {
+		if host == d || strings.HasSuffix(host, "."+d) {
+			return true
+		}
+	}
+	return false
+}
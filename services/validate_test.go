@@ -0,0 +1,73 @@
+package services
+
+import "testing"
+
+func TestValidLink(t *testing.T) {
+	x := linkDomains["x"]
+	cases := []struct {
+		link string
+		want bool
+	}{
+		{"https://x.com/someone", true},
+		{"https://twitter.com/someone", true},
+		{"http://www.x.com/someone", true},
+		{"https://X.COM/someone", true},
+		{"https://mobile.twitter.com/someone", true},
+		{"x.com/someone", false},
+		{"ftp://x.com/someone", false},
+		{"javascript://x.com/%0Aalert(1)", false},
+		{"https://evilx.com/someone", false},
+		{"https://x.com.evil.example/someone", false},
+		{"https://x.com@evil.example/someone", false},
+		{"https://user@x.com/someone", false},
+	}
+	for _, tc := range cases {
+		if got := validLink(tc.link, x); got != tc.want {
+			t.Errorf("validLink(%q) = %v, want %v", tc.link, got, tc.want)
+		}
+	}
+
+	// Without domains any http(s) URL with a host is accepted.
+	for link, want := range map[string]bool{
+		"https://example.com/me":   true,
+		"http://blog.example.org":  true,
+		"https:///no-host":         false,
+		"javascript:alert(1)":      false,
+		"https://user@example.com": false,
+	} {
+		if got := validLink(link, nil); got != want {
+			t.Errorf("validLink(%q, nil) = %v, want %v", link, got, want)
+		}
+	}
+}
+
+func TestFieldErrors(t *testing.T) {
+	t.Run("first problem per field wins", func(t *testing.T) {
+		fe := fieldErrors{}
+		fe.add("bio", "error.bioTooLong")
+		fe.add("bio", "error.somethingElse")
+		if fe["bio"] != "error.bioTooLong" {
+			t.Errorf("got %q", fe["bio"])
+		}
+	})
+
+	t.Run("single problem is its own summary", func(t *testing.T) {
+		err := fieldErrors{"handle": "error.handleInvalid"}.err()
+		if err == nil || err.Error() != "error.handleInvalid" {
+			t.Errorf("got %v", err)
+		}
+	})
+
+	t.Run("several problems share a summary", func(t *testing.T) {
+		err := fieldErrors{"bio": "error.bioTooLong", "country": "error.countryInvalid"}.err()
+		if err == nil || err.Error() != "error.invalidFields" {
+			t.Errorf("got %v", err)
+		}
+	})
+
+	t.Run("nothing reported", func(t *testing.T) {
+		if err := (fieldErrors{}).err(); err != nil {
+			t.Errorf("got %v", err)
+		}
+	})
+}
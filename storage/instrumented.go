@@ -0,0 +1,61 @@
+package storage
+
+import (
+	"context"
+	"io"
+	"time"
+
+	"myapp/metrics"
+	"myapp/tracing"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+type instrumentedStorage struct {
+	Storage
+	backend string
+	metrics *metrics.Metrics
+}
+
+// WithMetrics wraps s so every upload records its size and latency under
+// the given backend label. The reader is passed through untouched so
+// backends can still seek it.
+func WithMetrics(s Storage, backend string, m *metrics.Metrics) Storage {
+	return &instrumentedStorage{Storage: s, backend: backend, metrics: m}
+}
+
+func (s *instrumentedStorage) Upload(ctx context.Context, key string, r io.Reader, size int64, contentType string) (string, error) {
+	start := time.Now()
+	url, err := s.Storage.Upload(ctx, key, r, size, contentType)
+	s.metrics.ObserveUpload(s.backend, size, time.Since(start), err)
+	return url, err
+}
+
+type tracedStorage struct {
+	Storage
+	backend string
+	tracer  trace.Tracer
+}
+
+// WithTracing wraps s so every upload runs inside a "storage.Upload" span.
+func WithTracing(s Storage, backend string, tp trace.TracerProvider) Storage {
+	return &tracedStorage{Storage: s, backend: backend, tracer: tp.Tracer("myapp/storage")}
+}
+
+func (s *tracedStorage) Upload(ctx context.Context, key string, r io.Reader, size int64, contentType string) (string, error) {
+	ctx, span := s.tracer.Start(ctx, "storage.Upload",
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			attribute.String("storage.backend", s.backend),
+			attribute.String("storage.key", key),
+			attribute.Int64("storage.size", size),
+			attribute.String("storage.content_type", contentType),
+		),
+	)
+	defer span.End()
+
+	url, err := s.Storage.Upload(ctx, key, r, size, contentType)
+	tracing.RecordError(span, err)
+	return url, err
+}
@@ -2,10 +2,14 @@ package storage
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"io"
+	"io/fs"
 	"os"
 	"path/filepath"
+
+	"myapp/util"
 )
 
 type LocalStorage struct {
@@ -20,7 +24,7 @@ func NewLocalStorage(baseDir, baseURL string) (*LocalStorage, error) {
 	return &LocalStorage{baseDir: baseDir, baseURL: baseURL}, nil
 }
 
-func (s *LocalStorage) Upload(_ context.Context, key string, r io.Reader, _ int64, _ string) (string, error) {
+func (s *LocalStorage) Upload(ctx context.Context, key string, r io.Reader, _ int64, _ string) (string, error) {
 	path := filepath.Join(s.baseDir, filepath.FromSlash(key))
 	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
 		return "", fmt.Errorf("mkdir: %w", err)
@@ -33,5 +37,36 @@ func (s *LocalStorage) Upload(_ context.Context, key string, r io.Reader, _ int6
 	if _, err := io.Copy(f, r); err != nil {
 		return "", fmt.Errorf("write file: %w", err)
 	}
+	util.Logger(ctx).Debug("storage upload", "backend", "local", "key", key)
 	return s.baseURL + "/" + key, nil
 }
+
+func (s *LocalStorage) Open(_ context.Context, key string) (io.ReadCloser, error) {
+	f, err := os.Open(filepath.Join(s.baseDir, filepath.FromSlash(key)))
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil, ErrNotFound
+		}
+		return nil, fmt.Errorf("open file: %w", err)
+	}
+	return f, nil
+}
+
+func (s *LocalStorage) Delete(_ context.Context, key string) error {
+	err := os.Remove(filepath.Join(s.baseDir, filepath.FromSlash(key)))
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return fmt.Errorf("remove file: %w", err)
+	}
+	return nil
+}
+
+func (s *LocalStorage) Ping(_ context.Context) error {
+	info, err := os.Stat(s.baseDir)
+	if err != nil {
+		return fmt.Errorf("stat upload directory: %w", err)
+	}
+	if !info.IsDir() {
+		return fmt.Errorf("upload path %s is not a directory", s.baseDir)
+	}
+	return nil
+}
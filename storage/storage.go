@@ -2,11 +2,20 @@ package storage
 
 import (
 	"context"
+	"errors"
 	"io"
 )
 
+var ErrNotFound = errors.New("object not found")
+
 type Storage interface {
 	Upload(ctx context.Context, key string, r io.Reader, size int64, contentType string) (string, error)
+	// Open returns a reader for the object stored under key, or ErrNotFound.
+	Open(ctx context.Context, key string) (io.ReadCloser, error)
+	// Delete removes the object stored under key. Deleting a missing key is not an error.
+	Delete(ctx context.Context, key string) error
+	// Ping reports whether the backend is reachable.
+	Ping(ctx context.Context) error
 }
 
 type noopStorage struct{}
@@ -15,6 +24,18 @@ func (n *noopStorage) Upload(_ context.Context, _ string, _ io.Reader, _ int64,
 	return "", nil
 }
 
+func (n *noopStorage) Open(_ context.Context, _ string) (io.ReadCloser, error) {
+	return nil, ErrNotFound
+}
+
+func (n *noopStorage) Delete(_ context.Context, _ string) error {
+	return nil
+}
+
+func (n *noopStorage) Ping(_ context.Context) error {
+	return nil
+}
+
 func Noop() Storage {
 	return &noopStorage{}
 }
@@ -0,0 +1,183 @@
+package storage
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"myapp/metrics"
+	"myapp/testutil"
+
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+const testBaseURL = "https://cdn.example.com"
+
+func newTestLocalStorage(t *testing.T) Storage {
+	t.Helper()
+	s, err := NewLocalStorage(t.TempDir(), testBaseURL)
+	if err != nil {
+		t.Fatalf("NewLocalStorage failed: %v", err)
+	}
+	return s
+}
+
+func newTestS3Storage(t *testing.T) Storage {
+	t.Helper()
+	srv := testutil.NewS3Server(t)
+	s, err := NewS3Storage(srv.URL, "us-east-1", srv.Bucket, "key-id", "app-key", testBaseURL)
+	if err != nil {
+		t.Fatalf("NewS3Storage failed: %v", err)
+	}
+	return s
+}
+
+func TestConformance(t *testing.T) {
+	backends := map[string]func(t *testing.T) Storage{
+		"local": newTestLocalStorage,
+		"s3":    newTestS3Storage,
+	}
+	for name, newStore := range backends {
+		t.Run(name, func(t *testing.T) {
+			testStorage(t, newStore)
+		})
+	}
+}
+
+func testStorage(t *testing.T, newStore func(t *testing.T) Storage) {
+	ctx := context.Background()
+	payload := []byte("fake image bytes")
+
+	upload := func(t *testing.T, s Storage, key string) string {
+		t.Helper()
+		url, err := s.Upload(ctx, key, bytes.NewReader(payload), int64(len(payload)), "image/png")
+		if err != nil {
+			t.Fatalf("Upload failed: %v", err)
+		}
+		return url
+	}
+
+	read := func(t *testing.T, s Storage, key string) ([]byte, error) {
+		t.Helper()
+		rc, err := s.Open(ctx, key)
+		if err != nil {
+			return nil, err
+		}
+		defer rc.Close()
+		return io.ReadAll(rc)
+	}
+
+	t.Run("upload returns public url", func(t *testing.T) {
+		url := upload(t, newStore(t), "avatars/user.png")
+		if url != testBaseURL+"/avatars/user.png" {
+			t.Errorf("got url %q, want %q", url, testBaseURL+"/avatars/user.png")
+		}
+	})
+
+	t.Run("read after upload", func(t *testing.T) {
+		s := newStore(t)
+		upload(t, s, "avatars/user.png")
+		got, err := read(t, s, "avatars/user.png")
+		if err != nil {
+			t.Fatalf("Open failed: %v", err)
+		}
+		if !bytes.Equal(got, payload) {
+			t.Errorf("got %q, want %q", got, payload)
+		}
+	})
+
+	t.Run("upload overwrites existing key", func(t *testing.T) {
+		s := newStore(t)
+		upload(t, s, "avatars/user.png")
+		replacement := "new image bytes"
+		if _, err := s.Upload(ctx, "avatars/user.png", strings.NewReader(replacement), int64(len(replacement)), "image/png"); err != nil {
+			t.Fatalf("Upload failed: %v", err)
+		}
+		got, err := read(t, s, "avatars/user.png")
+		if err != nil {
+			t.Fatalf("Open failed: %v", err)
+		}
+		if string(got) != replacement {
+			t.Errorf("got %q, want %q", got, replacement)
+		}
+	})
+
+	t.Run("read missing key", func(t *testing.T) {
+		_, err := read(t, newStore(t), "avatars/missing.png")
+		if !errors.Is(err, ErrNotFound) {
+			t.Errorf("expected ErrNotFound, got %v", err)
+		}
+	})
+
+	t.Run("delete removes object", func(t *testing.T) {
+		s := newStore(t)
+		upload(t, s, "avatars/user.png")
+		if err := s.Delete(ctx, "avatars/user.png"); err != nil {
+			t.Fatalf("Delete failed: %v", err)
+		}
+		if _, err := read(t, s, "avatars/user.png"); !errors.Is(err, ErrNotFound) {
+			t.Errorf("expected ErrNotFound after delete, got %v", err)
+		}
+	})
+
+	t.Run("ping", func(t *testing.T) {
+		if err := newStore(t).Ping(ctx); err != nil {
+			t.Errorf("Ping failed: %v", err)
+		}
+	})
+
+	t.Run("delete missing key", func(t *testing.T) {
+		if err := newStore(t).Delete(ctx, "avatars/missing.png"); err != nil {
+			t.Errorf("expected no error, got %v", err)
+		}
+	})
+}
+
+func TestS3PingMissingBucket(t *testing.T) {
+	srv := testutil.NewS3Server(t)
+	s, err := NewS3Storage(srv.URL, "us-east-1", "other-bucket", "key-id", "app-key", testBaseURL)
+	if err != nil {
+		t.Fatalf("NewS3Storage failed: %v", err)
+	}
+	if err := s.Ping(context.Background()); err == nil {
+		t.Error("expected error for missing bucket, got nil")
+	}
+}
+
+func TestWithMetrics(t *testing.T) {
+	m := metrics.New()
+	s := WithMetrics(newTestS3Storage(t), "s3", m)
+	payload := []byte("fake image bytes")
+	if _, err := s.Upload(context.Background(), "avatars/user.png", bytes.NewReader(payload), int64(len(payload)), "image/png"); err != nil {
+		t.Fatalf("Upload failed: %v", err)
+	}
+
+	w := httptest.NewRecorder()
+	m.Handler().ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	want := fmt.Sprintf(`myapp_storage_upload_bytes_total{backend="s3"} %d`, len(payload))
+	if !strings.Contains(w.Body.String(), want) {
+		t.Errorf("metrics output missing %q", want)
+	}
+}
+
+func TestWithTracing(t *testing.T) {
+	rec := tracetest.NewSpanRecorder()
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(rec))
+	s := WithTracing(newTestLocalStorage(t), "local", tp)
+	payload := []byte("fake image bytes")
+	if _, err := s.Upload(context.Background(), "avatars/user.png", bytes.NewReader(payload), int64(len(payload)), "image/png"); err != nil {
+		t.Fatalf("Upload failed: %v", err)
+	}
+
+	spans := rec.Ended()
+	if len(spans) != 1 || spans[0].Name() != "storage.Upload" {
+		t.Fatalf("expected one storage.Upload span, got %v", spans)
+	}
+}
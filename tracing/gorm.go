@@ -0,0 +1,87 @@
+package tracing
+
+import (
+	"context"
+	"errors"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+	"gorm.io/gorm"
+)
+
+const (
+	spanKey      = "tracing:span"
+	parentCtxKey = "tracing:parent_ctx"
+)
+
+// GormPlugin starts a client span for every GORM statement, parented to the
+// context passed through db.WithContext. Register it with db.Use.
+func GormPlugin(tp trace.TracerProvider) gorm.Plugin {
+	return &gormPlugin{tracer: tp.Tracer(tracerName)}
+}
+
+type gormPlugin struct {
+	tracer trace.Tracer
+}
+
+func (p *gormPlugin) Name() string {
+	return "tracing"
+}
+
+func (p *gormPlugin) Initialize(db *gorm.DB) error {
+	cb := db.Callback()
+	hooks := []struct {
+		operation string
+		before    func(name string, fn func(*gorm.DB)) error
+		after     func(name string, fn func(*gorm.DB)) error
+	}{
+		{"create", cb.Create().Before("gorm:create").Register, cb.Create().After("gorm:create").Register},
+		{"query", cb.Query().Before("gorm:query").Register, cb.Query().After("gorm:query").Register},
+		{"update", cb.Update().Before("gorm:update").Register, cb.Update().After("gorm:update").Register},
+		{"delete", cb.Delete().Before("gorm:delete").Register, cb.Delete().After("gorm:delete").Register},
+		{"row", cb.Row().Before("gorm:row").Register, cb.Row().After("gorm:row").Register},
+		{"raw", cb.Raw().Before("gorm:raw").Register, cb.Raw().After("gorm:raw").Register},
+	}
+
+	for _, h := range hooks {
+		operation := h.operation
+		if err := h.before("tracing:before_"+operation, func(tx *gorm.DB) {
+			parent := tx.Statement.Context
+			ctx, span := p.tracer.Start(parent, "gorm."+operation,
+				trace.WithSpanKind(trace.SpanKindClient),
+				trace.WithAttributes(attribute.String("db.system", "sqlite"), attribute.String("db.operation", operation)),
+			)
+			tx.Statement.Context = ctx
+			tx.InstanceSet(spanKey, span)
+			tx.InstanceSet(parentCtxKey, parent)
+		}); err != nil {
+			return err
+		}
+		if err := h.after("tracing:after_"+operation, func(tx *gorm.DB) {
+			v, ok := tx.InstanceGet(spanKey)
+			if !ok {
+				return
+			}
+			span := v.(trace.Span)
+			defer span.End()
+			// Restore the caller's context so statements chained on a
+			// non-cloned session don't nest under this finished span.
+			if parent, ok := tx.InstanceGet(parentCtxKey); ok {
+				tx.Statement.Context = parent.(context.Context)
+			}
+
+			span.SetName("gorm." + operation + " " + tx.Statement.Table)
+			span.SetAttributes(
+				attribute.String("db.sql.table", tx.Statement.Table),
+				attribute.String("db.statement", tx.Statement.SQL.String()),
+				attribute.Int64("db.rows_affected", tx.RowsAffected),
+			)
+			if !errors.Is(tx.Error, gorm.ErrRecordNotFound) {
+				RecordError(span, tx.Error)
+			}
+		}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,98 @@
+package tracing
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"myapp/config"
+	"myapp/model"
+	"myapp/testutil"
+
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+func newRecorder() (*sdktrace.TracerProvider, *tracetest.SpanRecorder) {
+	rec := tracetest.NewSpanRecorder()
+	return sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(rec)), rec
+}
+
+func TestSetup(t *testing.T) {
+	for _, exporter := range []string{"none", "stdout"} {
+		t.Run(exporter, func(t *testing.T) {
+			tp, shutdown, err := Setup(context.Background(), config.TracingConfig{Exporter: exporter, SampleRatio: 1})
+			if err != nil {
+				t.Fatalf("Setup failed: %v", err)
+			}
+			if tp == nil {
+				t.Fatal("expected tracer provider")
+			}
+			if err := shutdown(context.Background()); err != nil {
+				t.Errorf("shutdown failed: %v", err)
+			}
+		})
+	}
+
+	t.Run("unknown exporter", func(t *testing.T) {
+		if _, _, err := Setup(context.Background(), config.TracingConfig{Exporter: "zipkin"}); err == nil {
+			t.Error("expected error, got nil")
+		}
+	})
+}
+
+func TestLoader(t *testing.T) {
+	tp, rec := newRecorder()
+	db := testutil.NewTestDB(t, &model.User{})
+	if err := db.Use(GormPlugin(tp)); err != nil {
+		t.Fatalf("Use failed: %v", err)
+	}
+	repo := model.NewUserRepository(db)
+
+	t.Run("queries are children of the loader span", func(t *testing.T) {
+		load := Loader(tp, "/user/{handle}", func(req *http.Request) (map[string]any, error) {
+			_, err := repo.GetByHandle(req.Context(), "missing")
+			return nil, err
+		})
+		if _, err := load(httptest.NewRequest(http.MethodGet, "/user/missing", nil)); err == nil {
+			t.Fatal("expected not found error")
+		}
+
+		spans := rec.Ended()
+		if len(spans) != 2 {
+			t.Fatalf("got %d spans, want 2", len(spans))
+		}
+		query, loader := spans[0], spans[1]
+		if loader.Name() != "loader /user/{handle}" {
+			t.Errorf("got loader span %q", loader.Name())
+		}
+		if query.Name() != "gorm.query users" {
+			t.Errorf("got query span %q", query.Name())
+		}
+		if query.Parent().SpanID() != loader.SpanContext().SpanID() {
+			t.Error("query span is not parented to the loader span")
+		}
+		if query.Status().Code == codes.Error {
+			t.Error("record not found should not mark the query span as failed")
+		}
+		if loader.Status().Code != codes.Error {
+			t.Errorf("got loader status %v, want error", loader.Status().Code)
+		}
+	})
+}
+
+func TestRecordError(t *testing.T) {
+	tp, rec := newRecorder()
+	_, span := tp.Tracer("test").Start(context.Background(), "op")
+	RecordError(span, nil)
+	RecordError(span, errors.New("boom"))
+	span.End()
+
+	got := rec.Ended()[0]
+	if got.Status().Code != codes.Error || got.Status().Description != "boom" {
+		t.Errorf("got status %+v, want error boom", got.Status())
+	}
+}
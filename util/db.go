@@ -1,9 +1,15 @@
 package util
 
 import (
-	"myapp/config"
+	"context"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
 	"time"
 
+	"myapp/config"
+
 	"gorm.io/driver/sqlite"
 	"gorm.io/gorm"
 
@@ -11,20 +17,83 @@ import (
 	_ "github.com/tursodatabase/libsql-client-go/libsql"
 )
 
-var Db = newDatabase()
+// NewDatabase opens the database described by cfg, applies the pool limits
+// and verifies the connection with a ping. Local file: DSNs get WAL
+// journaling, a busy timeout and foreign keys on every pooled connection;
+// remote libSQL/Turso DSNs are used as is.
+func NewDatabase(ctx context.Context, cfg config.DBConfig) (*gorm.DB, error) {
+	dsn := cfg.DSN
+	if strings.HasPrefix(dsn, "file:") {
+		dsn = withSQLitePragmas(dsn, cfg.BusyTimeout)
+	}
 
-func newDatabase() *gorm.DB {
 	db, err := gorm.Open(sqlite.Dialector{
-		DSN:        config.Env.DB_DSN,
+		DSN:        dsn,
 		DriverName: "libsql",
 	}, &gorm.Config{},
 	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect database: %w", err)
+	}
 
+	sqlDB, err := db.DB()
 	if err != nil {
-		panic("failed to connect database")
+		return nil, fmt.Errorf("failed to get database handle: %w", err)
 	}
+	sqlDB.SetMaxOpenConns(cfg.MaxOpenConns)
+	sqlDB.SetMaxIdleConns(cfg.MaxIdleConns)
+	sqlDB.SetConnMaxLifetime(cfg.ConnMaxLifetime)
 
-	return db
+	if err := PingDatabase(ctx, db); err != nil {
+		sqlDB.Close()
+		return nil, err
+	}
+
+	return db, nil
+}
+
+// PingDatabase reports whether the database is reachable.
+func PingDatabase(ctx context.Context, db *gorm.DB) error {
+	sqlDB, err := db.DB()
+	if err != nil {
+		return fmt.Errorf("failed to get database handle: %w", err)
+	}
+	if err := sqlDB.PingContext(ctx); err != nil {
+		return fmt.Errorf("failed to ping database: %w", err)
+	}
+	return nil
+}
+
+// CloseDatabase closes the underlying connection pool.
+func CloseDatabase(db *gorm.DB) error {
+	sqlDB, err := db.DB()
+	if err != nil {
+		return fmt.Errorf("failed to get database handle: %w", err)
+	}
+	return sqlDB.Close()
+}
+
+// withSQLitePragmas adds go-sqlite3 connection parameters, which are applied
+// to each new connection rather than once per pool.
+func withSQLitePragmas(dsn string, busyTimeout time.Duration) string {
+	path, rawQuery, _ := strings.Cut(dsn, "?")
+	q, err := url.ParseQuery(rawQuery)
+	if err != nil {
+		return dsn
+	}
+	setDefault := func(key, value string) {
+		if q.Get(key) == "" {
+			q.Set(key, value)
+		}
+	}
+	setDefault("_journal_mode", "WAL")
+	setDefault("_busy_timeout", strconv.FormatInt(busyTimeout.Milliseconds(), 10))
+	setDefault("_foreign_keys", "on")
+	// Take the write lock when a transaction begins. A deferred transaction
+	// that reads before writing fails with "database is locked" instead of
+	// waiting when another connection wrote in the meantime.
+	setDefault("_txlock", "immediate")
+	return path + "?" + q.Encode()
 }
 
 type Entity struct {
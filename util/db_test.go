@@ -0,0 +1,69 @@
+package util
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"myapp/config"
+)
+
+func TestNewDatabase(t *testing.T) {
+	ctx := context.Background()
+	cfg := config.DBConfig{
+		DSN:             "file:" + filepath.Join(t.TempDir(), "test.db"),
+		MaxOpenConns:    4,
+		MaxIdleConns:    2,
+		ConnMaxLifetime: time.Minute,
+		BusyTimeout:     3 * time.Second,
+	}
+
+	db, err := NewDatabase(ctx, cfg)
+	if err != nil {
+		t.Fatalf("NewDatabase failed: %v", err)
+	}
+	t.Cleanup(func() { _ = CloseDatabase(db) })
+
+	t.Run("pragmas applied", func(t *testing.T) {
+		var journalMode string
+		var busyTimeout, foreignKeys int
+		db.Raw("PRAGMA journal_mode").Scan(&journalMode)
+		db.Raw("PRAGMA busy_timeout").Scan(&busyTimeout)
+		db.Raw("PRAGMA foreign_keys").Scan(&foreignKeys)
+
+		if journalMode != "wal" {
+			t.Errorf("got journal_mode %q, want wal", journalMode)
+		}
+		if busyTimeout != 3000 {
+			t.Errorf("got busy_timeout %d, want 3000", busyTimeout)
+		}
+		if foreignKeys != 1 {
+			t.Errorf("got foreign_keys %d, want 1", foreignKeys)
+		}
+	})
+
+	t.Run("pool limits applied", func(t *testing.T) {
+		sqlDB, _ := db.DB()
+		if got := sqlDB.Stats().MaxOpenConnections; got != 4 {
+			t.Errorf("got max open conns %d, want 4", got)
+		}
+	})
+
+	t.Run("ping", func(t *testing.T) {
+		if err := PingDatabase(ctx, db); err != nil {
+			t.Errorf("PingDatabase failed: %v", err)
+		}
+	})
+
+	t.Run("ping after close", func(t *testing.T) {
+		closed, err := NewDatabase(ctx, cfg)
+		if err != nil {
+			t.Fatalf("NewDatabase failed: %v", err)
+		}
+		_ = CloseDatabase(closed)
+		if err := PingDatabase(ctx, closed); err == nil {
+			t.Error("expected error after close, got nil")
+		}
+	})
+}
@@ -1,11 +1,133 @@
 package util
 
+import "net/http"
+
+// Kind classifies an AppError. Each kind maps to one HTTP status, so layers
+// below the handlers can say what went wrong without knowing about HTTP.
+type Kind int
+
+const (
+	KindInternal Kind = iota
+	KindBadRequest
+	KindUnauthorized
+	KindForbidden
+	KindNotFound
+	KindConflict
+	KindTooLarge
+	KindUnsupported
+	KindValidation
+	KindRateLimited
+)
+
+var kindStatus = map[Kind]int{
+	KindInternal:     http.StatusInternalServerError,
+	KindBadRequest:   http.StatusBadRequest,
+	KindUnauthorized: http.StatusUnauthorized,
+	KindForbidden:    http.StatusForbidden,
+	KindNotFound:     http.StatusNotFound,
+	KindConflict:     http.StatusConflict,
+	KindTooLarge:     http.StatusRequestEntityTooLarge,
+	KindUnsupported:  http.StatusUnsupportedMediaType,
+	KindValidation:   http.StatusUnprocessableEntity,
+	KindRateLimited:  http.StatusTooManyRequests,
+}
+
+// Status returns the HTTP status for k.
+func (k Kind) Status() int {
+	if status, ok := kindStatus[k]; ok {
+		return status
+	}
+	return http.StatusInternalServerError
+}
+
+// AppError is an error that knows how to present itself to a user. Message
+// is an i18n key such as "error.handleTaken"; the handlers localize it.
+//
+// Package-level AppErrors act as sentinels: errors.Is matches any AppError
+// with the same kind and message, including copies made by Wrap.
 type AppError struct {
-	Error   error
-	Code    int
+	Kind    Kind
 	Message string
+	// Fields maps form or JSON field names to i18n keys for validation errors.
+	Fields map[string]string
+	// Err is the underlying cause, if any. It is logged, never shown.
+	Err error
+}
+
+func (e *AppError) Error() string {
+	if e.Err != nil {
+		return e.Message + ": " + e.Err.Error()
+	}
+	return e.Message
+}
+
+func (e *AppError) Unwrap() error {
+	return e.Err
+}
+
+func (e *AppError) Is(target error) bool {
+	t, ok := target.(*AppError)
+	return ok && t.Kind == e.Kind && t.Message == e.Message
+}
+
+// Status returns the HTTP status for the error's kind.
+func (e *AppError) Status() int {
+	return e.Kind.Status()
+}
+
+// Wrap returns a copy of e with cause attached, leaving e untouched so
+// sentinels can be wrapped safely.
+func (e *AppError) Wrap(cause error) *AppError {
+	c := *e
+	c.Err = cause
+	return &c
+}
+
+// ForField returns a copy of e that also reports its message against field,
+// so a form can show it next to that input.
+func (e *AppError) ForField(field string) *AppError {
+	c := *e
+	c.Fields = map[string]string{field: e.Message}
+	return &c
+}
+
+func NewError(kind Kind, message string) *AppError {
+	return &AppError{Kind: kind, Message: message}
+}
+
+func NotFound(message string) *AppError {
+	return NewError(KindNotFound, message)
+}
+
+func Conflict(message string) *AppError {
+	return NewError(KindConflict, message)
+}
+
+func Unauthorized(message string) *AppError {
+	return NewError(KindUnauthorized, message)
+}
+
+func Forbidden(message string) *AppError {
+	return NewError(KindForbidden, message)
+}
+
+func BadRequest(message string) *AppError {
+	return NewError(KindBadRequest, message)
+}
+
+// Validation reports invalid input. fields maps each offending field to the
+// i18n key describing its problem; message summarizes the whole error.
+func Validation(message string, fields map[string]string) *AppError {
+	return &AppError{Kind: KindValidation, Message: message, Fields: fields}
+}
+
+// Invalid is Validation for a single field, whose problem is also the
+// summary.
+func Invalid(field, message string) *AppError {
+	return Validation(message, map[string]string{field: message})
 }
 
-func Error(err error, code int, message string) *AppError {
-	return &AppError{Error: err, Code: code, Message: message}
+// Internal wraps an unexpected error. Its message is deliberately generic.
+func Internal(err error) *AppError {
+	return &AppError{Kind: KindInternal, Message: "error.somethingWrong", Err: err}
 }
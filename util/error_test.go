@@ -0,0 +1,67 @@
+package util
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+)
+
+func TestAppError(t *testing.T) {
+	sentinel := Conflict("error.handleTaken")
+
+	t.Run("status follows kind", func(t *testing.T) {
+		tests := []struct {
+			err  *AppError
+			want int
+		}{
+			{NotFound("error.notFound"), http.StatusNotFound},
+			{sentinel, http.StatusConflict},
+			{Invalid("bio", "error.bioTooLong"), http.StatusUnprocessableEntity},
+			{Unauthorized("error.unauthorized"), http.StatusUnauthorized},
+			{Internal(errors.New("boom")), http.StatusInternalServerError},
+			{NewError(Kind(99), "error.somethingWrong"), http.StatusInternalServerError},
+		}
+		for _, tt := range tests {
+			if got := tt.err.Status(); got != tt.want {
+				t.Errorf("%s: Status() = %d, want %d", tt.err.Message, got, tt.want)
+			}
+		}
+	})
+
+	t.Run("errors.Is matches sentinels through wrapping", func(t *testing.T) {
+		cause := errors.New("unique constraint")
+		wrapped := fmt.Errorf("update profile: %w", sentinel.Wrap(cause))
+		if !errors.Is(wrapped, sentinel) {
+			t.Error("expected wrapped copy to match its sentinel")
+		}
+		if !errors.Is(wrapped, cause) {
+			t.Error("expected the cause to stay reachable")
+		}
+		if errors.Is(wrapped, Conflict("error.emailTaken")) {
+			t.Error("expected a different message not to match")
+		}
+		if sentinel.Err != nil {
+			t.Error("Wrap must not modify the sentinel")
+		}
+	})
+
+	t.Run("ForField copies and still matches", func(t *testing.T) {
+		sentinel := Conflict("error.handleTaken")
+		field := sentinel.ForField("handle")
+		if !errors.Is(field, sentinel) || field.Fields["handle"] != "error.handleTaken" {
+			t.Errorf("expected handle field matching the sentinel, got %+v", field)
+		}
+		if sentinel.Fields != nil {
+			t.Error("ForField must not modify the sentinel")
+		}
+	})
+
+	t.Run("errors.As finds the AppError", func(t *testing.T) {
+		var appErr *AppError
+		err := fmt.Errorf("signup: %w", Invalid("handle", "error.handleInvalid"))
+		if !errors.As(err, &appErr) || appErr.Fields["handle"] != "error.handleInvalid" {
+			t.Errorf("expected validation error with handle field, got %v", appErr)
+		}
+	})
+}
@@ -2,24 +2,23 @@ package util
 
 import (
 	"fmt"
-	"net/http"
 
 	"github.com/golang-jwt/jwt/v5"
 )
 
-func SignJwt(secret string, claims jwt.MapClaims) (*string, *AppError) {
+func SignJwt(secret string, claims jwt.MapClaims) (string, error) {
 	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
 
 	tokenString, err := token.SignedString([]byte(secret))
 
 	if err != nil {
-		return nil, Error(err, 500, "Failed to sign token")
+		return "", Internal(fmt.Errorf("failed to sign token: %w", err))
 	}
 
-	return &tokenString, nil
+	return tokenString, nil
 }
 
-func ParseJwt(secret string, tokenString string) (jwt.MapClaims, *AppError) {
+func ParseJwt(secret string, tokenString string) (jwt.MapClaims, error) {
 	token, err := jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
 		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
 			return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
@@ -29,13 +28,13 @@ func ParseJwt(secret string, tokenString string) (jwt.MapClaims, *AppError) {
 	})
 
 	if err != nil {
-		return nil, Error(err, http.StatusInternalServerError, "Failed to parse token")
+		return nil, Unauthorized("error.unauthorized").Wrap(err)
 	}
 
 	claims, ok := token.Claims.(jwt.MapClaims)
 
 	if !ok {
-		return nil, Error(err, http.StatusForbidden, "Invalid claims")
+		return nil, Unauthorized("error.unauthorized")
 	}
 
 	return claims, nil
@@ -0,0 +1,54 @@
+package util
+
+import (
+	"context"
+	"io"
+	"log/slog"
+)
+
+type loggerKey struct{}
+
+type requestInfoKey struct{}
+
+// requestInfo is shared by pointer so code deeper in the request (e.g. the
+// auth service) can annotate the access log written by the middleware.
+type requestInfo struct {
+	userID string
+}
+
+func NewLogger(w io.Writer, level slog.Level) *slog.Logger {
+	return slog.New(slog.NewJSONHandler(w, &slog.HandlerOptions{Level: level}))
+}
+
+// WithLogger returns a context carrying a request-scoped logger.
+func WithLogger(ctx context.Context, logger *slog.Logger) context.Context {
+	return context.WithValue(ctx, loggerKey{}, logger)
+}
+
+// Logger returns the request-scoped logger, or slog.Default outside a request.
+func Logger(ctx context.Context) *slog.Logger {
+	if logger, ok := ctx.Value(loggerKey{}).(*slog.Logger); ok {
+		return logger
+	}
+	return slog.Default()
+}
+
+// WithRequestInfo prepares ctx to collect the authenticated user ID.
+func WithRequestInfo(ctx context.Context) context.Context {
+	return context.WithValue(ctx, requestInfoKey{}, &requestInfo{})
+}
+
+// SetRequestUserID records the authenticated user for the access log.
+func SetRequestUserID(ctx context.Context, userID string) {
+	if info, ok := ctx.Value(requestInfoKey{}).(*requestInfo); ok {
+		info.userID = userID
+	}
+}
+
+// RequestUserID returns the user recorded by SetRequestUserID, if any.
+func RequestUserID(ctx context.Context) string {
+	if info, ok := ctx.Value(requestInfoKey{}).(*requestInfo); ok {
+		return info.userID
+	}
+	return ""
+}